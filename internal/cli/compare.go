@@ -3,22 +3,32 @@ package cli
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"jonopens/sitemapper/internal/repositories"
 	"jonopens/sitemapper/internal/services"
 	"jonopens/sitemapper/pkg/sitemap"
 )
 
 var (
 	compareShowUnchanged bool
+	compareNormalize     []string
+	compareUserID        string
 )
 
 var compareCmd = &cobra.Command{
 	Use:   "compare <source1> <source2>",
 	Short: "Compare two sitemaps and show differences",
-	Long: `Compare two sitemaps and display added, removed, and unchanged URLs.
+	Long: `Compare two sitemaps and display added, removed, changed and unchanged URLs.
+A URL is changed when its lastmod, changefreq or priority differs; the old and
+new values of every changed field are shown.
 Sources can be URLs, file paths, or report IDs from tracked snapshots.
+When both sources are report IDs the diff is stored; see "sitemapper diff".
+Report IDs are looked up among the reports of the user given by --user-id, or
+the configured default user.
+--normalize matches URLs after normalization (e.g. --normalize all, or
+--normalize trailing_slash,scheme), so CDN rewrites are not reported as
+added and removed URLs.
 Examples:
   sitemapper compare file1.xml file2.xml
   sitemapper compare https://example.com/sitemap.xml file.xml
@@ -29,6 +39,8 @@ Examples:
 
 func init() {
 	compareCmd.Flags().BoolVar(&compareShowUnchanged, "show-unchanged", false, "show unchanged URLs in output")
+	compareCmd.Flags().StringSliceVar(&compareNormalize, "normalize", nil, normalizeFlagUsage)
+	compareCmd.Flags().StringVar(&compareUserID, "user-id", "", "user ID (defaults to config default_user_id)")
 }
 
 func runCompare(cmd *cobra.Command, args []string) error {
@@ -38,22 +50,52 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	
 	ctx.Formatter.Info(fmt.Sprintf("Comparing: %s vs %s", source1, source2))
 	
-	// Load first sitemap
-	sitemap1, name1, err := loadSitemapFromSource(ctx, source1)
+	normalizer, err := urlNormalizer(ctx, cmd)
 	if err != nil {
-		ctx.Formatter.Error(fmt.Sprintf("Failed to load first sitemap: %v", err))
+		ctx.Formatter.Error(err.Error())
 		return err
 	}
-	
-	// Load second sitemap
-	sitemap2, name2, err := loadSitemapFromSource(ctx, source2)
-	if err != nil {
-		ctx.Formatter.Error(fmt.Sprintf("Failed to load second sitemap: %v", err))
-		return err
+	if normalizer.Enabled() {
+		ctx.Formatter.Info(fmt.Sprintf("Normalizing URLs: %s", joinRules(normalizer.Rules())))
 	}
 	
-	// Compare sitemaps
-	added, removed, unchanged := compareSitemaps(sitemap1, sitemap2)
+	var (
+		diff         *sitemap.Diff
+		name1, name2 string
+		diffID       string
+	)
+	
+	if isTrackedReport(ctx, source1) && isTrackedReport(ctx, source2) {
+		// Both sides are tracked reports, so store the diff for later review
+		sitemapService := services.NewSitemapService(ctx.UserDB(compareUser(ctx)))
+		reportDiff, result, err := sitemapService.CompareSitemaps(context.Background(), source1, source2, normalizer)
+		if err != nil {
+			ctx.Formatter.Error(fmt.Sprintf("Failed to compare reports: %v", err))
+			return err
+		}
+		diff = result
+		diffID = reportDiff.ID
+		name1 = fmt.Sprintf("Report: %s", source1)
+		name2 = fmt.Sprintf("Report: %s", source2)
+	} else {
+		// Index the first sitemap by location
+		differ := sitemap.NewDiffer()
+		name1, err = streamSitemapSource(ctx, source1, normalizer, differ.AddBaseKey)
+		if err != nil {
+			ctx.Formatter.Error(fmt.Sprintf("Failed to load first sitemap: %v", err))
+			return err
+		}
+		
+		// Stream the second sitemap against the first
+		name2, err = streamSitemapSource(ctx, source2, normalizer, differ.AddCompareKey)
+		if err != nil {
+			ctx.Formatter.Error(fmt.Sprintf("Failed to load second sitemap: %v", err))
+			return err
+		}
+		
+		diff = differ.Result()
+	}
+	added, removed, changed, unchanged := diff.Added, diff.Removed, diff.Changed, diff.Unchanged
 	
 	// Output results
 	if ctx.Config.OutputFormat == "json" {
@@ -62,24 +104,34 @@ func runCompare(cmd *cobra.Command, args []string) error {
 			"source2":   name2,
 			"added":     len(added),
 			"removed":   len(removed),
+			"changed":   len(changed),
 			"unchanged": len(unchanged),
 			"added_urls":   added,
 			"removed_urls": removed,
+			"changed_urls": changed,
 		}
 		if compareShowUnchanged {
 			result["unchanged_urls"] = unchanged
 		}
+		if diffID != "" {
+			result["diff_id"] = diffID
+		}
 		return ctx.Formatter.Print(result)
 	}
 	
 	// Print summary
 	fmt.Printf("\nComparison Results:\n")
-	fmt.Printf("  Source 1: %s (%d URLs)\n", name1, len(sitemap1.URLs))
-	fmt.Printf("  Source 2: %s (%d URLs)\n", name2, len(sitemap2.URLs))
+	fmt.Printf("  Source 1: %s (%d URLs)\n", name1, diff.BaseCount)
+	fmt.Printf("  Source 2: %s (%d URLs)\n", name2, diff.CompareCount)
 	fmt.Printf("\n")
 	fmt.Printf("  Added:     %d URLs\n", len(added))
 	fmt.Printf("  Removed:   %d URLs\n", len(removed))
+	fmt.Printf("  Changed:   %d URLs\n", len(changed))
 	fmt.Printf("  Unchanged: %d URLs\n", len(unchanged))
+	if diffID != "" {
+		fmt.Printf("\n")
+		fmt.Printf("  Diff ID:   %s\n", diffID)
+	}
 	fmt.Printf("\n")
 	
 	// Print differences
@@ -107,6 +159,21 @@ func runCompare(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 	
+	if len(changed) > 0 {
+		fmt.Println("Changed URLs:")
+		for i, change := range changed {
+			if i >= 20 {
+				fmt.Printf("  ... and %d more\n", len(changed)-20)
+				break
+			}
+			fmt.Printf("  ~ %s\n", change.Loc)
+			for _, field := range change.Fields {
+				fmt.Printf("      %s: %s -> %s\n", field.Field, valueOrNone(field.Old), valueOrNone(field.New))
+			}
+		}
+		fmt.Println()
+	}
+	
 	if compareShowUnchanged && len(unchanged) > 0 {
 		fmt.Println("Unchanged URLs (first 10):")
 		limit := 10
@@ -124,7 +191,7 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	
 	// Use colored diff output
 	if ctx.Config.ColorOutput {
-		ctx.Formatter.PrintDiff(added, removed, nil)
+		ctx.Formatter.PrintDiff(diff, false)
 	}
 	
 	ctx.Formatter.Success("Comparison complete")
@@ -132,103 +199,89 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func loadSitemapFromSource(ctx *CLIContext, source string) (*sitemap.Sitemap, string, error) {
+// streamSitemapSource calls fn for every URL of a tracked report, file or URL,
+// together with its location normalized by normalizer, and returns a display
+// name for the source
+func streamSitemapSource(ctx *CLIContext, source string, normalizer *sitemap.Normalizer, fn func(key string, u sitemap.URL) error) (string, error) {
 	// First try to load as a report ID from database
-	reportService := services.NewReportService(ctx.DB)
-	report, err := reportService.GetReport(context.Background(), source)
-	if err == nil && report != nil {
-		// Load entries from report
-		sm, err := loadSitemapFromReport(ctx, source)
-		if err == nil {
-			return sm, fmt.Sprintf("Report: %s", source), nil
+	if isTrackedReport(ctx, source) {
+		sitemapService := services.NewSitemapService(ctx.UserDB(compareUser(ctx)))
+		err := sitemapService.StreamReportURLs(context.Background(), source, normalizer, fn)
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("Report: %s", source), nil
 	}
 	
-	// Otherwise, load as file or URL
-	data, err := readSitemapSource(source)
+	// Otherwise, stream from file or URL, expanding compressed sources
+	_, sources, closeSources, err := openSitemapSources(ctx, source)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
+	defer closeSources()
 	
-	parser := sitemap.NewParser()
-	sm, err := parser.Parse(data)
-	if err != nil {
-		return nil, "", err
+	// Resolve sitemap indexes so both sides compare merged URL sets
+	resolver := sitemap.NewResolver(sitemapFetcher(ctx))
+	for _, src := range sources {
+		_, err = resolver.Stream(context.Background(), src.Name, src.Reader, func(u sitemap.SourcedURL) error {
+			return fn(normalizer.Normalize(u.Loc), u.URL)
+		})
+		if err != nil {
+			return "", err
+		}
 	}
 	
-	return sm, source, nil
+	return source, nil
 }
 
-func loadSitemapFromReport(ctx *CLIContext, reportID string) (*sitemap.Sitemap, error) {
-	// Get entries from database for this report
-	entryRepo := ctx.DB.Entries()
-	entries, err := entryRepo.List(context.Background(), repositories.EntryFilters{
-		ReportID: reportID,
-	})
+// normalizeFlagUsage describes the --normalize flag of compare and track
+const normalizeFlagUsage = "URL normalization rules: all, none, or a list of trailing_slash, scheme, www, host_case, default_port, tracking_params (defaults to config normalize)"
+
+// urlNormalizer builds the normalizer selected by the --normalize flag of cmd,
+// falling back to the normalize and tracking_params config settings
+func urlNormalizer(ctx *CLIContext, cmd *cobra.Command) (*sitemap.Normalizer, error) {
+	names := ctx.Config.Normalize
+	if flag := cmd.Flags().Lookup("normalize"); flag != nil && flag.Changed {
+		names, _ = cmd.Flags().GetStringSlice("normalize")
+	}
+	
+	rules, err := sitemap.ParseNormalizeRules(names)
 	if err != nil {
 		return nil, err
 	}
-	
-	// Convert entries to sitemap
-	sm := &sitemap.Sitemap{
-		URLs: make([]sitemap.URL, 0, len(entries)),
+	if len(rules) == 0 {
+		return nil, nil
 	}
-	
-	for _, entry := range entries {
-		u := sitemap.URL{
-			Loc: entry.URL,
-		}
-		
-		if entry.LastModified != nil {
-			u.LastMod = entry.LastModified.Format("2006-01-02")
-		}
-		if entry.ChangeFreq != nil {
-			u.ChangeFreq = *entry.ChangeFreq
-		}
-		if entry.Priority != nil {
-			u.Priority = *entry.Priority
-		}
-		
-		sm.URLs = append(sm.URLs, u)
-	}
-	
-	return sm, nil
+	return sitemap.NewNormalizer(rules, ctx.Config.TrackingParams), nil
 }
 
-func compareSitemaps(sm1, sm2 *sitemap.Sitemap) (added, removed, unchanged []string) {
-	// Build sets of URLs
-	urls1 := make(map[string]bool)
-	urls2 := make(map[string]bool)
-	
-	for _, u := range sm1.URLs {
-		urls1[u.Loc] = true
-	}
-	
-	for _, u := range sm2.URLs {
-		urls2[u.Loc] = true
-	}
-	
-	// Find added URLs (in sm2 but not in sm1)
-	for _, u := range sm2.URLs {
-		if !urls1[u.Loc] {
-			added = append(added, u.Loc)
-		}
-	}
-	
-	// Find removed URLs (in sm1 but not in sm2)
-	for _, u := range sm1.URLs {
-		if !urls2[u.Loc] {
-			removed = append(removed, u.Loc)
-		}
+// joinRules renders normalization rules for display
+func joinRules(rules []sitemap.NormalizeRule) string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = string(rule)
 	}
-	
-	// Find unchanged URLs (in both)
-	for _, u := range sm1.URLs {
-		if urls2[u.Loc] {
-			unchanged = append(unchanged, u.Loc)
-		}
+	return strings.Join(names, ", ")
+}
+
+// isTrackedReport reports whether source is the ID of a stored report
+func isTrackedReport(ctx *CLIContext, source string) bool {
+	report, err := services.NewReportService(ctx.UserDB(compareUser(ctx))).GetReport(context.Background(), source)
+	return err == nil && report != nil
+}
+
+// compareUser returns the --user-id flag or the configured default user
+func compareUser(ctx *CLIContext) string {
+	if compareUserID != "" {
+		return compareUserID
 	}
-	
-	return added, removed, unchanged
+	return ctx.Config.DefaultUserID
 }
 
+// valueOrNone renders an absent field value
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
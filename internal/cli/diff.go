@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "View stored report diffs",
+	Long: `List and view diffs stored by comparing two tracked reports.
+A diff is stored every time compare is run with two report IDs.
+Commands only see the diffs of the user given by --user-id, or the
+configured default user.`,
+}
+
+var diffListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored diffs",
+	Long:  `List stored report diffs, newest first.`,
+	RunE:  runDiffList,
+}
+
+var diffGetCmd = &cobra.Command{
+	Use:   "get <diff-id>",
+	Short: "Show a stored diff",
+	Long:  `Display a stored report diff and its added, removed and changed URLs.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDiffGet,
+}
+
+var (
+	diffUserID       string
+	diffListReportID string
+	diffListLimit    int
+	diffGetType      string
+)
+
+func init() {
+	diffCmd.PersistentFlags().StringVar(&diffUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+
+	// List command flags
+	diffListCmd.Flags().StringVar(&diffListReportID, "report", "", "only list diffs involving this report ID")
+	diffListCmd.Flags().IntVar(&diffListLimit, "limit", 50, "maximum number of diffs to list")
+
+	// Get command flags
+	diffGetCmd.Flags().StringVar(&diffGetType, "type", "", "only show entries of this change type (added, removed, changed)")
+
+	// Add subcommands
+	diffCmd.AddCommand(diffListCmd)
+	diffCmd.AddCommand(diffGetCmd)
+}
+
+func runDiffList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := diffUser(ctx)
+
+	sitemapService := services.NewSitemapService(ctx.UserDB(userID))
+	diffs, err := sitemapService.ListDiffs(context.Background(), repositories.ReportDiffFilters{
+		UserID:   userID,
+		ReportID: diffListReportID,
+		Limit:    diffListLimit,
+	})
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list diffs: %v", err))
+		return err
+	}
+
+	if len(diffs) == 0 {
+		ctx.Formatter.Info("No diffs found")
+		return nil
+	}
+
+	// Output results
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(diffs)
+	}
+
+	fmt.Printf("\nFound %d diff(s):\n\n", len(diffs))
+
+	rows := [][]string{
+		{"ID", "Base Report", "Compare Report", "Added", "Removed", "Changed", "Created"},
+	}
+
+	for _, diff := range diffs {
+		rows = append(rows, []string{
+			truncate(diff.ID, 20),
+			truncate(diff.BaseReportID, 20),
+			truncate(diff.CompareReportID, 20),
+			fmt.Sprintf("%d", diff.EntriesAdded),
+			fmt.Sprintf("%d", diff.EntriesRemoved),
+			fmt.Sprintf("%d", diff.EntriesChanged),
+			diff.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	ctx.Formatter.Print(rows)
+
+	fmt.Printf("\nTotal: %d diff(s)\n", len(diffs))
+
+	return nil
+}
+
+func runDiffGet(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	diffID := args[0]
+
+	changeType := models.DiffChangeType(strings.ToLower(diffGetType))
+	switch changeType {
+	case "", models.DiffChangeTypeAdded, models.DiffChangeTypeRemoved, models.DiffChangeTypeChanged:
+	default:
+		return fmt.Errorf("invalid change type %q: use added, removed or changed", diffGetType)
+	}
+
+	sitemapService := services.NewSitemapService(ctx.UserDB(diffUser(ctx)))
+	diff, err := sitemapService.GetDiff(context.Background(), diffID)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to get diff: %v", err))
+		return err
+	}
+
+	if diff == nil {
+		ctx.Formatter.Error("Diff not found")
+		return fmt.Errorf("diff not found: %s", diffID)
+	}
+
+	all, err := sitemapService.ListDiffEntries(context.Background(), diffID)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list diff entries: %v", err))
+		return err
+	}
+
+	entries := make([]*models.ReportDiffEntry, 0, len(all))
+	for _, entry := range all {
+		if changeType == "" || entry.ChangeType == changeType {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Output results
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(map[string]interface{}{
+			"diff":    diff,
+			"entries": entries,
+		})
+	}
+
+	// Print diff details
+	fmt.Printf("\nDiff Details:\n")
+	fmt.Printf("  ID:                %s\n", diff.ID)
+	fmt.Printf("  User ID:           %s\n", diff.UserID)
+	fmt.Printf("  Base Report:       %s\n", diff.BaseReportID)
+	fmt.Printf("  Compare Report:    %s\n", diff.CompareReportID)
+	fmt.Printf("\n")
+	fmt.Printf("Changes:\n")
+	fmt.Printf("  Added:             %d\n", diff.EntriesAdded)
+	fmt.Printf("  Removed:           %d\n", diff.EntriesRemoved)
+	fmt.Printf("  Changed:           %d\n", diff.EntriesChanged)
+	fmt.Printf("\n")
+	fmt.Printf("  Created:           %s\n", diff.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	if len(entries) == 0 {
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Printf("\nEntries (%d):\n\n", len(entries))
+
+	rows := [][]string{
+		{"Change", "URL", "Field", "Old", "New"},
+	}
+
+	for _, entry := range entries {
+		if len(entry.Fields) == 0 {
+			rows = append(rows, []string{string(entry.ChangeType), truncate(entry.URL, 70), "", "", ""})
+			continue
+		}
+		for i, field := range entry.Fields {
+			change, url := string(entry.ChangeType), truncate(entry.URL, 70)
+			if i > 0 {
+				change, url = "", ""
+			}
+			rows = append(rows, []string{change, url, field.Field, valueOrNone(field.Old), valueOrNone(field.New)})
+		}
+	}
+
+	ctx.Formatter.Print(rows)
+
+	fmt.Println()
+
+	return nil
+}
+
+// diffUser returns the --user-id flag or the configured default user
+func diffUser(ctx *CLIContext) string {
+	if diffUserID != "" {
+		return diffUserID
+	}
+	return ctx.Config.DefaultUserID
+}
@@ -3,23 +3,28 @@ package cli
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
 )
 
 var groupingCmd = &cobra.Command{
 	Use:   "grouping",
 	Short: "Manage URL groupings",
-	Long:  `Create and manage URL groupings for organizing sitemap entries.`,
+	Long: `Create and manage URL groupings for organizing sitemap entries.
+Commands only see the groupings, rules, overrides and reports of the user
+given by --user-id, or the configured default user.`,
 }
 
 var groupingListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all groupings",
-	Long:  `List all URL groupings.`,
+	Long:  `List all of a user's URL groupings.`,
 	RunE:  runGroupingList,
 }
 
@@ -30,22 +35,133 @@ var groupingCreateCmd = &cobra.Command{
 	RunE:  runGroupingCreate,
 }
 
+var groupingRuleCmd = &cobra.Command{
+	Use:   "rule",
+	Short: "Manage grouping rules",
+	Long: `Manage rules that assign URLs to groupings.
+Rules are evaluated from the highest priority down and the first match wins.
+URLs that match no rule fall back to automatic path grouping.
+Rule types:
+  glob         glob over the URL path ("*" stays within a segment, "**" spans segments);
+               patterns containing "://" match the full URL
+  regex        regular expression over the full URL
+  host         host name, or *.example.com for subdomains
+  query_param  query parameter name, or name=value`,
+}
+
+var groupingRuleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a grouping rule",
+	Long: `Add a rule that assigns matching URLs to a grouping.
+Example:
+  sitemapper grouping rule add --grouping "Blog Posts" --type glob --pattern "/blog/**" --priority 10`,
+	RunE: runGroupingRuleAdd,
+}
+
+var groupingRuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List grouping rules in evaluation order",
+	RunE:  runGroupingRuleList,
+}
+
+var groupingRuleTestCmd = &cobra.Command{
+	Use:   "test <url>",
+	Short: "Show which grouping a URL is assigned to",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupingRuleTest,
+}
+
+var groupingRuleDeleteCmd = &cobra.Command{
+	Use:   "delete <rule-id>",
+	Short: "Delete a grouping rule",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupingRuleDelete,
+}
+
+var groupingOverrideCmd = &cobra.Command{
+	Use:   "override",
+	Short: "Manage per-URL grouping overrides",
+	Long:  `Pin individual URLs to a grouping. Overrides take precedence over rules and automatic grouping.`,
+}
+
+var groupingOverrideSetCmd = &cobra.Command{
+	Use:   "set <url>",
+	Short: "Assign a URL to a grouping",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupingOverrideSet,
+}
+
+var groupingOverrideListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List grouping overrides",
+	RunE:  runGroupingOverrideList,
+}
+
+var groupingOverrideRemoveCmd = &cobra.Command{
+	Use:   "remove <url>",
+	Short: "Remove the grouping override of a URL",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runGroupingOverrideRemove,
+}
+
+var groupingApplyCmd = &cobra.Command{
+	Use:   "apply <report-id>",
+	Short: "Re-apply groupings to an existing report",
+	Long: `Re-assign every stored URL of a report using the current overrides, rules and
+automatic grouping, and recompute the report's per-grouping totals.
+Sampled reports are refused: their totals count every URL of the sitemap, but
+only a sample of the URLs is stored to re-assign.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGroupingApply,
+}
+
 var (
 	groupingName        string
 	groupingDescription string
 	groupingUserID      string
+	
+	groupingRuleGrouping string
+	groupingRuleType     string
+	groupingRulePattern  string
+	groupingRulePriority int
+	
+	groupingOverrideGrouping string
 )
 
 func init() {
 	// Create command flags
 	groupingCreateCmd.Flags().StringVar(&groupingName, "name", "", "name for the grouping (required)")
 	groupingCreateCmd.Flags().StringVar(&groupingDescription, "description", "", "description for the grouping")
-	groupingCreateCmd.Flags().StringVar(&groupingUserID, "user-id", "", "user ID (defaults to config default_user_id)")
 	groupingCreateCmd.MarkFlagRequired("name")
+	groupingCmd.PersistentFlags().StringVar(&groupingUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+	
+	// Rule command flags
+	groupingRuleAddCmd.Flags().StringVar(&groupingRuleGrouping, "grouping", "", "grouping ID or name (required)")
+	groupingRuleAddCmd.Flags().StringVar(&groupingRuleType, "type", string(models.GroupingRuleTypeGlob), "rule type (glob, regex, host, query_param)")
+	groupingRuleAddCmd.Flags().StringVar(&groupingRulePattern, "pattern", "", "pattern to match (required)")
+	groupingRuleAddCmd.Flags().IntVar(&groupingRulePriority, "priority", 0, "rule priority; higher priorities are evaluated first")
+	groupingRuleAddCmd.MarkFlagRequired("grouping")
+	groupingRuleAddCmd.MarkFlagRequired("pattern")
+	
+	// Override command flags
+	groupingOverrideSetCmd.Flags().StringVar(&groupingOverrideGrouping, "grouping", "", "grouping ID or name (required)")
+	groupingOverrideSetCmd.MarkFlagRequired("grouping")
 	
 	// Add subcommands
+	groupingRuleCmd.AddCommand(groupingRuleAddCmd)
+	groupingRuleCmd.AddCommand(groupingRuleListCmd)
+	groupingRuleCmd.AddCommand(groupingRuleTestCmd)
+	groupingRuleCmd.AddCommand(groupingRuleDeleteCmd)
+	
+	groupingOverrideCmd.AddCommand(groupingOverrideSetCmd)
+	groupingOverrideCmd.AddCommand(groupingOverrideListCmd)
+	groupingOverrideCmd.AddCommand(groupingOverrideRemoveCmd)
+	
 	groupingCmd.AddCommand(groupingListCmd)
 	groupingCmd.AddCommand(groupingCreateCmd)
+	groupingCmd.AddCommand(groupingRuleCmd)
+	groupingCmd.AddCommand(groupingOverrideCmd)
+	groupingCmd.AddCommand(groupingApplyCmd)
 }
 
 func runGroupingList(cmd *cobra.Command, args []string) error {
@@ -54,7 +170,7 @@ func runGroupingList(cmd *cobra.Command, args []string) error {
 	ctx.Formatter.Info("Listing groupings")
 	
 	// Get groupings from database
-	groupingRepo := ctx.DB.Groupings()
+	groupingRepo := groupingDB(ctx).Groupings()
 	groupings, err := groupingRepo.List(context.Background())
 	if err != nil {
 		ctx.Formatter.Error(fmt.Sprintf("Failed to list groupings: %v", err))
@@ -102,17 +218,12 @@ func runGroupingList(cmd *cobra.Command, args []string) error {
 func runGroupingCreate(cmd *cobra.Command, args []string) error {
 	ctx := GetContext()
 	
-	// Use default user ID from config if not provided
-	if groupingUserID == "" {
-		groupingUserID = ctx.Config.DefaultUserID
-	}
-	
 	ctx.Formatter.Info(fmt.Sprintf("Creating grouping: %s", groupingName))
 	
 	// Create grouping
 	grouping := &models.Group{
 		ID:          uuid.New().String(),
-		UserID:      groupingUserID,
+		UserID:      groupingUser(ctx),
 		Name:        groupingName,
 		Description: stringPtrOrNil(groupingDescription),
 		CreatedAt:   time.Now(),
@@ -120,7 +231,7 @@ func runGroupingCreate(cmd *cobra.Command, args []string) error {
 	}
 	
 	// Save to database
-	groupingRepo := ctx.DB.Groupings()
+	groupingRepo := groupingDB(ctx).Groupings()
 	if err := groupingRepo.Create(context.Background(), grouping); err != nil {
 		ctx.Formatter.Error(fmt.Sprintf("Failed to create grouping: %v", err))
 		return err
@@ -153,3 +264,348 @@ func stringPtrOrNil(s string) *string {
 	return &s
 }
 
+
+func runGroupingRuleAdd(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := groupingUser(ctx)
+	
+	grouping, err := findGrouping(ctx, userID, groupingRuleGrouping)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+	
+	rule := &models.GroupingRule{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		GroupingID: grouping.ID,
+		Type:       models.GroupingRuleType(groupingRuleType),
+		Pattern:    groupingRulePattern,
+		Priority:   groupingRulePriority,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	
+	service := services.NewGroupingService(groupingDB(ctx))
+	if err := service.AddRule(context.Background(), rule); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to add rule: %v", err))
+		return err
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Rule added with ID: %s", rule.ID))
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(rule)
+	}
+	
+	fmt.Printf("\nRule Details:\n")
+	fmt.Printf("  ID:       %s\n", rule.ID)
+	fmt.Printf("  Grouping: %s\n", grouping.Name)
+	fmt.Printf("  Type:     %s\n", rule.Type)
+	fmt.Printf("  Pattern:  %s\n", rule.Pattern)
+	fmt.Printf("  Priority: %d\n", rule.Priority)
+	fmt.Println()
+	
+	return nil
+}
+
+func runGroupingRuleList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := groupingUser(ctx)
+	
+	service := services.NewGroupingService(groupingDB(ctx))
+	rules, err := service.ListRules(context.Background(), userID)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list rules: %v", err))
+		return err
+	}
+	
+	if len(rules) == 0 {
+		ctx.Formatter.Info("No grouping rules found")
+		return nil
+	}
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(rules)
+	}
+	
+	names := groupingNames(ctx)
+	rows := [][]string{
+		{"ID", "Priority", "Type", "Pattern", "Grouping"},
+	}
+	for _, rule := range rules {
+		rows = append(rows, []string{
+			truncate(rule.ID, 20),
+			fmt.Sprintf("%d", rule.Priority),
+			string(rule.Type),
+			truncate(rule.Pattern, 40),
+			truncate(nameOr(names, rule.GroupingID), 30),
+		})
+	}
+	
+	fmt.Println()
+	ctx.Formatter.Print(rows)
+	fmt.Printf("\nTotal: %d rule(s)\n", len(rules))
+	
+	return nil
+}
+
+func runGroupingRuleTest(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	rawURL := args[0]
+	
+	service := services.NewGroupingService(groupingDB(ctx))
+	service.Depth = ctx.Config.GroupingDepth
+	engine, err := service.NewEngine(context.Background(), groupingUser(ctx), "")
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to load grouping rules: %v", err))
+		return err
+	}
+	
+	groupID, name, source, ruleID := engine.Classify(rawURL)
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(map[string]interface{}{
+			"url":         rawURL,
+			"grouping_id": groupID,
+			"grouping":    name,
+			"source":      source,
+			"rule_id":     ruleID,
+		})
+	}
+	
+	if name == "" && groupID == "" {
+		ctx.Formatter.Warning(fmt.Sprintf("%s cannot be grouped", rawURL))
+		return nil
+	}
+	
+	fmt.Printf("\nURL:      %s\n", rawURL)
+	fmt.Printf("Grouping: %s\n", name)
+	if groupID == "" {
+		fmt.Printf("          (new automatic grouping, created on the next track)\n")
+	}
+	fmt.Printf("Source:   %s\n", source)
+	if ruleID != "" {
+		fmt.Printf("Rule:     %s\n", ruleID)
+	}
+	fmt.Println()
+	
+	return nil
+}
+
+func runGroupingRuleDelete(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	ruleID := args[0]
+	
+	if _, err := groupingDB(ctx).GroupingRules().GetByID(context.Background(), ruleID); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Rule not found: %s", ruleID))
+		return err
+	}
+	
+	if err := groupingDB(ctx).GroupingRules().Delete(context.Background(), ruleID); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to delete rule: %v", err))
+		return err
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Rule %s deleted", ruleID))
+	return nil
+}
+
+func runGroupingOverrideSet(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := groupingUser(ctx)
+	
+	grouping, err := findGrouping(ctx, userID, groupingOverrideGrouping)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+	
+	service := services.NewGroupingService(groupingDB(ctx))
+	override, err := service.SetOverride(context.Background(), userID, args[0], grouping.ID)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to set override: %v", err))
+		return err
+	}
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(override)
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("%s is now assigned to %s", override.URL, grouping.Name))
+	return nil
+}
+
+func runGroupingOverrideList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	
+	overrides, err := groupingDB(ctx).GroupingOverrides().ListByUserID(context.Background(), groupingUser(ctx))
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list overrides: %v", err))
+		return err
+	}
+	
+	if len(overrides) == 0 {
+		ctx.Formatter.Info("No grouping overrides found")
+		return nil
+	}
+	
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].URL < overrides[j].URL })
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(overrides)
+	}
+	
+	names := groupingNames(ctx)
+	rows := [][]string{
+		{"URL", "Grouping", "Updated"},
+	}
+	for _, override := range overrides {
+		rows = append(rows, []string{
+			truncate(override.URL, 60),
+			truncate(nameOr(names, override.GroupingID), 30),
+			override.UpdatedAt.Format("2006-01-02"),
+		})
+	}
+	
+	fmt.Println()
+	ctx.Formatter.Print(rows)
+	fmt.Printf("\nTotal: %d override(s)\n", len(overrides))
+	
+	return nil
+}
+
+func runGroupingOverrideRemove(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	
+	override, err := groupingDB(ctx).GroupingOverrides().GetByURL(context.Background(), groupingUser(ctx), args[0])
+	if err != nil || override == nil {
+		ctx.Formatter.Error(fmt.Sprintf("No override found for %s", args[0]))
+		return fmt.Errorf("no override found for %s", args[0])
+	}
+	
+	if err := groupingDB(ctx).GroupingOverrides().Delete(context.Background(), override.ID); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to remove override: %v", err))
+		return err
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Override for %s removed", args[0]))
+	return nil
+}
+
+func runGroupingApply(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	reportID := args[0]
+	contextBg := context.Background()
+	
+	report, err := groupingDB(ctx).Reports().GetByID(contextBg, reportID)
+	if err != nil || report == nil {
+		ctx.Formatter.Error(fmt.Sprintf("Report not found: %s", reportID))
+		return fmt.Errorf("report not found: %s", reportID)
+	}
+	
+	tx, err := groupingDB(ctx).BeginTx(contextBg)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	service := services.NewGroupingService(tx)
+	service.Depth = ctx.Config.GroupingDepth
+	aggregates, err := service.ApplyToReport(contextBg, report)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to apply groupings: %v", err))
+		return err
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Applied groupings to report %s: %d grouping(s), %d ungrouped", reportID, report.GroupingCount, report.UngroupedCount))
+	
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(aggregates)
+	}
+	
+	names := groupingNames(ctx)
+	rows := [][]string{
+		{"Grouping", "Total", "Valid", "Invalid", "Down", "Min URL", "Max URL"},
+	}
+	for _, rg := range aggregates {
+		rows = append(rows, []string{
+			truncate(nameOr(names, rg.GroupingID), 30),
+			fmt.Sprintf("%d", rg.TotalEntryCount),
+			fmt.Sprintf("%d", rg.ValidEntryCount),
+			fmt.Sprintf("%d", rg.InvalidEntryCount),
+			fmt.Sprintf("%d", rg.DownEntryCount),
+			truncate(derefString(rg.MinURL), 40),
+			truncate(derefString(rg.MaxURL), 40),
+		})
+	}
+	
+	fmt.Println()
+	ctx.Formatter.Print(rows)
+	fmt.Println()
+	
+	return nil
+}
+
+// groupingUser returns --user-id or the configured default user
+func groupingUser(ctx *CLIContext) string {
+	if groupingUserID != "" {
+		return groupingUserID
+	}
+	return ctx.Config.DefaultUserID
+}
+
+// groupingDB returns the database scoped to the grouping user
+func groupingDB(ctx *CLIContext) repositories.Database {
+	return ctx.UserDB(groupingUser(ctx))
+}
+
+// findGrouping resolves a grouping by ID, or by name among the user's groupings
+func findGrouping(ctx *CLIContext, userID, ref string) (*models.Group, error) {
+	if grouping, err := groupingDB(ctx).Groupings().GetByID(context.Background(), ref); err == nil && grouping != nil && grouping.UserID == userID {
+		return grouping, nil
+	}
+	
+	groupings, err := groupingDB(ctx).Groupings().List(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groupings: %w", err)
+	}
+	for _, grouping := range groupings {
+		if grouping.UserID == userID && grouping.Name == ref {
+			return grouping, nil
+		}
+	}
+	
+	return nil, fmt.Errorf("grouping not found: %s", ref)
+}
+
+// groupingNames maps grouping IDs to names
+func groupingNames(ctx *CLIContext) map[string]string {
+	names := make(map[string]string)
+	groupings, err := groupingDB(ctx).Groupings().List(context.Background())
+	if err != nil {
+		return names
+	}
+	for _, grouping := range groupings {
+		names[grouping.ID] = grouping.Name
+	}
+	return names
+}
+
+func nameOr(names map[string]string, id string) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return id
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
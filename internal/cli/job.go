@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+	"jonopens/sitemapper/pkg/scheduler"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+var jobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Manage report jobs",
+	Long: `List, inspect, cancel, retry and run report jobs.
+A job fetches a sitemap and stores it as a new report. Jobs are queued by
+schedules and move from pending to running to completed, failed, cancelled
+or timed_out. Commands only see the jobs of the user given by --user-id, or
+the configured default user.`,
+}
+
+var jobListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List jobs",
+	Long:  `List report jobs, newest first.`,
+	RunE:  runJobList,
+}
+
+var jobGetCmd = &cobra.Command{
+	Use:   "get <job-id>",
+	Short: "Show a job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runJobGet,
+}
+
+var jobCancelCmd = &cobra.Command{
+	Use:   "cancel <job-id>",
+	Short: "Cancel a pending or running job",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runJobCancel,
+}
+
+var jobRetryCmd = &cobra.Command{
+	Use:   "retry <job-id>",
+	Short: "Queue a failed, cancelled or timed out job again",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runJobRetry,
+}
+
+var jobRunCmd = &cobra.Command{
+	Use:   "run [job-id]",
+	Short: "Run pending jobs",
+	Long: `Run pending jobs, oldest first, until interrupted.
+With a job ID, run only that job. With --once, run the jobs pending now and exit.
+Jobs still running when the runner is interrupted return to pending.
+Like serve, the runner works through every user's jobs; with --user-id it
+only runs that user's.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runJobRun,
+}
+
+var (
+	jobUserID     string
+	jobListStatus string
+	jobListLimit  int
+
+	jobRunOnce     bool
+	jobRunInterval time.Duration
+	jobRunTimeout  time.Duration
+)
+
+func init() {
+	jobCmd.PersistentFlags().StringVar(&jobUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+
+	// List command flags
+	jobListCmd.Flags().StringVar(&jobListStatus, "status", "", "filter by status (pending, running, completed, failed, cancelled, timed_out)")
+	jobListCmd.Flags().IntVar(&jobListLimit, "limit", 50, "maximum number of jobs to list")
+
+	// Run command flags
+	jobRunCmd.Flags().BoolVar(&jobRunOnce, "once", false, "run the jobs pending now and exit")
+	jobRunCmd.Flags().DurationVar(&jobRunInterval, "interval", scheduler.DefaultPollInterval, "how often to check for pending jobs")
+	jobRunCmd.Flags().DurationVar(&jobRunTimeout, "timeout", 0, "default per-job timeout (defaults to config job_timeout)")
+
+	// Add subcommands
+	jobCmd.AddCommand(jobListCmd)
+	jobCmd.AddCommand(jobGetCmd)
+	jobCmd.AddCommand(jobCancelCmd)
+	jobCmd.AddCommand(jobRetryCmd)
+	jobCmd.AddCommand(jobRunCmd)
+}
+
+// newJobService creates a job service on db configured from the CLI context.
+// Only services that run jobs need normalizer.
+func newJobService(ctx *CLIContext, db repositories.Database, timeout time.Duration, normalizer *sitemap.Normalizer) *services.JobService {
+	if timeout <= 0 {
+		timeout = ctx.Config.JobTimeout
+	}
+	return services.NewJobService(db, services.JobOptions{
+		Timeout:         timeout,
+		MaxUploadSize:   ctx.Config.MaxUploadSize,
+		WorkerCount:     ctx.Config.WorkerCount,
+		LivenessRate:    defaultLivenessRate,
+		GroupDepth:      ctx.Config.GroupingDepth,
+		UploadDir:       ctx.Config.UploadDir,
+		UploadRetention: ctx.Config.UploadRetention,
+		Normalizer:      normalizer,
+		Progress:        ctx.Formatter.Info,
+	})
+}
+
+func runJobList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := jobUser(ctx)
+
+	jobs, err := newJobService(ctx, ctx.UserDB(userID), 0, nil).ListJobs(context.Background(), repositories.JobFilters{
+		Status: jobListStatus,
+		UserID: userID,
+		Limit:  jobListLimit,
+	})
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list jobs: %v", err))
+		return err
+	}
+
+	if len(jobs) == 0 {
+		ctx.Formatter.Info("No jobs found")
+		return nil
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(jobs)
+	}
+
+	fmt.Printf("\nFound %d job(s):\n\n", len(jobs))
+
+	rows := [][]string{
+		{"ID", "Source", "Status", "Report ID", "Created", "Completed"},
+	}
+
+	for _, job := range jobs {
+		rows = append(rows, []string{
+			truncate(job.ID, 20),
+			truncate(job.SourceLocation, 40),
+			job.Status,
+			truncate(derefString(job.ReportID), 20),
+			job.CreatedAt.Format("2006-01-02 15:04"),
+			formatOptionalTime(job.CompletedAt),
+		})
+	}
+
+	ctx.Formatter.Print(rows)
+
+	fmt.Printf("\nTotal: %d job(s)\n", len(jobs))
+
+	return nil
+}
+
+func runJobGet(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	job, err := newJobService(ctx, ctx.UserDB(jobUser(ctx)), 0, nil).GetJob(context.Background(), args[0])
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to get job: %v", err))
+		return err
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(job)
+	}
+
+	printJob(job)
+
+	return nil
+}
+
+func runJobCancel(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	if _, err := newJobService(ctx, ctx.UserDB(jobUser(ctx)), 0, nil).CancelJob(context.Background(), args[0]); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to cancel job: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Job %s cancelled", args[0]))
+	return nil
+}
+
+func runJobRetry(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	if _, err := newJobService(ctx, ctx.UserDB(jobUser(ctx)), 0, nil).RetryJob(context.Background(), args[0]); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to retry job: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Job %s queued for retry", args[0]))
+	return nil
+}
+
+func runJobRun(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	// A job ID or --user-id limits the runner to one user's jobs
+	db := ctx.DB
+	if len(args) == 1 || jobUserID != "" {
+		db = ctx.UserDB(jobUser(ctx))
+	}
+	normalizer, err := urlNormalizer(ctx, cmd)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+	service := newJobService(ctx, db, jobRunTimeout, normalizer)
+
+	// Stop on interrupt; running jobs return to pending
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(args) == 1 {
+		job, err := service.Claim(runCtx, args[0])
+		if err != nil {
+			ctx.Formatter.Error(fmt.Sprintf("Failed to run job: %v", err))
+			return err
+		}
+		if err := service.Execute(runCtx, job); err != nil {
+			ctx.Formatter.Error(err.Error())
+			return err
+		}
+		printJobOutcome(ctx, job)
+		return nil
+	}
+
+	tick := func(tickCtx context.Context, now time.Time) error {
+		ran, err := service.RunPending(tickCtx)
+		for _, job := range ran {
+			printJobOutcome(ctx, job)
+		}
+		return err
+	}
+
+	if jobRunOnce {
+		return tick(runCtx, time.Now())
+	}
+
+	runner := scheduler.New(tick)
+	runner.Interval = jobRunInterval
+	runner.OnError = func(err error) {
+		ctx.Formatter.Error(fmt.Sprintf("Job runner: %v", err))
+	}
+
+	ctx.Formatter.Info(fmt.Sprintf("Job runner started, checking every %s (Ctrl+C to stop)", jobRunInterval))
+	runner.Start(runCtx)
+	<-runner.Done()
+	ctx.Formatter.Info("Job runner stopped")
+
+	return nil
+}
+
+// printJobOutcome reports the final status of a job
+func printJobOutcome(ctx *CLIContext, job *models.ReportJob) {
+	switch job.Status {
+	case models.ReportJobStatusCompleted:
+		ctx.Formatter.Success(fmt.Sprintf("Job %s completed: report %s", job.ID, derefString(job.ReportID)))
+	case models.ReportJobStatusPending:
+		ctx.Formatter.Warning(fmt.Sprintf("Job %s interrupted and returned to pending", job.ID))
+	default:
+		ctx.Formatter.Error(fmt.Sprintf("Job %s %s: %s", job.ID, job.Status, derefString(job.ErrorMessage)))
+	}
+}
+
+func printJob(job *models.ReportJob) {
+	fmt.Printf("\nJob Details:\n")
+	fmt.Printf("  ID:         %s\n", job.ID)
+	fmt.Printf("  User ID:    %s\n", job.UserID)
+	fmt.Printf("  Source:     %s\n", job.SourceLocation)
+	fmt.Printf("  Type:       %s\n", job.JobType)
+	if job.CompressionFormat != nil {
+		fmt.Printf("  Compressed: %s\n", *job.CompressionFormat)
+	}
+	if job.ReportScheduleID != nil {
+		fmt.Printf("  Schedule:   %s\n", *job.ReportScheduleID)
+	}
+	fmt.Printf("  Status:     %s\n", job.Status)
+	if job.ErrorMessage != nil {
+		fmt.Printf("  Error:      %s\n", *job.ErrorMessage)
+	}
+	if job.ReportID != nil {
+		fmt.Printf("  Report ID:  %s\n", *job.ReportID)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("Options:\n")
+	fmt.Printf("  Liveness:   %t\n", job.ShouldCheckEntryLiveness)
+	fmt.Printf("  Validate:   %t\n", job.ShouldCheckForValidEntries)
+	fmt.Printf("  Max Stored: %d\n", job.MaxStoredEntries)
+	if job.TimeoutSeconds > 0 {
+		fmt.Printf("  Timeout:    %s\n", time.Duration(job.TimeoutSeconds)*time.Second)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("Timestamps:\n")
+	fmt.Printf("  Created:    %s\n", job.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Started:    %s\n", formatOptionalTime(job.StartedAt))
+	fmt.Printf("  Completed:  %s\n", formatOptionalTime(job.CompletedAt))
+	fmt.Println()
+}
+
+// jobUser returns the --user-id flag or the configured default user
+func jobUser(ctx *CLIContext) string {
+	if jobUserID != "" {
+		return jobUserID
+	}
+	return ctx.Config.DefaultUserID
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/database/migrate"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+	Long: `Apply, revert and inspect schema migrations for SQL databases.
+Migrations are embedded in the binary and recorded in the schema_migrations table.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	RunE:  runMigrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert applied migrations",
+	Long: `Revert the most recently applied migrations, newest first.
+Reverting the initial migration drops every table and its data.
+SQLite databases apply pending migrations whenever they are opened.`,
+	RunE: runMigrateDown,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	RunE:  runMigrateStatus,
+}
+
+var migrateSteps int
+
+func init() {
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of migrations to revert")
+
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+}
+
+// schemaMigrator returns the migrator of the configured database
+func schemaMigrator(ctx *CLIContext) (*migrate.Migrator, error) {
+	db, ok := ctx.DB.(migrate.Migratable)
+	if !ok {
+		return nil, fmt.Errorf("database type %s has no schema migrations", ctx.Config.DatabaseType)
+	}
+	return db.Migrator(), nil
+}
+
+func runMigrateUp(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	migrator, err := schemaMigrator(ctx)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		ctx.Formatter.Success(fmt.Sprintf("Applied %04d_%s", m.Version, m.Name))
+	}
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to migrate: %v", err))
+		return err
+	}
+
+	if len(applied) == 0 {
+		ctx.Formatter.Info("Schema is up to date")
+	}
+	return nil
+}
+
+func runMigrateDown(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	if migrateSteps < 1 {
+		return fmt.Errorf("--steps must be at least 1")
+	}
+
+	migrator, err := schemaMigrator(ctx)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+
+	reverted, err := migrator.Down(context.Background(), migrateSteps)
+	for _, m := range reverted {
+		ctx.Formatter.Success(fmt.Sprintf("Reverted %04d_%s", m.Version, m.Name))
+	}
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to revert migration: %v", err))
+		return err
+	}
+
+	if len(reverted) == 0 {
+		ctx.Formatter.Info("No applied migrations to revert")
+	}
+	return nil
+}
+
+func runMigrateStatus(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	migrator, err := schemaMigrator(ctx)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to read migration status: %v", err))
+		return err
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(statuses)
+	}
+
+	rows := [][]string{
+		{"Version", "Name", "Status", "Applied At"},
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		state := "applied"
+		if !status.Applied {
+			state = "pending"
+			pending++
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("%04d", status.Version),
+			status.Name,
+			state,
+			formatOptionalTime(status.AppliedAt),
+		})
+	}
+
+	fmt.Println()
+	ctx.Formatter.Print(rows)
+	fmt.Printf("\n%d migration(s), %d pending\n", len(statuses), pending)
+
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
+	"jonopens/sitemapper/pkg/sitemap"
 )
 
 // Format represents the output format type
@@ -138,29 +139,32 @@ func (f *Formatter) Info(message string) {
 	}
 }
 
-// PrintDiff prints a diff with colors
-func (f *Formatter) PrintDiff(added, removed, unchanged []string) {
-	if f.colorOutput {
-		green := color.New(color.FgGreen)
-		red := color.New(color.FgRed)
-		
-		for _, item := range added {
-			green.Fprintf(f.writer, "+ %s\n", item)
-		}
-		for _, item := range removed {
-			red.Fprintf(f.writer, "- %s\n", item)
-		}
-		for _, item := range unchanged {
-			fmt.Fprintf(f.writer, "  %s\n", item)
-		}
-	} else {
-		for _, item := range added {
-			fmt.Fprintf(f.writer, "+ %s\n", item)
-		}
-		for _, item := range removed {
-			fmt.Fprintf(f.writer, "- %s\n", item)
+// PrintDiff prints a sitemap diff with colors: added URLs in green, removed in
+// red and changed in yellow with their old and new field values
+func (f *Formatter) PrintDiff(diff *sitemap.Diff, showUnchanged bool) {
+	green := color.New(color.FgGreen)
+	red := color.New(color.FgRed)
+	yellow := color.New(color.FgYellow)
+	if !f.colorOutput {
+		green.DisableColor()
+		red.DisableColor()
+		yellow.DisableColor()
+	}
+	
+	for _, item := range diff.Added {
+		green.Fprintf(f.writer, "+ %s\n", item)
+	}
+	for _, item := range diff.Removed {
+		red.Fprintf(f.writer, "- %s\n", item)
+	}
+	for _, change := range diff.Changed {
+		yellow.Fprintf(f.writer, "~ %s\n", change.Loc)
+		for _, field := range change.Fields {
+			yellow.Fprintf(f.writer, "    %s: %q -> %q\n", field.Field, field.Old, field.New)
 		}
-		for _, item := range unchanged {
+	}
+	if showUnchanged {
+		for _, item := range diff.Unchanged {
 			fmt.Fprintf(f.writer, "  %s\n", item)
 		}
 	}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+// Validation report formats
+const (
+	ReportFormatTable = "table"
+	ReportFormatJSON  = "json"
+	ReportFormatSARIF = "sarif"
+)
+
+// PrintValidationReport renders a validation report as a table, JSON or SARIF
+func (f *Formatter) PrintValidationReport(report *sitemap.ValidationReport, format string) error {
+	switch format {
+	case ReportFormatJSON:
+		return f.printJSON(report)
+	case ReportFormatSARIF:
+		encoder := json.NewEncoder(f.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report.SARIF("sitemapper", ""))
+	case ReportFormatTable, string(FormatText), "":
+		return f.printValidationTable(report)
+	default:
+		return fmt.Errorf("unsupported report format: %s", format)
+	}
+}
+
+func (f *Formatter) printValidationTable(report *sitemap.ValidationReport) error {
+	fmt.Fprintf(f.writer, "\nValidation Report: %s\n", report.Source)
+	fmt.Fprintf(f.writer, "  Entries:  %d\n", report.EntryCount)
+	fmt.Fprintf(f.writer, "  Errors:   %d\n", report.Count(sitemap.SeverityError))
+	fmt.Fprintf(f.writer, "  Warnings: %d\n", report.Count(sitemap.SeverityWarning))
+	fmt.Fprintf(f.writer, "  Info:     %d\n\n", report.Count(sitemap.SeverityInfo))
+
+	if len(report.Findings) == 0 {
+		return nil
+	}
+
+	rows := [][]string{
+		{"Severity", "Rule", "Entry", "Line:Col", "Message"},
+	}
+	for _, finding := range report.Findings {
+		entry := "-"
+		if finding.Index >= 0 {
+			entry = fmt.Sprintf("%d", finding.Index)
+		}
+		position := "-"
+		if finding.Line > 0 {
+			position = fmt.Sprintf("%d:%d", finding.Line, finding.Column)
+		}
+		message := finding.Message
+		if len(message) > 80 {
+			message = message[:77] + "..."
+		}
+		rows = append(rows, []string{
+			string(finding.Severity),
+			finding.Rule,
+			entry,
+			position,
+			message,
+		})
+	}
+
+	if err := f.printTable(rows); err != nil {
+		return err
+	}
+	fmt.Fprintln(f.writer)
+	return nil
+}
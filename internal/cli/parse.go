@@ -1,20 +1,30 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
-	"net/url"
 	"os"
-	"time"
 
 	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/cli/output"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
 	"jonopens/sitemapper/pkg/http"
 	"jonopens/sitemapper/pkg/sitemap"
 )
 
 var (
-	parseValidate  bool
-	parseShowStats bool
+	parseValidate      bool
+	parseShowStats     bool
+	parseRecursive     bool
+	parseMaxDepth      int
+	parseStrict        bool
+	parseReportFormat  string
+	parseReportFile    string
+	parseCheckLiveness bool
+	parseLivenessRate  float64
 )
 
 var parseCmd = &cobra.Command{
@@ -22,14 +32,30 @@ var parseCmd = &cobra.Command{
 	Short: "Parse and validate a sitemap from file or URL",
 	Long: `Parse and optionally validate an XML sitemap.
 Supports both local files and remote URLs.
-Can detect sitemap type (sitemap vs sitemap index) and show statistics.`,
+Can detect sitemap type (sitemap vs sitemap index) and show statistics.
+Use --recursive to fetch and merge every child sitemap of an index.
+Use --strict to check full sitemaps.org protocol conformance (absolute same-host
+URLs, URL length, W3C datetimes, entity escaping, size limits, duplicates and
+namespace declaration). Validation lists every finding with its rule, severity,
+entry index and line/column; use --report-format sarif for CI annotations.
+Gzip (.xml.gz) and zip sources are decompressed automatically; each file in
+a zip archive is parsed separately.
+Use --check-liveness to request every URL (HEAD, falling back to GET) with
+worker_count concurrent workers and report the URLs that are down.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runParse,
 }
 
 func init() {
 	parseCmd.Flags().BoolVar(&parseValidate, "validate", false, "validate sitemap structure")
+	parseCmd.Flags().BoolVar(&parseStrict, "strict", false, "check full sitemaps.org protocol conformance and report every finding (implies --validate)")
+	parseCmd.Flags().StringVar(&parseReportFormat, "report-format", "", "validation report format (table, json, sarif); defaults to --format")
+	parseCmd.Flags().StringVar(&parseReportFile, "report-file", "", "write the validation report to a file instead of stdout")
 	parseCmd.Flags().BoolVar(&parseShowStats, "show-stats", false, "show sitemap statistics")
+	parseCmd.Flags().BoolVar(&parseRecursive, "recursive", false, "fetch and merge child sitemaps of a sitemap index")
+	parseCmd.Flags().IntVar(&parseMaxDepth, "max-depth", sitemap.DefaultMaxDepth, "maximum nesting depth when resolving sitemap indexes")
+	parseCmd.Flags().BoolVar(&parseCheckLiveness, "check-liveness", false, "request every URL and report which are down")
+	parseCmd.Flags().Float64Var(&parseLivenessRate, "liveness-rate", defaultLivenessRate, "maximum liveness requests per second to a single host (0 = unlimited)")
 }
 
 func runParse(cmd *cobra.Command, args []string) error {
@@ -38,12 +64,44 @@ func runParse(cmd *cobra.Command, args []string) error {
 	
 	ctx.Formatter.Info(fmt.Sprintf("Parsing sitemap from: %s", source))
 	
-	// Read sitemap data
-	data, err := readSitemapSource(source)
+	// Read sitemap data, expanding compressed sources
+	format, sources, closeSources, err := openSitemapSources(ctx, source)
 	if err != nil {
 		ctx.Formatter.Error(fmt.Sprintf("Failed to read sitemap: %v", err))
 		return err
 	}
+	defer closeSources()
+	
+	if format != nil {
+		ctx.Formatter.Info(fmt.Sprintf("Detected %s compression (%d sitemap file(s))", *format, len(sources)))
+	}
+	
+	for _, src := range sources {
+		data, err := io.ReadAll(src.Reader)
+		if err != nil {
+			ctx.Formatter.Error(fmt.Sprintf("Failed to read sitemap: %v", err))
+			return err
+		}
+		
+		if len(sources) > 1 {
+			ctx.Formatter.Info(fmt.Sprintf("Parsing %s", src.Name))
+		}
+		
+		if err := parseSitemapData(ctx, src.Name, data); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+func parseSitemapData(ctx *CLIContext, source string, data []byte) error {
+	// Validation runs over the raw document so findings carry line/column positions
+	if parseValidate || parseStrict {
+		if err := validateSitemapData(ctx, source, data); err != nil {
+			return err
+		}
+	}
 	
 	// Parse sitemap
 	parser := sitemap.NewParser()
@@ -59,6 +117,9 @@ func runParse(cmd *cobra.Command, args []string) error {
 	case "sitemap":
 		return parseSitemap(ctx, parser, data)
 	case "index":
+		if parseRecursive {
+			return parseResolvedIndex(ctx, source, data)
+		}
 		return parseSitemapIndex(ctx, parser, data)
 	default:
 		return fmt.Errorf("unknown sitemap type: %s", sitemapType)
@@ -72,16 +133,6 @@ func parseSitemap(ctx *CLIContext, parser *sitemap.Parser, data []byte) error {
 		return err
 	}
 	
-	// Validate if requested
-	if parseValidate {
-		validator := sitemap.NewValidator()
-		if err := validator.Validate(sm); err != nil {
-			ctx.Formatter.Error(fmt.Sprintf("Validation failed: %v", err))
-			return err
-		}
-		ctx.Formatter.Success("Sitemap is valid")
-	}
-	
 	// Show stats if requested
 	if parseShowStats {
 		showSitemapStats(ctx, sm)
@@ -89,7 +140,10 @@ func parseSitemap(ctx *CLIContext, parser *sitemap.Parser, data []byte) error {
 	
 	// Output sitemap data
 	if ctx.Config.OutputFormat == "json" {
-		return ctx.Formatter.Print(sm)
+		if err := ctx.Formatter.Print(sm); err != nil {
+			return err
+		}
+		return checkURLLiveness(ctx, sm.URLs)
 	}
 	
 	ctx.Formatter.Success(fmt.Sprintf("Successfully parsed sitemap with %d URLs", len(sm.URLs)))
@@ -123,7 +177,7 @@ func parseSitemap(ctx *CLIContext, parser *sitemap.Parser, data []byte) error {
 		ctx.Formatter.Print(rows)
 	}
 	
-	return nil
+	return checkURLLiveness(ctx, sm.URLs)
 }
 
 func parseSitemapIndex(ctx *CLIContext, parser *sitemap.Parser, data []byte) error {
@@ -140,6 +194,221 @@ func parseSitemapIndex(ctx *CLIContext, parser *sitemap.Parser, data []byte) err
 	
 	ctx.Formatter.Success(fmt.Sprintf("Successfully parsed sitemap index with %d sitemaps", len(index.Sitemaps)))
 	
+	if len(index.Sitemaps) > 0 {
+		fmt.Println("\nChild Sitemaps:")
+		rows := [][]string{
+			{"Location", "Last Modified"},
+		}
+		for _, ref := range index.Sitemaps {
+			rows = append(rows, []string{truncate(ref.Loc, 70), ref.LastMod})
+		}
+		ctx.Formatter.Print(rows)
+	}
+	
+	ctx.Formatter.Info("Use --recursive to fetch and merge the child sitemaps")
+	
+	return nil
+}
+
+func parseResolvedIndex(ctx *CLIContext, source string, data []byte) error {
+	resolver := sitemap.NewResolver(sitemapFetcher(ctx))
+	resolver.MaxDepth = parseMaxDepth
+	
+	resolved, err := resolver.Resolve(context.Background(), source, data)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to resolve sitemap index: %v", err))
+		return err
+	}
+	
+	if ctx.Config.OutputFormat == "json" {
+		if err := ctx.Formatter.Print(resolved); err != nil {
+			return err
+		}
+		return checkURLLiveness(ctx, resolved.Sitemap().URLs)
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Resolved %d child sitemaps with %d URLs", len(resolved.Children), len(resolved.URLs)))
+	
+	fmt.Println("\nChild Sitemaps:")
+	rows := [][]string{
+		{"Location", "Depth", "URLs", "Status"},
+	}
+	for _, child := range resolved.Children {
+		status := "ok"
+		if child.IsIndex {
+			status = "index"
+		}
+		if child.Error != "" {
+			status = truncate(child.Error, 40)
+		}
+		rows = append(rows, []string{
+			truncate(child.Loc, 60),
+			fmt.Sprintf("%d", child.Depth),
+			fmt.Sprintf("%d", child.URLCount),
+			status,
+		})
+	}
+	ctx.Formatter.Print(rows)
+	
+	for _, child := range resolved.Children {
+		if child.Error != "" {
+			ctx.Formatter.Warning(fmt.Sprintf("%s: %s", child.Loc, child.Error))
+		}
+	}
+	
+	// Child documents are streamed, so merged URLs are validated without positions
+	if parseValidate || parseStrict {
+		validator := sitemap.NewValidator()
+		if err := printValidationReport(ctx, validator.ValidateURLs(source, resolved.Sitemap().URLs)); err != nil {
+			return err
+		}
+	}
+	
+	if parseShowStats {
+		showSitemapStats(ctx, resolved.Sitemap())
+	}
+	
+	return checkURLLiveness(ctx, resolved.Sitemap().URLs)
+}
+
+// checkURLLiveness requests every URL when --check-liveness is set and prints
+// a summary with the URLs that are down
+func checkURLLiveness(ctx *CLIContext, urls []sitemap.URL) error {
+	if !parseCheckLiveness || len(urls) == 0 {
+		return nil
+	}
+	
+	ctx.Formatter.Info(fmt.Sprintf("Checking liveness of %d URLs with %d workers...", len(urls), ctx.Config.WorkerCount))
+	
+	locs := make([]string, len(urls))
+	for i, u := range urls {
+		locs[i] = u.Loc
+	}
+	
+	liveness := services.NewLivenessService(ctx.Config.WorkerCount, parseLivenessRate)
+	results := liveness.CheckURLs(context.Background(), locs)
+	
+	live, redirected := 0, 0
+	var down []http.LivenessResult
+	for _, result := range results {
+		if result.IsLive {
+			live++
+		} else {
+			down = append(down, result)
+		}
+		if len(result.RedirectChain) > 0 {
+			redirected++
+		}
+	}
+	
+	if ctx.Config.OutputFormat == "json" {
+		entries := make([]map[string]interface{}, 0, len(results))
+		for _, result := range results {
+			entry := map[string]interface{}{
+				"url":              result.URL,
+				"final_url":        result.FinalURL,
+				"status_code":      result.StatusCode,
+				"is_live":          result.IsLive,
+				"method":           result.Method,
+				"redirect_chain":   result.RedirectChain,
+				"response_time_ms": result.ResponseTime.Milliseconds(),
+			}
+			if result.Err != nil {
+				entry["error"] = result.Err.Error()
+			}
+			entries = append(entries, entry)
+		}
+		return ctx.Formatter.Print(map[string]interface{}{
+			"live_count": live,
+			"down_count": len(down),
+			"results":    entries,
+		})
+	}
+	
+	fmt.Printf("\nLiveness:\n")
+	fmt.Printf("  Live:       %d\n", live)
+	fmt.Printf("  Down:       %d\n", len(down))
+	fmt.Printf("  Redirected: %d\n", redirected)
+	
+	if len(down) > 0 {
+		fmt.Println("\nDown URLs:")
+		rows := [][]string{
+			{"URL", "Status", "Time", "Error"},
+		}
+		for _, result := range down {
+			status := "-"
+			if result.StatusCode != 0 {
+				status = fmt.Sprintf("%d", result.StatusCode)
+			}
+			errMsg := ""
+			if result.Err != nil {
+				errMsg = truncate(result.Err.Error(), 40)
+			}
+			rows = append(rows, []string{
+				truncate(result.URL, 60),
+				status,
+				fmt.Sprintf("%dms", result.ResponseTime.Milliseconds()),
+				errMsg,
+			})
+		}
+		ctx.Formatter.Print(rows)
+	}
+	fmt.Println()
+	
+	return nil
+}
+
+// validateSitemapData builds a validation report for a raw sitemap document
+// and renders it. It fails if the report contains any error findings.
+func validateSitemapData(ctx *CLIContext, source string, data []byte) error {
+	validator := sitemap.NewValidator()
+	report, err := validator.ValidateDocument(context.Background(), bytes.NewReader(data), source, sitemap.ConformanceOptions{
+		Strict: parseStrict,
+	})
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Validation failed: %v", err))
+		return err
+	}
+	
+	return printValidationReport(ctx, report)
+}
+
+// printValidationReport renders the report to stdout or --report-file and
+// returns an error if it contains error findings
+func printValidationReport(ctx *CLIContext, report *sitemap.ValidationReport) error {
+	format := parseReportFormat
+	if format == "" {
+		format = output.ReportFormatTable
+		if ctx.Config.OutputFormat == "json" {
+			format = output.ReportFormatJSON
+		}
+	}
+	
+	formatter := ctx.Formatter
+	if parseReportFile != "" {
+		f, err := os.Create(parseReportFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer f.Close()
+		formatter = output.NewFormatter(output.Format(ctx.Config.OutputFormat), false)
+		formatter.SetWriter(f)
+	}
+	
+	if err := formatter.PrintValidationReport(report, format); err != nil {
+		return err
+	}
+	if parseReportFile != "" {
+		ctx.Formatter.Info(fmt.Sprintf("Validation report written to %s", parseReportFile))
+	}
+	
+	errorCount := report.Count(sitemap.SeverityError)
+	if errorCount > 0 {
+		ctx.Formatter.Error(fmt.Sprintf("Validation failed: %d error(s), %d warning(s)", errorCount, report.Count(sitemap.SeverityWarning)))
+		return fmt.Errorf("sitemap validation failed with %d error(s)", errorCount)
+	}
+	
+	ctx.Formatter.Success(fmt.Sprintf("Sitemap is valid (%d warning(s))", report.Count(sitemap.SeverityWarning)))
 	return nil
 }
 
@@ -149,6 +418,10 @@ func showSitemapStats(ctx *CLIContext, sm *sitemap.Sitemap) {
 		"With LastMod": countWithLastMod(sm),
 		"With Priority": countWithPriority(sm),
 		"With ChangeFreq": countWithChangeFreq(sm),
+		"With Images":     countWith(sm, func(u *sitemap.URL) bool { return len(u.Images) > 0 }),
+		"With Videos":     countWith(sm, func(u *sitemap.URL) bool { return len(u.Videos) > 0 }),
+		"With News":       countWith(sm, func(u *sitemap.URL) bool { return u.News != nil }),
+		"With Hreflang":   countWith(sm, func(u *sitemap.URL) bool { return len(u.Alternates) > 0 }),
 	}
 	
 	fmt.Println("\nStatistics:")
@@ -177,6 +450,16 @@ func countWithPriority(sm *sitemap.Sitemap) int {
 	return count
 }
 
+func countWith(sm *sitemap.Sitemap, match func(*sitemap.URL) bool) int {
+	count := 0
+	for i := range sm.URLs {
+		if match(&sm.URLs[i]) {
+			count++
+		}
+	}
+	return count
+}
+
 func countWithChangeFreq(sm *sitemap.Sitemap) int {
 	count := 0
 	for _, u := range sm.URLs {
@@ -187,26 +470,22 @@ func countWithChangeFreq(sm *sitemap.Sitemap) int {
 	return count
 }
 
-func readSitemapSource(source string) ([]byte, error) {
-	// Check if source is a URL
-	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
-		// Fetch from URL
-		client := http.NewRetryClient(3, 30*time.Second)
-		resp, err := client.Get(source)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch URL: %w", err)
-		}
-		defer resp.Body.Close()
-		
-		if resp.StatusCode != 200 {
-			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-		}
-		
-		return io.ReadAll(resp.Body)
-	}
-	
-	// Read from file
-	return os.ReadFile(source)
+// readSitemapSource reads a single, decompressed sitemap document into memory
+func readSitemapSource(ctx *CLIContext, source string) ([]byte, error) {
+	return services.NewSourceService(ctx.Config.MaxUploadSize).Read(context.Background(), source)
+}
+
+// openSitemapSources opens a sitemap file or URL for streaming and expands
+// gzip and zip compression into one source per sitemap document. The caller
+// must call the returned close function once done with the sources.
+func openSitemapSources(ctx *CLIContext, source string) (*models.CompressionFormat, []*services.DecompressedSource, func(), error) {
+	return services.NewSourceService(ctx.Config.MaxUploadSize).Open(context.Background(), source)
+}
+
+// sitemapFetcher returns a fetcher that opens child sitemaps the same way
+// top-level sources are opened
+func sitemapFetcher(ctx *CLIContext) sitemap.Fetcher {
+	return services.NewSourceService(ctx.Config.MaxUploadSize).Fetcher()
 }
 
 func truncate(s string, maxLen int) string {
@@ -11,7 +11,9 @@ import (
 var reportCmd = &cobra.Command{
 	Use:   "report",
 	Short: "Manage reports",
-	Long:  `List, view, and manage sitemap reports.`,
+	Long: `List, view, and manage sitemap reports.
+Commands only see the reports of the user given by --user-id, or the
+configured default user.`,
 }
 
 var reportListCmd = &cobra.Command{
@@ -30,13 +32,14 @@ var reportGetCmd = &cobra.Command{
 }
 
 var (
-	reportListUserID string
-	reportListLimit  int
+	reportUserID    string
+	reportListLimit int
 )
 
 func init() {
+	reportCmd.PersistentFlags().StringVar(&reportUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+
 	// List command flags
-	reportListCmd.Flags().StringVar(&reportListUserID, "user-id", "", "filter by user ID (defaults to config default_user_id)")
 	reportListCmd.Flags().IntVar(&reportListLimit, "limit", 50, "maximum number of reports to list")
 	
 	// Add subcommands
@@ -46,18 +49,14 @@ func init() {
 
 func runReportList(cmd *cobra.Command, args []string) error {
 	ctx := GetContext()
+	userID := reportUser(ctx)
 	
-	// Use default user ID from config if not provided
-	if reportListUserID == "" {
-		reportListUserID = ctx.Config.DefaultUserID
-	}
-	
-	ctx.Formatter.Info(fmt.Sprintf("Listing reports for user: %s", reportListUserID))
+	ctx.Formatter.Info(fmt.Sprintf("Listing reports for user: %s", userID))
 	
 	// Get reports from database
-	reportRepo := ctx.DB.Reports()
+	reportRepo := ctx.UserDB(userID).Reports()
 	reports, err := reportRepo.List(context.Background(), repositories.ReportFilters{
-		UserID: reportListUserID,
+		UserID: userID,
 		Limit:  reportListLimit,
 	})
 	if err != nil {
@@ -102,12 +101,13 @@ func runReportList(cmd *cobra.Command, args []string) error {
 
 func runReportGet(cmd *cobra.Command, args []string) error {
 	ctx := GetContext()
+	db := ctx.UserDB(reportUser(ctx))
 	reportID := args[0]
 	
 	ctx.Formatter.Info(fmt.Sprintf("Fetching report: %s", reportID))
 	
 	// Get report from database
-	reportRepo := ctx.DB.Reports()
+	reportRepo := db.Reports()
 	report, err := reportRepo.GetByID(context.Background(), reportID)
 	if err != nil {
 		ctx.Formatter.Error(fmt.Sprintf("Failed to get report: %v", err))
@@ -151,6 +151,12 @@ func runReportGet(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Child Sitemaps:    %d\n", report.ChildSitemapCount)
 	}
 	
+	if report.Normalization != nil {
+		fmt.Printf("\n")
+		fmt.Printf("Normalization:\n")
+		fmt.Printf("  Rules:             %s\n", *report.Normalization)
+	}
+	
 	fmt.Printf("\n")
 	fmt.Printf("Sampling:\n")
 	fmt.Printf("  Fully Stored:      %t\n", report.IsFullyStored)
@@ -166,7 +172,7 @@ func runReportGet(cmd *cobra.Command, args []string) error {
 	
 	// Get and display sample entries
 	ctx.Formatter.Info("\nFetching sample entries...")
-	entryRepo := ctx.DB.Entries()
+	entryRepo := db.Entries()
 	entries, err := entryRepo.List(context.Background(), repositories.EntryFilters{
 		ReportID: reportID,
 		Limit:    10,
@@ -198,3 +204,11 @@ func runReportGet(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+
+// reportUser returns the --user-id flag or the configured default user
+func reportUser(ctx *CLIContext) string {
+	if reportUserID != "" {
+		return reportUserID
+	}
+	return ctx.Config.DefaultUserID
+}
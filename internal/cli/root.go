@@ -95,6 +95,13 @@ func init() {
 	rootCmd.AddCommand(trackCmd)
 	rootCmd.AddCommand(reportCmd)
 	rootCmd.AddCommand(groupingCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(jobCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(userCmd)
+	rootCmd.AddCommand(tokenCmd)
 	rootCmd.AddCommand(interactiveCmd)
 }
 
@@ -103,3 +110,9 @@ func GetContext() *CLIContext {
 	return cliCtx
 }
 
+// UserDB returns the database scoped to one user, so commands can only read
+// and change that user's records
+func (c *CLIContext) UserDB(userID string) repositories.Database {
+	return repositories.ForUser(c.DB, userID)
+}
+
@@ -0,0 +1,354 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+	"jonopens/sitemapper/pkg/scheduler"
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage scheduled sitemap tracking",
+	Long: `Track sitemaps on a recurring cron schedule.
+Every run of a schedule queues a report job. "schedule run" starts the
+scheduler that queues jobs as they fall due and runs them, for every user;
+the other commands only see the schedules of the user given by --user-id, or
+the configured default user.
+Cron expressions have five fields (minute hour day-of-month month day-of-week)
+or use a shorthand: @hourly, @daily, @weekly, @monthly, @yearly.`,
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add <url|file>",
+	Short: "Add a tracking schedule",
+	Long: `Add a schedule that tracks a sitemap on a cron expression.
+Examples:
+  sitemapper schedule add https://example.com/sitemap.xml --cron "0 2 * * *"
+  sitemapper schedule add https://example.com/sitemap.xml --cron @hourly --jitter 10m --name hourly`,
+	Args: cobra.ExactArgs(1),
+	RunE: runScheduleAdd,
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tracking schedules",
+	RunE:  runScheduleList,
+}
+
+var schedulePauseCmd = &cobra.Command{
+	Use:   "pause <schedule-id>",
+	Short: "Pause a tracking schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSchedulePause,
+}
+
+var scheduleResumeCmd = &cobra.Command{
+	Use:   "resume <schedule-id>",
+	Short: "Resume a paused tracking schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runScheduleResume,
+}
+
+var scheduleDeleteCmd = &cobra.Command{
+	Use:   "delete <schedule-id>",
+	Short: "Delete a tracking schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runScheduleDelete,
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the scheduler",
+	Long: `Run the scheduler in the foreground, queueing a report job whenever a
+schedule falls due, until interrupted. Queued jobs run in the same process
+unless --run-jobs=false is given.
+A run picked up more than 5 minutes late (for example because the scheduler
+was stopped) is missed. Schedules with catch-up enabled queue one job for
+their missed runs; others skip them and wait for their next run.`,
+	RunE: runScheduleRun,
+}
+
+var (
+	scheduleUserID string
+
+	scheduleCron          string
+	scheduleName          string
+	scheduleTimezone      string
+	scheduleJitter        time.Duration
+	scheduleCatchUp       bool
+	scheduleCheckLiveness bool
+	scheduleMaxStored     int
+
+	scheduleInterval time.Duration
+	scheduleOnce     bool
+	scheduleRunJobs  bool
+)
+
+func init() {
+	scheduleCmd.PersistentFlags().StringVar(&scheduleUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+
+	// Add command flags
+	scheduleAddCmd.Flags().StringVar(&scheduleCron, "cron", "", "cron expression (required)")
+	scheduleAddCmd.Flags().StringVar(&scheduleName, "name", "", "name for the schedule (optional)")
+	scheduleAddCmd.Flags().StringVar(&scheduleTimezone, "timezone", "UTC", "IANA timezone the cron expression is evaluated in")
+	scheduleAddCmd.Flags().DurationVar(&scheduleJitter, "jitter", 0, "random delay of up to this duration added to every run")
+	scheduleAddCmd.Flags().BoolVar(&scheduleCatchUp, "catch-up", true, "queue one job for runs missed while the scheduler was down")
+	scheduleAddCmd.Flags().BoolVar(&scheduleCheckLiveness, "check-liveness", false, "request every URL and record its status (defaults to config enable_liveness)")
+	scheduleAddCmd.Flags().IntVar(&scheduleMaxStored, "max-stored-entries", -1, "store a stratified sample above this many URLs (0 = store all; defaults to config max_stored_entries)")
+	scheduleAddCmd.MarkFlagRequired("cron")
+
+	// Run command flags
+	scheduleRunCmd.Flags().DurationVar(&scheduleInterval, "interval", scheduler.DefaultPollInterval, "how often to check for due schedules")
+	scheduleRunCmd.Flags().BoolVar(&scheduleOnce, "once", false, "queue due jobs once and exit")
+	scheduleRunCmd.Flags().BoolVar(&scheduleRunJobs, "run-jobs", true, "run queued jobs in this process (disable when a separate \"job run\" worker runs them)")
+
+	// Add subcommands
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(schedulePauseCmd)
+	scheduleCmd.AddCommand(scheduleResumeCmd)
+	scheduleCmd.AddCommand(scheduleDeleteCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+}
+
+func runScheduleAdd(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	maxStored := ctx.Config.MaxStoredEntries
+	if scheduleMaxStored >= 0 {
+		maxStored = scheduleMaxStored
+	}
+	checkLiveness := ctx.Config.EnableLiveness
+	if cmd.Flags().Changed("check-liveness") {
+		checkLiveness = scheduleCheckLiveness
+	}
+
+	schedule := &models.ReportSchedule{
+		ID:                       uuid.New().String(),
+		UserID:                   scheduleUser(ctx),
+		Name:                     scheduleName,
+		SourceLocation:           args[0],
+		CronExpression:           scheduleCron,
+		Timezone:                 scheduleTimezone,
+		ShouldCheckEntryLiveness: checkLiveness,
+		MaxStoredEntries:         maxStored,
+		JitterSeconds:            int(scheduleJitter / time.Second),
+		CatchUp:                  scheduleCatchUp,
+		CreatedAt:                time.Now(),
+		UpdatedAt:                time.Now(),
+	}
+
+	service := services.NewScheduleService(ctx.UserDB(scheduleUser(ctx)))
+	if err := service.CreateSchedule(context.Background(), schedule); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to add schedule: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Schedule added with ID: %s", schedule.ID))
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(schedule)
+	}
+
+	printSchedule(schedule)
+
+	return nil
+}
+
+func runScheduleList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	userID := scheduleUser(ctx)
+
+	service := services.NewScheduleService(ctx.UserDB(userID))
+	schedules, err := service.ListSchedules(context.Background(), repositories.ScheduleFilters{
+		UserID:        userID,
+		IncludePaused: true,
+	})
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list schedules: %v", err))
+		return err
+	}
+
+	if len(schedules) == 0 {
+		ctx.Formatter.Info("No schedules found")
+		return nil
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(schedules)
+	}
+
+	fmt.Printf("\nFound %d schedule(s):\n\n", len(schedules))
+
+	rows := [][]string{
+		{"ID", "Name", "Source", "Cron", "Status", "Last Run", "Next Run"},
+	}
+
+	for _, schedule := range schedules {
+		status := "active"
+		if schedule.IsPaused {
+			status = "paused"
+		}
+		rows = append(rows, []string{
+			truncate(schedule.ID, 20),
+			truncate(schedule.Name, 20),
+			truncate(schedule.SourceLocation, 40),
+			schedule.CronExpression,
+			status,
+			formatOptionalTime(schedule.LastRunAt),
+			formatOptionalTime(schedule.NextRunAt),
+		})
+	}
+
+	ctx.Formatter.Print(rows)
+
+	fmt.Printf("\nTotal: %d schedule(s)\n", len(schedules))
+
+	return nil
+}
+
+func runSchedulePause(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	service := services.NewScheduleService(ctx.UserDB(scheduleUser(ctx)))
+	if _, err := service.PauseSchedule(context.Background(), args[0]); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to pause schedule: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Schedule %s paused", args[0]))
+	return nil
+}
+
+func runScheduleResume(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	service := services.NewScheduleService(ctx.UserDB(scheduleUser(ctx)))
+	schedule, err := service.ResumeSchedule(context.Background(), args[0])
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to resume schedule: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Schedule %s resumed, next run at %s", args[0], formatOptionalTime(schedule.NextRunAt)))
+	return nil
+}
+
+func runScheduleDelete(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	service := services.NewScheduleService(ctx.UserDB(scheduleUser(ctx)))
+	if err := service.DeleteSchedule(context.Background(), args[0]); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to delete schedule: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Schedule %s deleted", args[0]))
+	return nil
+}
+
+func runScheduleRun(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+	service := services.NewScheduleService(ctx.DB)
+	normalizer, err := urlNormalizer(ctx, cmd)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+	jobs := newJobService(ctx, ctx.DB, 0, normalizer)
+
+	tick := func(runCtx context.Context, now time.Time) error {
+		runs, err := service.RunDue(runCtx, now)
+		for _, run := range runs {
+			printScheduledRun(ctx, run)
+		}
+		if err != nil || !scheduleRunJobs {
+			return err
+		}
+
+		ran, err := jobs.RunPending(runCtx)
+		for _, job := range ran {
+			printJobOutcome(ctx, job)
+		}
+		return err
+	}
+
+	if scheduleOnce {
+		return tick(context.Background(), time.Now())
+	}
+
+	// Stop on interrupt, letting a tick in progress finish
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sched := scheduler.New(tick)
+	sched.Interval = scheduleInterval
+	sched.OnError = func(err error) {
+		ctx.Formatter.Error(fmt.Sprintf("Scheduler: %v", err))
+	}
+
+	ctx.Formatter.Info(fmt.Sprintf("Scheduler started, checking every %s (Ctrl+C to stop)", scheduleInterval))
+	sched.Start(runCtx)
+	<-sched.Done()
+	ctx.Formatter.Info("Scheduler stopped")
+
+	return nil
+}
+
+// printScheduledRun reports what happened to a due schedule
+func printScheduledRun(ctx *CLIContext, run *services.ScheduledRun) {
+	label := run.Schedule.ID
+	if run.Schedule.Name != "" {
+		label = run.Schedule.Name
+	}
+	switch {
+	case run.Job == nil:
+		ctx.Formatter.Warning(fmt.Sprintf("Skipped missed run of %s scheduled at %s", label, run.ScheduledAt.Format(time.RFC3339)))
+	case run.Missed:
+		ctx.Formatter.Success(fmt.Sprintf("Queued catch-up job %s for %s (missed run at %s)", run.Job.ID, label, run.ScheduledAt.Format(time.RFC3339)))
+	default:
+		ctx.Formatter.Success(fmt.Sprintf("Queued job %s for %s", run.Job.ID, label))
+	}
+}
+
+func printSchedule(schedule *models.ReportSchedule) {
+	fmt.Printf("\nSchedule Details:\n")
+	fmt.Printf("  ID:        %s\n", schedule.ID)
+	if schedule.Name != "" {
+		fmt.Printf("  Name:      %s\n", schedule.Name)
+	}
+	fmt.Printf("  Source:    %s\n", schedule.SourceLocation)
+	fmt.Printf("  Cron:      %s (%s)\n", schedule.CronExpression, schedule.Timezone)
+	if schedule.JitterSeconds > 0 {
+		fmt.Printf("  Jitter:    %s\n", time.Duration(schedule.JitterSeconds)*time.Second)
+	}
+	fmt.Printf("  Catch Up:  %t\n", schedule.CatchUp)
+	fmt.Printf("  Next Run:  %s\n", formatOptionalTime(schedule.NextRunAt))
+	fmt.Println()
+}
+
+// scheduleUser returns the --user-id flag or the configured default user
+func scheduleUser(ctx *CLIContext) string {
+	if scheduleUserID != "" {
+		return scheduleUserID
+	}
+	return ctx.Config.DefaultUserID
+}
+
+// formatOptionalTime renders a nullable timestamp in local time
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/handlers"
+	"jonopens/sitemapper/internal/services"
+	"jonopens/sitemapper/pkg/scheduler"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the REST API",
+	Long: `Serve a JSON REST API over the configured database.
+The API stores sitemaps (uploaded or fetched from a URL), lists and reads
+reports, entries, groupings and releases, and queues and polls jobs:
+
+  GET  /api/v1/health
+  POST /api/v1/sitemaps                 store a sitemap, returns the report
+  GET  /api/v1/reports                  list reports
+  GET  /api/v1/reports/{id}             get a report
+  GET  /api/v1/reports/{id}/entries     list a report's entries
+  GET  /api/v1/reports/{id}/groupings   list a report's group totals
+  GET  /api/v1/groupings[/{id}]         list or get groupings
+  GET  /api/v1/releases[/{id}]          list or get releases
+  POST /api/v1/jobs                     queue a job for a sitemap URL
+  POST /api/v1/uploads                  upload a sitemap and queue a job for it
+  GET  /api/v1/jobs[/{id}]              list or poll jobs
+  POST /api/v1/jobs/{id}/cancel         cancel a job
+  POST /api/v1/jobs/{id}/retry          retry a job
+
+Lists take limit and offset query parameters. Unless --run-jobs=false, the
+server also runs pending jobs, like 'job run'. Sources sent by clients must be
+http or https URLs; the server never reads local files for them.
+Example:
+  sitemapper serve --addr :8080`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveAddr        string
+	serveRunJobs     bool
+	serveJobInterval time.Duration
+	serveJobTimeout  time.Duration
+)
+
+// serveShutdownTimeout is how long in-flight requests get to finish on shutdown
+const serveShutdownTimeout = 30 * time.Second
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on")
+	serveCmd.Flags().BoolVar(&serveRunJobs, "run-jobs", true, "run pending jobs in the background")
+	serveCmd.Flags().DurationVar(&serveJobInterval, "job-interval", scheduler.DefaultPollInterval, "how often to check for pending jobs")
+	serveCmd.Flags().DurationVar(&serveJobTimeout, "job-timeout", 0, "default per-job timeout (defaults to config job_timeout)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	normalizer, err := urlNormalizer(ctx, cmd)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
+	}
+
+	timeout := serveJobTimeout
+	if timeout <= 0 {
+		timeout = ctx.Config.JobTimeout
+	}
+	jobs := services.NewJobService(ctx.DB, services.JobOptions{
+		Timeout:         timeout,
+		MaxUploadSize:   ctx.Config.MaxUploadSize,
+		WorkerCount:     ctx.Config.WorkerCount,
+		LivenessRate:    defaultLivenessRate,
+		GroupDepth:      ctx.Config.GroupingDepth,
+		RemoteOnly:      true,
+		UploadDir:       ctx.Config.UploadDir,
+		UploadRetention: ctx.Config.UploadRetention,
+		Normalizer:      normalizer,
+		Progress:        ctx.Formatter.Info,
+	})
+
+	server := &http.Server{
+		Addr: serveAddr,
+		Handler: handlers.NewServer(ctx.DB, jobs, handlers.Options{
+			MaxUploadSize:    ctx.Config.MaxUploadSize,
+			MaxStoredEntries: ctx.Config.MaxStoredEntries,
+			CheckLiveness:    ctx.Config.EnableLiveness,
+			LivenessRate:     defaultLivenessRate,
+			WorkerCount:      ctx.Config.WorkerCount,
+			GroupDepth:       ctx.Config.GroupingDepth,
+			UploadDir:        ctx.Config.UploadDir,
+			Normalizer:       normalizer,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Stop on interrupt; running jobs return to pending
+	runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var runner *scheduler.Scheduler
+	if serveRunJobs {
+		runner = scheduler.New(func(tickCtx context.Context, now time.Time) error {
+			ran, err := jobs.RunPending(tickCtx)
+			for _, job := range ran {
+				printJobOutcome(ctx, job)
+			}
+			return err
+		})
+		runner.Interval = serveJobInterval
+		runner.OnError = func(err error) {
+			ctx.Formatter.Error(fmt.Sprintf("Job runner: %v", err))
+		}
+		runner.Start(runCtx)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	ctx.Formatter.Info(fmt.Sprintf("Serving the API on %s (Ctrl+C to stop)", serveAddr))
+
+	select {
+	case err = <-serveErr:
+		stop()
+	case <-runCtx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		err = server.Shutdown(shutdownCtx)
+	}
+	if runner != nil {
+		<-runner.Done()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		ctx.Formatter.Error(fmt.Sprintf("Server: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Info("Server stopped")
+	return nil
+}
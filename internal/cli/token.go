@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage REST API tokens",
+	Long: `Issue, list and revoke the API tokens that authenticate REST API requests.
+Requests send a token as "Authorization: Bearer <token>" and only see the
+records of the token's user. Only a hash of each token is stored, so a token
+is shown once, when it's issued.
+Scopes:
+  read    read reports, entries, groupings, releases and jobs
+  write   store sitemaps and queue, cancel or retry jobs`,
+}
+
+var tokenIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue an API token",
+	Long: `Issue an API token for a user and print it.
+Examples:
+  sitemapper token issue --user-id default --name ci
+  sitemapper token issue --user-id default --name dashboard --scopes read --expires-in 720h`,
+	Args: cobra.NoArgs,
+	RunE: runTokenIssue,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List a user's API tokens",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke one of the user's API tokens",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevoke,
+}
+
+var (
+	tokenUserID    string
+	tokenName      string
+	tokenScopes    []string
+	tokenExpiresIn time.Duration
+)
+
+// defaultTokenLifetime is how long issued tokens last unless --expires-in is given
+const defaultTokenLifetime = 90 * 24 * time.Hour
+
+func init() {
+	tokenCmd.PersistentFlags().StringVar(&tokenUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+
+	tokenIssueCmd.Flags().StringVar(&tokenName, "name", "", "name to tell the token apart, e.g. where it's used")
+	tokenIssueCmd.Flags().StringSliceVar(&tokenScopes, "scopes", []string{models.TokenScopeRead, models.TokenScopeWrite}, "scopes to grant (read, write)")
+	tokenIssueCmd.Flags().DurationVar(&tokenExpiresIn, "expires-in", defaultTokenLifetime, "how long the token is valid (0 = never expires)")
+
+	tokenCmd.AddCommand(tokenIssueCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+}
+
+func runTokenIssue(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	token, plaintext, err := services.NewAuthService(ctx.UserDB(tokenUser(ctx))).IssueToken(context.Background(), tokenUser(ctx), tokenName, tokenScopes, tokenExpiresIn)
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to issue token: %v", err))
+		return err
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(struct {
+			*models.APIToken
+			Token string `json:"token"`
+		}{token, plaintext})
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Token issued with ID: %s", token.ID))
+	fmt.Printf("\n  %s\n\n", plaintext)
+	fmt.Printf("  Scopes:   %s\n", strings.Join(token.Scopes, ", "))
+	fmt.Printf("  Expires:  %s\n", formatOptionalTime(token.ExpiresAt))
+	fmt.Println()
+	ctx.Formatter.Warning("Store the token now; it can't be shown again")
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	tokens, err := services.NewAuthService(ctx.UserDB(tokenUser(ctx))).ListTokens(context.Background(), tokenUser(ctx))
+	if err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to list tokens: %v", err))
+		return err
+	}
+
+	if len(tokens) == 0 {
+		ctx.Formatter.Info("No tokens found")
+		return nil
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(tokens)
+	}
+
+	rows := [][]string{
+		{"ID", "Name", "Prefix", "Scopes", "Status", "Expires", "Created"},
+	}
+	now := time.Now()
+	for _, token := range tokens {
+		status := "active"
+		switch {
+		case token.RevokedAt != nil:
+			status = "revoked"
+		case token.ExpiresAt != nil && !now.Before(*token.ExpiresAt):
+			status = "expired"
+		}
+		rows = append(rows, []string{
+			token.ID,
+			truncate(token.Name, 20),
+			token.Prefix + "...",
+			strings.Join(token.Scopes, ","),
+			status,
+			formatOptionalTime(token.ExpiresAt),
+			formatOptionalTime(&token.CreatedAt),
+		})
+	}
+
+	ctx.Formatter.Print(rows)
+
+	fmt.Printf("\nTotal: %d token(s)\n", len(tokens))
+
+	return nil
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	if _, err := services.NewAuthService(ctx.UserDB(tokenUser(ctx))).RevokeToken(context.Background(), args[0]); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to revoke token: %v", err))
+		return err
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("Token %s revoked", args[0]))
+	return nil
+}
+
+// tokenUser returns the --user-id flag or the configured default user
+func tokenUser(ctx *CLIContext) string {
+	if tokenUserID != "" {
+		return tokenUserID
+	}
+	return ctx.Config.DefaultUserID
+}
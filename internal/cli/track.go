@@ -5,22 +5,44 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
-	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
 	"jonopens/sitemapper/pkg/sitemap"
 )
 
 var (
-	trackName   string
-	trackUserID string
+	trackName     string
+	trackUserID   string
+	trackMaxDepth      int
+	trackCheckLiveness bool
+	trackLivenessRate  float64
+	trackMaxStored     int
+	trackGroupDepth    int
+	trackNormalize     []string
 )
 
+// defaultLivenessRate is the default per-host request rate for liveness checks
+const defaultLivenessRate = 5.0
+
 var trackCmd = &cobra.Command{
 	Use:   "track <url|file>",
 	Short: "Track a sitemap by saving a snapshot to the database",
 	Long: `Parse a sitemap and save a snapshot to the database for historical tracking.
 This allows you to compare sitemaps over time using the compare command.
+Sitemap indexes are resolved recursively and every child sitemap is stored.
+Gzip and zip sources are decompressed; each file in a zip archive is stored
+as a child sitemap of the snapshot.
+Use --check-liveness to request every URL and record its status code,
+response time and redirect chain on the stored entries.
+URLs are grouped automatically by path (/blog/*, /products/*/reviews) with
+numeric and UUID segments collapsed; --group-depth sets how many segments
+make up a group.
+Use --max-stored-entries to cap how many URLs are stored: larger sitemaps keep
+accurate totals but store only a proportional sample per group, plus every
+invalid or down URL and the first and last URL of each group.
+Use --normalize to store the canonical form of every URL alongside the
+original, so later comparisons ignore trailing slashes, http vs https, www.,
+host case, default ports or tracking parameters.
 Example:
   sitemapper track https://example.com/sitemap.xml --name "example-v1"
   sitemapper track ./sitemap.xml --name "local-snapshot"`,
@@ -31,6 +53,12 @@ Example:
 func init() {
 	trackCmd.Flags().StringVar(&trackName, "name", "", "name for this snapshot (optional)")
 	trackCmd.Flags().StringVar(&trackUserID, "user-id", "", "user ID (defaults to config default_user_id)")
+	trackCmd.Flags().IntVar(&trackMaxDepth, "max-depth", sitemap.DefaultMaxDepth, "maximum nesting depth when resolving sitemap indexes")
+	trackCmd.Flags().BoolVar(&trackCheckLiveness, "check-liveness", false, "request every URL and record its status (defaults to config enable_liveness)")
+	trackCmd.Flags().IntVar(&trackMaxStored, "max-stored-entries", -1, "store a stratified sample above this many URLs (0 = store all; defaults to config max_stored_entries)")
+	trackCmd.Flags().IntVar(&trackGroupDepth, "group-depth", 0, "URL path segments used for automatic grouping (defaults to config grouping_depth)")
+	trackCmd.Flags().StringSliceVar(&trackNormalize, "normalize", nil, normalizeFlagUsage)
+	trackCmd.Flags().Float64Var(&trackLivenessRate, "liveness-rate", defaultLivenessRate, "maximum liveness requests per second to a single host (0 = unlimited)")
 }
 
 func runTrack(cmd *cobra.Command, args []string) error {
@@ -44,56 +72,101 @@ func runTrack(cmd *cobra.Command, args []string) error {
 	
 	ctx.Formatter.Info(fmt.Sprintf("Tracking sitemap from: %s", source))
 	
-	// Read and parse sitemap
-	data, err := readSitemapSource(source)
+	// Open sitemap for streaming, expanding compressed sources
+	format, sources, closeSources, err := openSitemapSources(ctx, source)
 	if err != nil {
 		ctx.Formatter.Error(fmt.Sprintf("Failed to read sitemap: %v", err))
 		return err
 	}
+	defer closeSources()
 	
-	parser := sitemap.NewParser()
-	sitemapType, err := parser.DetectType(data)
-	if err != nil {
-		ctx.Formatter.Error(fmt.Sprintf("Failed to detect sitemap type: %v", err))
-		return err
+	if format != nil {
+		ctx.Formatter.Info(fmt.Sprintf("Detected %s compression (%d sitemap file(s))", *format, len(sources)))
 	}
 	
-	if sitemapType != "sitemap" {
-		return fmt.Errorf("currently only regular sitemaps are supported for tracking, got: %s", sitemapType)
+	checkLiveness := ctx.Config.EnableLiveness
+	if cmd.Flags().Changed("check-liveness") {
+		checkLiveness = trackCheckLiveness
+	}
+	opts := services.SnapshotOptions{
+		CheckLiveness: checkLiveness,
+		LivenessRate:  trackLivenessRate,
+		WorkerCount:   ctx.Config.WorkerCount,
+		MaxStored:     ctx.Config.MaxStoredEntries,
+		GroupDepth:    ctx.Config.GroupingDepth,
+		MaxDepth:      trackMaxDepth,
+		Progress:      ctx.Formatter.Info,
+	}
+	if trackMaxStored >= 0 {
+		opts.MaxStored = trackMaxStored
+	}
+	if trackGroupDepth > 0 {
+		opts.GroupDepth = trackGroupDepth
+	}
+	opts.Normalizer, err = urlNormalizer(ctx, cmd)
+	if err != nil {
+		ctx.Formatter.Error(err.Error())
+		return err
 	}
 	
-	sm, err := parser.Parse(data)
+	// Parse and save to database
+	snapshotService := services.NewSnapshotService(ctx.UserDB(trackUserID), sitemapFetcher(ctx))
+	snapshot, err := snapshotService.Save(context.Background(), sources, source, trackUserID, opts)
 	if err != nil {
-		ctx.Formatter.Error(fmt.Sprintf("Failed to parse sitemap: %v", err))
+		ctx.Formatter.Error(fmt.Sprintf("Failed to save snapshot: %v", err))
 		return err
 	}
+	report, resolved := snapshot.Report, snapshot.Resolved
 	
-	// Validate sitemap
-	validator := sitemap.NewValidator()
-	if err := validator.Validate(sm); err != nil {
-		ctx.Formatter.Warning(fmt.Sprintf("Sitemap validation warning: %v", err))
+	for _, child := range resolved.Children {
+		if child.Error != "" {
+			ctx.Formatter.Warning(fmt.Sprintf("Child sitemap %s: %s", child.Loc, child.Error))
+		}
 	}
 	
-	ctx.Formatter.Info(fmt.Sprintf("Parsed %d URLs", len(sm.URLs)))
+	urlCount := report.EntryCount - report.ChildSitemapCount
+	if urlCount == 0 {
+		ctx.Formatter.Warning("Sitemap validation warning: sitemap contains no URLs")
+	} else if report.InvalidEntryCount > 0 {
+		ctx.Formatter.Warning(fmt.Sprintf("Sitemap validation warning: %d invalid entries", report.InvalidEntryCount))
+	}
 	
-	// Save to database
-	reportID, err := saveSitemapSnapshot(ctx, sm, source, trackUserID, trackName)
-	if err != nil {
-		ctx.Formatter.Error(fmt.Sprintf("Failed to save snapshot: %v", err))
-		return err
+	if resolved.IsIndex {
+		ctx.Formatter.Info(fmt.Sprintf("Parsed %d URLs from %d child sitemaps", urlCount, report.ChildSitemapCount))
+	} else {
+		ctx.Formatter.Info(fmt.Sprintf("Parsed %d URLs", urlCount))
+	}
+	
+	if checkLiveness {
+		ctx.Formatter.Info(fmt.Sprintf("Liveness: %d live, %d down", report.LiveEntryCount, report.DownEntryCount))
+		if report.DownEntryCount > 0 {
+			ctx.Formatter.Warning(fmt.Sprintf("%d URLs are down", report.DownEntryCount))
+		}
+	}
+	
+	if !report.IsFullyStored {
+		ctx.Formatter.Info(fmt.Sprintf("Stored a stratified sample of %d of %d entries (%.1f%% sampling rate)",
+			report.StoredEntryCount, report.EntryCount, *report.SamplingRate*100))
 	}
 	
+	reportID := report.ID
 	ctx.Formatter.Success(fmt.Sprintf("Snapshot saved with ID: %s", reportID))
 	
 	// Output report details
 	if ctx.Config.OutputFormat == "json" {
 		result := map[string]interface{}{
-			"report_id":  reportID,
-			"source":     source,
-			"name":       trackName,
-			"user_id":    trackUserID,
-			"url_count":  len(sm.URLs),
-			"created_at": time.Now().Format(time.RFC3339),
+			"report_id":           reportID,
+			"source":              source,
+			"name":                trackName,
+			"user_id":             trackUserID,
+			"url_count":           urlCount,
+			"child_sitemap_count": report.ChildSitemapCount,
+			"live_entry_count":    report.LiveEntryCount,
+			"down_entry_count":    report.DownEntryCount,
+			"stored_entry_count":  report.StoredEntryCount,
+			"is_fully_stored":     report.IsFullyStored,
+			"grouping_count":      report.GroupingCount,
+			"created_at":          report.CreatedAt.Format(time.RFC3339),
 		}
 		return ctx.Formatter.Print(result)
 	}
@@ -105,134 +178,40 @@ func runTrack(cmd *cobra.Command, args []string) error {
 		fmt.Printf("  Name:      %s\n", trackName)
 	}
 	fmt.Printf("  User ID:   %s\n", trackUserID)
-	fmt.Printf("  URLs:      %d\n", len(sm.URLs))
-	fmt.Printf("  Created:   %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Println()
-	
-	ctx.Formatter.Info(fmt.Sprintf("Use 'sitemapper report get %s' to view this report", reportID))
-	
-	return nil
-}
-
-func saveSitemapSnapshot(ctx *CLIContext, sm *sitemap.Sitemap, source, userID, name string) (string, error) {
-	contextBg := context.Background()
-	
-	// Generate report ID
-	reportID := uuid.New().String()
-	
-	// Count valid entries
-	validCount := 0
-	for _, u := range sm.URLs {
-		validator := sitemap.NewValidator()
-		if err := validator.ValidateURL(&u); err == nil {
-			validCount++
-		}
+	fmt.Printf("  URLs:      %d\n", urlCount)
+	if resolved.IsIndex {
+		fmt.Printf("  Sitemaps:  %d\n", report.ChildSitemapCount)
 	}
-	
-	// Create report
-	report := &models.Report{
-		ID:                reportID,
-		UserID:            userID,
-		EntryCount:        len(sm.URLs),
-		StoredEntryCount:  len(sm.URLs),
-		ValidEntryCount:   validCount,
-		InvalidEntryCount: len(sm.URLs) - validCount,
-		LiveEntryCount:    0, // Not checking liveness yet
-		DownEntryCount:    0,
-		GroupingCount:     0,
-		UngroupedCount:    len(sm.URLs),
-		ChildSitemapCount: 0,
-		IsFullyStored:     true,
-		SamplingStrategy:  models.SamplingStrategyNone,
-		SamplingRate:      nil,
-		CreatedAt:         time.Now(),
-		UpdatedAt:         time.Now(),
-	}
-	
-	// Start transaction
-	tx, err := ctx.DB.BeginTx(contextBg)
-	if err != nil {
-		return "", fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-	
-	// Save report
-	if err := tx.Reports().Create(contextBg, report); err != nil {
-		return "", fmt.Errorf("failed to create report: %w", err)
-	}
-	
-	// Save entries
-	for _, u := range sm.URLs {
-		entry := &models.Entry{
-			ID:              uuid.New().String(),
-			ReportID:        reportID,
-			GroupingID:      nil,
-			Type:            models.EntryTypeURL,
-			URL:             u.Loc,
-			LastModified:    parseLastMod(u.LastMod),
-			ChangeFreq:      stringPtr(u.ChangeFreq),
-			Priority:        float64Ptr(u.Priority),
-			IsValid:         true,
-			ValidationError: nil,
-			SelectionReason: models.SelectionReasonFullStorage,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-		}
-		
-		// Validate entry
-		validator := sitemap.NewValidator()
-		if err := validator.ValidateURL(&u); err != nil {
-			entry.IsValid = false
-			errMsg := err.Error()
-			entry.ValidationError = &errMsg
-		}
-		
-		if err := tx.Entries().Create(contextBg, entry); err != nil {
-			return "", fmt.Errorf("failed to create entry: %w", err)
-		}
+	if checkLiveness {
+		fmt.Printf("  Live:      %d\n", report.LiveEntryCount)
+		fmt.Printf("  Down:      %d\n", report.DownEntryCount)
 	}
-	
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return "", fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	
-	return reportID, nil
-}
-
-func parseLastMod(lastMod string) *time.Time {
-	if lastMod == "" {
-		return nil
-	}
-	
-	// Try common date formats
-	formats := []string{
-		"2006-01-02",
-		"2006-01-02T15:04:05Z",
-		"2006-01-02T15:04:05-07:00",
-		time.RFC3339,
+	if !report.IsFullyStored {
+		fmt.Printf("  Stored:    %d (sampled)\n", report.StoredEntryCount)
 	}
+	fmt.Printf("  Created:   %s\n", report.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Println()
 	
-	for _, format := range formats {
-		if t, err := time.Parse(format, lastMod); err == nil {
-			return &t
+	if len(snapshot.Groupings) > 0 {
+		fmt.Println("Groups:")
+		rows := [][]string{
+			{"Group", "Total", "Stored", "Valid", "Invalid", "Down"},
+		}
+		for _, rg := range snapshot.Groupings {
+			rows = append(rows, []string{
+				truncate(snapshot.GroupNames[rg.GroupingID], 40),
+				fmt.Sprintf("%d", rg.TotalEntryCount),
+				fmt.Sprintf("%d", rg.StoredEntryCount),
+				fmt.Sprintf("%d", rg.ValidEntryCount),
+				fmt.Sprintf("%d", rg.InvalidEntryCount),
+				fmt.Sprintf("%d", rg.DownEntryCount),
+			})
 		}
+		ctx.Formatter.Print(rows)
+		fmt.Println()
 	}
 	
+	ctx.Formatter.Info(fmt.Sprintf("Use 'sitemapper report get %s' to view this report", reportID))
+	
 	return nil
 }
-
-func stringPtr(s string) *string {
-	if s == "" {
-		return nil
-	}
-	return &s
-}
-
-func float64Ptr(f float64) *float64 {
-	if f == 0 {
-		return nil
-	}
-	return &f
-}
-
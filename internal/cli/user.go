@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+	Long: `Manage the users that own reports, groupings, schedules and jobs.
+REST API requests act as the user of their API token (see "token issue").`,
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a user",
+	Long: `Create a user. The ID is generated unless --id is given, e.g. to give
+records already tracked under default_user_id an owner.
+Examples:
+  sitemapper user create --email team@example.com --name "Web team"
+  sitemapper user create --id default --email me@example.com`,
+	Args: cobra.NoArgs,
+	RunE: runUserCreate,
+}
+
+var (
+	userCreateID    string
+	userCreateEmail string
+	userCreateName  string
+)
+
+func init() {
+	userCreateCmd.Flags().StringVar(&userCreateID, "id", "", "user ID (generated if not set)")
+	userCreateCmd.Flags().StringVar(&userCreateEmail, "email", "", "email address (required)")
+	userCreateCmd.Flags().StringVar(&userCreateName, "name", "", "display name")
+	userCreateCmd.MarkFlagRequired("email")
+
+	userCmd.AddCommand(userCreateCmd)
+}
+
+func runUserCreate(cmd *cobra.Command, args []string) error {
+	ctx := GetContext()
+
+	user := &models.User{
+		ID:    userCreateID,
+		Email: userCreateEmail,
+		Name:  userCreateName,
+	}
+	if err := services.NewAuthService(ctx.DB).CreateUser(context.Background(), user); err != nil {
+		ctx.Formatter.Error(fmt.Sprintf("Failed to create user: %v", err))
+		return err
+	}
+
+	if ctx.Config.OutputFormat == "json" {
+		return ctx.Formatter.Print(user)
+	}
+
+	ctx.Formatter.Success(fmt.Sprintf("User created with ID: %s", user.ID))
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -23,6 +24,48 @@ type Config struct {
 	MaxUploadSize  int64 `yaml:"max_upload_size" mapstructure:"max_upload_size"`
 	EnableLiveness bool  `yaml:"enable_liveness" mapstructure:"enable_liveness"`
 	WorkerCount    int   `yaml:"worker_count" mapstructure:"worker_count"`
+	
+	// Sampling threshold: snapshots with more entries store a stratified sample (0 = store all)
+	MaxStoredEntries int `yaml:"max_stored_entries" mapstructure:"max_stored_entries"`
+	
+	// Number of URL path segments used for automatic grouping (0 = the default of 1)
+	GroupingDepth int `yaml:"grouping_depth" mapstructure:"grouping_depth"`
+	
+	// Directory uploaded sitemaps are stored in until their jobs run
+	UploadDir string `yaml:"upload_dir" mapstructure:"upload_dir"`
+	
+	// How long uploads of jobs that didn't complete are kept for a retry (0 = keep)
+	UploadRetention time.Duration `yaml:"upload_retention" mapstructure:"upload_retention"`
+	
+	// Default maximum run time of a report job (0 = none)
+	JobTimeout time.Duration `yaml:"job_timeout" mapstructure:"job_timeout"`
+	
+	// URL normalization rules applied by compare and track ("all" or rule names)
+	Normalize []string `yaml:"normalize" mapstructure:"normalize"`
+	// Query parameters dropped by the tracking_params rule (empty = built-in list)
+	TrackingParams []string `yaml:"tracking_params" mapstructure:"tracking_params"`
+}
+
+// defaultConfig returns the settings used for fields a config file leaves out
+func defaultConfig() Config {
+	return Config{
+		Environment:     "development",
+		MaxUploadSize:   100 * 1024 * 1024, // 100MB
+		WorkerCount:     5,
+		GroupingDepth:   1,
+		JobTimeout:      30 * time.Minute,
+		UploadDir:       "./data/uploads",
+		UploadRetention: 7 * 24 * time.Hour,
+		DefaultUserID:   "default",
+		OutputFormat:    "table",
+		ColorOutput:     true,
+	}
+}
+
+// durationKeys are the config keys holding a time.Duration
+var durationKeys = map[string]bool{
+	"upload_retention": true,
+	"job_timeout":      true,
 }
 
 // LoadConfig reads and parses the configuration file
@@ -32,30 +75,26 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
-
-	// Set defaults
-	if cfg.Environment == "" {
-		cfg.Environment = "development"
-	}
-	if cfg.MaxUploadSize == 0 {
-		cfg.MaxUploadSize = 100 * 1024 * 1024 // 100MB
-	}
-	if cfg.WorkerCount == 0 {
-		cfg.WorkerCount = 5
-	}
-	if cfg.DefaultUserID == "" {
-		cfg.DefaultUserID = "default"
-	}
-	if cfg.OutputFormat == "" {
-		cfg.OutputFormat = "table"
+	// Viper reads a plain integer duration as nanoseconds, so "job_timeout: 0"
+	// must decode here too
+	if len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		fields := doc.Content[0].Content
+		for i := 0; i+1 < len(fields); i += 2 {
+			if durationKeys[fields[i].Value] && fields[i+1].ShortTag() == "!!int" {
+				fields[i+1].Tag, fields[i+1].Value = "!!str", fields[i+1].Value+"ns"
+			}
+		}
 	}
-	// ColorOutput defaults to true
-	if !cfg.ColorOutput {
-		cfg.ColorOutput = true
+
+	// Fields missing from the file keep their defaults, while explicit zero
+	// values are kept as written, matching LoadConfigWithViper
+	cfg := defaultConfig()
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	return &cfg, nil
@@ -81,12 +120,17 @@ func LoadConfigWithViper(configPath string) (*Config, error) {
 	v.AutomaticEnv()
 	
 	// Set defaults
-	v.SetDefault("environment", "development")
-	v.SetDefault("max_upload_size", 100*1024*1024)
-	v.SetDefault("worker_count", 5)
-	v.SetDefault("default_user_id", "default")
-	v.SetDefault("output_format", "table")
-	v.SetDefault("color_output", true)
+	defaults := defaultConfig()
+	v.SetDefault("environment", defaults.Environment)
+	v.SetDefault("max_upload_size", defaults.MaxUploadSize)
+	v.SetDefault("worker_count", defaults.WorkerCount)
+	v.SetDefault("grouping_depth", defaults.GroupingDepth)
+	v.SetDefault("job_timeout", defaults.JobTimeout)
+	v.SetDefault("upload_dir", defaults.UploadDir)
+	v.SetDefault("upload_retention", defaults.UploadRetention)
+	v.SetDefault("default_user_id", defaults.DefaultUserID)
+	v.SetDefault("output_format", defaults.OutputFormat)
+	v.SetDefault("color_output", defaults.ColorOutput)
 	
 	// Read config
 	if err := v.ReadInConfig(); err != nil {
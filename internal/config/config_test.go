@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoaders(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want func(*Config)
+	}{
+		{
+			name: "empty",
+			want: func(cfg *Config) { cfg.DatabaseType = "" },
+		},
+		{
+			name: "defaults",
+			yaml: "database_type: memory\n",
+			want: func(cfg *Config) {},
+		},
+		{
+			// An explicit 0 is kept as written rather than replaced by the default
+			name: "explicit zeros",
+			yaml: "database_type: memory\nmax_stored_entries: 0\ngrouping_depth: 0\nupload_retention: 0\njob_timeout: 0\ncolor_output: false\n",
+			want: func(cfg *Config) {
+				cfg.GroupingDepth = 0
+				cfg.UploadRetention = 0
+				cfg.JobTimeout = 0
+				cfg.ColorOutput = false
+			},
+		},
+		{
+			name: "values",
+			yaml: "database_type: memory\nmax_stored_entries: 1000\ngrouping_depth: 2\nupload_retention: 24h\njob_timeout: 5m\n",
+			want: func(cfg *Config) {
+				cfg.MaxStoredEntries = 1000
+				cfg.GroupingDepth = 2
+				cfg.UploadRetention = 24 * time.Hour
+				cfg.JobTimeout = 5 * time.Minute
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			want := defaultConfig()
+			want.DatabaseType = "memory"
+			tt.want(&want)
+
+			for name, load := range map[string]func(string) (*Config, error){
+				"LoadConfig":          LoadConfig,
+				"LoadConfigWithViper": LoadConfigWithViper,
+			} {
+				cfg, err := load(path)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if !reflect.DeepEqual(*cfg, want) {
+					t.Errorf("%s = %+v\nwant %+v", name, *cfg, want)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"net/url"
 
 	"jonopens/sitemapper/internal/config"
 	"jonopens/sitemapper/internal/database/memory"
@@ -21,9 +22,41 @@ func NewDatabase(cfg *config.Config) (repositories.Database, error) {
 	case "sqlite", "sqlite3":
 		return sqlite.New(cfg.DatabaseURL)
 	case "memory":
-		return memory.New(), nil
+		if cfg.DatabaseURL == "" {
+			return memory.New(), nil
+		}
+		path, err := memoryFile(cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return memory.Open(path)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.DatabaseType)
 	}
 }
 
+// memoryFile returns the snapshot file named by a memory database_url, a
+// plain path or a file: URL. Other URLs, such as a postgres URL left over from
+// another database_type, are rejected instead of being used as a path.
+func memoryFile(databaseURL string) (string, error) {
+	u, err := url.Parse(databaseURL)
+	// A one-letter scheme is a Windows drive, as in C:\data\sitemapper.json
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		return databaseURL, nil
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("memory database_url must be a file path or file: URL, not a %s: URL", u.Scheme)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("memory database_url %q names a remote host", databaseURL)
+	}
+	path := u.Path
+	if u.Opaque != "" {
+		// file:data/sitemapper.json is relative
+		path = u.Opaque
+	}
+	if path == "" {
+		return "", fmt.Errorf("memory database_url %q has no path", databaseURL)
+	}
+	return path, nil
+}
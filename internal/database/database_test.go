@@ -0,0 +1,28 @@
+package database
+
+import "testing"
+
+func TestMemoryFile(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{url: "./data/sitemapper.json", want: "./data/sitemapper.json"},
+		{url: "/var/lib/sitemapper.json", want: "/var/lib/sitemapper.json"},
+		{url: `C:\data\sitemapper.json`, want: `C:\data\sitemapper.json`},
+		{url: "file:///var/lib/sitemapper.json", want: "/var/lib/sitemapper.json"},
+		{url: "file://localhost/var/lib/sitemapper.json", want: "/var/lib/sitemapper.json"},
+		{url: "file:data/sitemapper.json", want: "data/sitemapper.json"},
+		{url: "postgresql://localhost:5432/sitemapper?sslmode=disable", wantErr: true},
+		{url: "mysql://user@localhost/sitemapper", wantErr: true},
+		{url: "file://example.com/sitemapper.json", wantErr: true},
+		{url: "file:", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := memoryFile(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("memoryFile(%q) = %q, %v; want %q, error %v", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package memory
 
 import (
 	"context"
+	"sort"
 
 	"jonopens/sitemapper/internal/models"
 	"jonopens/sitemapper/internal/repositories"
@@ -12,10 +13,24 @@ type EntryRepository struct {
 }
 
 func (r *EntryRepository) Create(ctx context.Context, entry *models.Entry) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.entries[entry.ID] = entry
-	return nil
+	stored := clone(entry)
+	return r.db.write(func(d *Database) error {
+		d.entries[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *EntryRepository) CreateBatch(ctx context.Context, entries []*models.Entry) error {
+	stored := make([]*models.Entry, len(entries))
+	for i, entry := range entries {
+		stored[i] = clone(entry)
+	}
+	return r.db.write(func(d *Database) error {
+		for _, entry := range stored {
+			d.entries[entry.ID] = entry
+		}
+		return nil
+	})
 }
 
 func (r *EntryRepository) GetByID(ctx context.Context, id string) (*models.Entry, error) {
@@ -25,42 +40,55 @@ func (r *EntryRepository) GetByID(ctx context.Context, id string) (*models.Entry
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return entry, nil
+	return clone(entry), nil
 }
 
 func (r *EntryRepository) List(ctx context.Context, filters repositories.EntryFilters) ([]*models.Entry, error) {
 	r.db.mu.RLock()
 	defer r.db.mu.RUnlock()
-	
+
 	var entries []*models.Entry
 	for _, entry := range r.db.entries {
-		// Apply filters if needed
+		if filters.ReportID != "" && entry.ReportID != filters.ReportID {
+			continue
+		}
+		if filters.Type != nil && entry.Type != *filters.Type {
+			continue
+		}
 		entries = append(entries, entry)
 	}
-	return entries, nil
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return cloneAll(paginate(entries, filters.Limit, filters.Offset)), nil
 }
 
 func (r *EntryRepository) Update(ctx context.Context, entry *models.Entry) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	if _, exists := r.db.entries[entry.ID]; !exists {
-		return ErrNotFound
-	}
-	r.db.entries[entry.ID] = entry
-	return nil
+	stored := clone(entry)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.entries[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.entries[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *EntryRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.entries, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.entries, id)
+		return nil
+	})
 }
 
 func (r *EntryRepository) CountByType(ctx context.Context, entryType models.EntryType) (int, error) {
 	r.db.mu.RLock()
 	defer r.db.mu.RUnlock()
-	
+
 	count := 0
 	for _, entry := range r.db.entries {
 		if entry.Type == entryType {
@@ -69,4 +97,3 @@ func (r *EntryRepository) CountByType(ctx context.Context, entryType models.Entr
 	}
 	return count, nil
 }
-
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+// fileVersion is the format version of snapshot files
+const fileVersion = 1
+
+// lockTimeout is how long Open waits for another process to release the file
+const lockTimeout = 10 * time.Second
+
+// fileData is the snapshot file format; the maps mirror the Database tables
+type fileData struct {
+	Version           int                                  `json:"version"`
+	Entries           map[string]*models.Entry             `json:"entries"`
+	Reports           map[string]*models.Report            `json:"reports"`
+	Users             map[string]*models.User              `json:"users"`
+	Groupings         map[string]*models.Group             `json:"groupings"`
+	ReportGroupings   map[string]*models.ReportGrouping    `json:"report_groupings"`
+	ReportDiffs       map[string]*models.ReportDiff        `json:"report_diffs"`
+	DiffEntries       map[string][]*models.ReportDiffEntry `json:"diff_entries"`
+	Schedules         map[string]*models.ReportSchedule    `json:"schedules"`
+	GroupingRules     map[string]*models.GroupingRule      `json:"grouping_rules"`
+	GroupingOverrides map[string]*models.GroupingOverride  `json:"grouping_overrides"`
+	Jobs              map[string]*models.ReportJob         `json:"jobs"`
+	Releases          map[string]*models.Release           `json:"releases"`
+	APITokens         map[string]*fileAPIToken             `json:"api_tokens"`
+}
+
+// fileAPIToken keeps the token hash, which APIToken leaves out of JSON
+type fileAPIToken struct {
+	models.APIToken
+	TokenHash string `json:"token_hash"`
+}
+
+// Open creates an in-memory database backed by a JSON snapshot file. The
+// file is loaded if it exists and rewritten on Close when anything changed.
+// The database holds a lock on the file until it's closed, so other
+// processes opening the same file wait for it, for up to lockTimeout.
+func Open(path string) (*Database, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	lock, err := lockFile(path+".lock", lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	d := New()
+	d.path = path
+	d.lock = lock
+	if err := d.load(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// load reads the snapshot file, leaving the database empty if there is none
+func (d *Database) load() error {
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read database file: %w", err)
+	}
+
+	var file fileData
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to decode database file %s: %w", d.path, err)
+	}
+	if file.Version != fileVersion {
+		return fmt.Errorf("database file %s has unsupported version %d", d.path, file.Version)
+	}
+
+	load(d.entries, file.Entries)
+	load(d.reports, file.Reports)
+	load(d.users, file.Users)
+	load(d.groupings, file.Groupings)
+	load(d.reportGroupings, file.ReportGroupings)
+	load(d.reportDiffs, file.ReportDiffs)
+	load(d.diffEntries, file.DiffEntries)
+	load(d.schedules, file.Schedules)
+	load(d.groupingRules, file.GroupingRules)
+	load(d.groupingOverrides, file.GroupingOverrides)
+	load(d.jobs, file.Jobs)
+	load(d.releases, file.Releases)
+	for id, token := range file.APITokens {
+		token.APIToken.TokenHash = token.TokenHash
+		d.apiTokens[id] = &token.APIToken
+	}
+	return nil
+}
+
+// load copies a decoded table into the database
+func load[V any](table, decoded map[string]V) {
+	for id, v := range decoded {
+		table[id] = v
+	}
+}
+
+// save atomically replaces the snapshot file: the data is written to a
+// temporary file in the same directory, which is then renamed over it
+func (d *Database) save() error {
+	d.mu.RLock()
+	apiTokens := make(map[string]*fileAPIToken, len(d.apiTokens))
+	for id, token := range d.apiTokens {
+		apiTokens[id] = &fileAPIToken{APIToken: *token, TokenHash: token.TokenHash}
+	}
+	data, err := json.Marshal(fileData{
+		Version:           fileVersion,
+		Entries:           d.entries,
+		Reports:           d.reports,
+		Users:             d.users,
+		Groupings:         d.groupings,
+		ReportGroupings:   d.reportGroupings,
+		ReportDiffs:       d.reportDiffs,
+		DiffEntries:       d.diffEntries,
+		Schedules:         d.schedules,
+		GroupingRules:     d.groupingRules,
+		GroupingOverrides: d.groupingOverrides,
+		Jobs:              d.jobs,
+		Releases:          d.releases,
+		APITokens:         apiTokens,
+	})
+	d.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode database: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write database file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write database file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write database file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write database file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write database file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path); err != nil {
+		return fmt.Errorf("failed to replace database file: %w", err)
+	}
+	return nil
+}
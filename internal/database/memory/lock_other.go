@@ -0,0 +1,15 @@
+//go:build !unix
+
+package memory
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// lockFile is only implemented on Unix; without a lock, two processes could
+// overwrite each other's changes, so file-backed mode is refused instead
+func lockFile(path string, timeout time.Duration) (io.Closer, error) {
+	return nil, fmt.Errorf("file-backed memory databases are not supported on this platform")
+}
@@ -0,0 +1,39 @@
+//go:build unix
+
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// lockFile takes an exclusive lock on path, creating it if needed and
+// retrying until timeout while another process holds it. The lock is
+// released when the returned file is closed, or when the process exits.
+func lockFile(path string, timeout time.Duration) (io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) && !errors.Is(err, syscall.EINTR) {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("database file is in use by another process (lock %s)", path)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
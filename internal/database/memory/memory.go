@@ -2,7 +2,11 @@ package memory
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"sync"
 
 	"jonopens/sitemapper/internal/models"
@@ -10,26 +14,56 @@ import (
 )
 
 // Database implements repositories.Database with in-memory storage
-// Useful for testing and development
+// Useful for testing and development; Open adds persistence to a file
+//
+// A transaction is a Database holding a snapshot of the tables taken at
+// BeginTx. Its writes go to the snapshot and to a journal, which Commit
+// replays on the database the transaction began on. Stored models are copied
+// on the way in and out, so callers can't change them without an Update.
 type Database struct {
-	entries   map[string]*models.Entry
-	reports   map[string]*models.Report
-	users     map[string]*models.User
-	groupings map[string]*models.Group
-	jobs      map[string]*models.ReportJob
-	releases  map[string]*models.Release
-	mu        sync.RWMutex
+	entries           map[string]*models.Entry
+	reports           map[string]*models.Report
+	users             map[string]*models.User
+	groupings         map[string]*models.Group
+	reportGroupings   map[string]*models.ReportGrouping
+	reportDiffs       map[string]*models.ReportDiff
+	diffEntries       map[string][]*models.ReportDiffEntry // by report diff ID
+	schedules         map[string]*models.ReportSchedule
+	groupingRules     map[string]*models.GroupingRule
+	groupingOverrides map[string]*models.GroupingOverride
+	jobs              map[string]*models.ReportJob
+	releases          map[string]*models.Release
+	apiTokens         map[string]*models.APIToken
+	mu                sync.RWMutex
+
+	// Transaction state; parent is nil outside a transaction
+	parent  *Database
+	journal []func(*Database) error
+	done    bool
+
+	// File persistence state; path is empty unless the database was opened
+	// with Open, and dirty is set by any change since it was loaded
+	path  string
+	lock  io.Closer
+	dirty bool
 }
 
 // New creates a new in-memory database
 func New() *Database {
 	return &Database{
-		entries:   make(map[string]*models.Entry),
-		reports:   make(map[string]*models.Report),
-		users:     make(map[string]*models.User),
-		groupings: make(map[string]*models.Group),
-		jobs:      make(map[string]*models.ReportJob),
-		releases:  make(map[string]*models.Release),
+		entries:           make(map[string]*models.Entry),
+		reports:           make(map[string]*models.Report),
+		users:             make(map[string]*models.User),
+		groupings:         make(map[string]*models.Group),
+		reportGroupings:   make(map[string]*models.ReportGrouping),
+		reportDiffs:       make(map[string]*models.ReportDiff),
+		diffEntries:       make(map[string][]*models.ReportDiffEntry),
+		schedules:         make(map[string]*models.ReportSchedule),
+		groupingRules:     make(map[string]*models.GroupingRule),
+		groupingOverrides: make(map[string]*models.GroupingOverride),
+		jobs:              make(map[string]*models.ReportJob),
+		releases:          make(map[string]*models.Release),
+		apiTokens:         make(map[string]*models.APIToken),
 	}
 }
 
@@ -53,38 +87,225 @@ func (d *Database) Groupings() repositories.GroupingRepository {
 	return &GroupingRepository{db: d}
 }
 
+// ReportDiffs returns the report diff repository
+func (d *Database) ReportDiffs() repositories.ReportDiffRepository {
+	return &ReportDiffRepository{db: d}
+}
+
+// GroupingRules returns the grouping rule repository
+func (d *Database) GroupingRules() repositories.GroupingRuleRepository {
+	return &GroupingRuleRepository{db: d}
+}
+
+// GroupingOverrides returns the grouping override repository
+func (d *Database) GroupingOverrides() repositories.GroupingOverrideRepository {
+	return &GroupingOverrideRepository{db: d}
+}
+
+// ReportGroupings returns the report grouping repository
+func (d *Database) ReportGroupings() repositories.ReportGroupingRepository {
+	return &ReportGroupingRepository{db: d}
+}
+
 // ReportJobs returns the report job repository
 func (d *Database) ReportJobs() repositories.ReportJobRepository {
 	return &ReportJobRepository{db: d}
 }
 
+// ReportSchedules returns the report schedule repository
+func (d *Database) ReportSchedules() repositories.ReportScheduleRepository {
+	return &ReportScheduleRepository{db: d}
+}
+
 // Releases returns the release repository
 func (d *Database) Releases() repositories.ReleaseRepository {
 	return &ReleaseRepository{db: d}
 }
 
-// BeginTx starts a new transaction (no-op for in-memory)
+// APITokens returns the API token repository
+func (d *Database) APITokens() repositories.APITokenRepository {
+	return &APITokenRepository{db: d}
+}
+
+// BeginTx starts a new transaction on a snapshot of the database
 func (d *Database) BeginTx(ctx context.Context) (repositories.Database, error) {
-	// For in-memory, we just return the same instance
-	// In a real implementation, you might want to implement transaction isolation
-	return d, nil
+	// Like a SQL connection, a transaction begins on the database itself,
+	// not inside another transaction
+	root := d
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	root.mu.RLock()
+	defer root.mu.RUnlock()
+
+	tx := root.snapshot()
+	tx.parent = root
+	return tx, nil
 }
 
-// Commit commits the transaction (no-op for in-memory)
+// Commit replays the transaction's writes on the database it began on. If a
+// write no longer applies, none of them are kept and Commit returns its error.
 func (d *Database) Commit() error {
+	if d.parent == nil {
+		return fmt.Errorf("no transaction to commit")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return sql.ErrTxDone
+	}
+	d.done = true
+
+	journal := d.journal
+	d.journal = nil
+	if len(journal) == 0 {
+		return nil
+	}
+
+	d.parent.mu.Lock()
+	defer d.parent.mu.Unlock()
+
+	// Replay on a copy, so a change that no longer applies (its row was
+	// deleted or claimed outside the transaction) leaves the database as it
+	// was, like a failed SQL commit
+	replay := d.parent.snapshot()
+	for _, change := range journal {
+		if err := change(replay); err != nil {
+			return fmt.Errorf("transaction conflicts with a change made since it began: %w", err)
+		}
+	}
+	d.parent.setTables(replay)
+	d.parent.dirty = true
 	return nil
 }
 
-// Rollback rolls back the transaction (no-op for in-memory)
+// snapshot returns a database holding copies of d's tables. Stored models are
+// never changed in place, so the copies can share them.
+func (d *Database) snapshot() *Database {
+	snap := &Database{
+		entries:           maps.Clone(d.entries),
+		reports:           maps.Clone(d.reports),
+		users:             maps.Clone(d.users),
+		groupings:         maps.Clone(d.groupings),
+		reportGroupings:   maps.Clone(d.reportGroupings),
+		reportDiffs:       maps.Clone(d.reportDiffs),
+		diffEntries:       make(map[string][]*models.ReportDiffEntry, len(d.diffEntries)),
+		schedules:         maps.Clone(d.schedules),
+		groupingRules:     maps.Clone(d.groupingRules),
+		groupingOverrides: maps.Clone(d.groupingOverrides),
+		jobs:              maps.Clone(d.jobs),
+		releases:          maps.Clone(d.releases),
+		apiTokens:         maps.Clone(d.apiTokens),
+	}
+	for diffID, entries := range d.diffEntries {
+		snap.diffEntries[diffID] = slices.Clip(entries)
+	}
+	return snap
+}
+
+// setTables replaces d's tables with those of src
+func (d *Database) setTables(src *Database) {
+	d.entries = src.entries
+	d.reports = src.reports
+	d.users = src.users
+	d.groupings = src.groupings
+	d.reportGroupings = src.reportGroupings
+	d.reportDiffs = src.reportDiffs
+	d.diffEntries = src.diffEntries
+	d.schedules = src.schedules
+	d.groupingRules = src.groupingRules
+	d.groupingOverrides = src.groupingOverrides
+	d.jobs = src.jobs
+	d.releases = src.releases
+	d.apiTokens = src.apiTokens
+}
+
+// Rollback discards the transaction's writes
 func (d *Database) Rollback() error {
+	if d.parent == nil {
+		return fmt.Errorf("no transaction to rollback")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return sql.ErrTxDone
+	}
+	d.done = true
+	d.journal = nil
+	return nil
+}
+
+// write applies a change under the write lock and, inside a transaction,
+// records it for Commit
+func (d *Database) write(change func(*Database) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return sql.ErrTxDone
+	}
+	if err := change(d); err != nil {
+		return err
+	}
+	if d.parent != nil {
+		d.journal = append(d.journal, change)
+	} else {
+		d.dirty = true
+	}
 	return nil
 }
 
-// Close closes the database (no-op for in-memory)
+// Close writes a file-backed database back to its file if it changed and
+// releases the file lock; it's a no-op for a purely in-memory database
 func (d *Database) Close() error {
+	if d.lock == nil {
+		return nil
+	}
+	defer func() {
+		d.lock.Close()
+		d.lock = nil
+	}()
+
+	if !d.dirty {
+		return nil
+	}
+	if err := d.save(); err != nil {
+		return err
+	}
+	d.dirty = false
 	return nil
 }
 
-// Common error
-var ErrNotFound = fmt.Errorf("not found")
+// Common error, shared by every backend so callers can match it
+var ErrNotFound = repositories.ErrNotFound
+
+// clone returns a copy of a stored model, so callers and the store don't
+// share it
+func clone[T any](v *T) *T {
+	c := *v
+	return &c
+}
 
+// cloneAll copies every model in a list
+func cloneAll[T any](items []*T) []*T {
+	for i, item := range items {
+		items[i] = clone(item)
+	}
+	return items
+}
+
+// paginate applies a list's Offset and Limit, where zero means no limit
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return nil
+		}
+		items = items[offset:]
+	}
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,225 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/repositories/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Suite{
+		New:         func(t *testing.T) repositories.Database { return New() },
+		ErrNotFound: ErrNotFound,
+	}.Run(t)
+}
+
+func newReport(id string) *models.Report {
+	now := time.Now()
+	return &models.Report{ID: id, UserID: "user-1", SamplingStrategy: models.SamplingStrategyNone, CreatedAt: now, UpdatedAt: now}
+}
+
+func TestTransactionIsolation(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	if err := db.Reports().Create(ctx, newReport("before")); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Reports().Create(ctx, newReport("inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reports().Create(ctx, newReport("outside")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither side sees the other's uncommitted writes
+	if _, err := db.Reports().GetByID(ctx, "inside"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("database sees uncommitted report: err = %v", err)
+	}
+	if _, err := tx.Reports().GetByID(ctx, "outside"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("transaction sees report written after BeginTx: err = %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Commit replays the transaction's writes without losing the others
+	reports, err := db.Reports().List(ctx, repositories.ReportFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Errorf("got %d reports after Commit, want 3", len(reports))
+	}
+
+	if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Rollback after Commit = %v, want %v", err, sql.ErrTxDone)
+	}
+	if err := tx.Reports().Create(ctx, newReport("late")); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Create after Commit = %v, want %v", err, sql.ErrTxDone)
+	}
+	if err := db.Commit(); err == nil {
+		t.Error("Commit outside a transaction succeeded")
+	}
+}
+
+func TestCommitConflict(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	if err := db.Reports().Create(ctx, newReport("deleted")); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Reports().Create(ctx, newReport("inside")); err != nil {
+		t.Fatal(err)
+	}
+	report, err := tx.Reports().GetByID(ctx, "deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report.EntryCount = 10
+	if err := tx.Reports().Update(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reports().Delete(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The update no longer applies, so the commit keeps none of the writes
+	if err := tx.Commit(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Commit = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := db.Reports().GetByID(ctx, "inside"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("report created in a failed commit was kept: err = %v", err)
+	}
+	if _, err := db.Reports().GetByID(ctx, "deleted"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted report was restored: err = %v", err)
+	}
+	if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Rollback after a failed Commit = %v, want %v", err, sql.ErrTxDone)
+	}
+}
+
+func TestStoredModelsAreCopies(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	report := newReport("report-1")
+	if err := db.Reports().Create(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing a model read in a rolled back transaction changes nothing
+	got, err := tx.Reports().GetByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.EntryCount = 10
+	if err := tx.Reports().Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	report.EntryCount = 20
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = db.Reports().GetByID(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EntryCount != 0 {
+		t.Errorf("EntryCount = %d, want 0", got.EntryCount)
+	}
+}
+
+func TestConformanceFile(t *testing.T) {
+	repotest.Suite{
+		New: func(t *testing.T) repositories.Database {
+			db, err := Open(filepath.Join(t.TempDir(), "sitemapper.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			return db
+		},
+		ErrNotFound: ErrNotFound,
+	}.Run(t)
+}
+
+func TestFilePersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "sitemapper.json")
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reports().Create(ctx, newReport("report-1")); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Reports().Create(ctx, newReport("report-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	diff := &models.ReportDiff{ID: "diff-1", BaseReportID: "report-1", CompareReportID: "report-2"}
+	if err := db.ReportDiffs().Create(ctx, diff); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.ReportDiffs().CreateEntry(ctx, &models.ReportDiffEntry{ID: "de-1", ReportDiffID: "diff-1", URL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second process can't open the file while it's in use
+	if _, err := lockFile(path+".lock", 0); err == nil {
+		t.Error("locked the database file while it was open")
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	reports, err := db.Reports().List(ctx, repositories.ReportFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Errorf("got %d reports after reopening, want 2", len(reports))
+	}
+	entries, err := db.ReportDiffs().ListEntries(ctx, "diff-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].URL != "https://example.com/" {
+		t.Errorf("got diff entries %+v after reopening", entries)
+	}
+}
@@ -2,6 +2,9 @@ package memory
 
 import (
 	"context"
+	"slices"
+	"sort"
+	"time"
 
 	"jonopens/sitemapper/internal/models"
 	"jonopens/sitemapper/internal/repositories"
@@ -14,10 +17,11 @@ type ReportRepository struct {
 }
 
 func (r *ReportRepository) Create(ctx context.Context, report *models.Report) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.reports[report.ID] = report
-	return nil
+	stored := clone(report)
+	return r.db.write(func(d *Database) error {
+		d.reports[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReportRepository) GetByID(ctx context.Context, id string) (*models.Report, error) {
@@ -27,19 +31,11 @@ func (r *ReportRepository) GetByID(ctx context.Context, id string) (*models.Repo
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return report, nil
+	return clone(report), nil
 }
 
 func (r *ReportRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Report, error) {
-	r.db.mu.RLock()
-	defer r.db.mu.RUnlock()
-	var reports []*models.Report
-	for _, report := range r.db.reports {
-		if report.UserID == userID {
-			reports = append(reports, report)
-		}
-	}
-	return reports, nil
+	return r.List(ctx, repositories.ReportFilters{UserID: userID})
 }
 
 func (r *ReportRepository) List(ctx context.Context, filters repositories.ReportFilters) ([]*models.Report, error) {
@@ -47,23 +43,50 @@ func (r *ReportRepository) List(ctx context.Context, filters repositories.Report
 	defer r.db.mu.RUnlock()
 	var reports []*models.Report
 	for _, report := range r.db.reports {
+		if filters.UserID != "" && report.UserID != filters.UserID {
+			continue
+		}
 		reports = append(reports, report)
 	}
-	return reports, nil
+
+	// Newest first
+	sort.Slice(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+	return cloneAll(paginate(reports, filters.Limit, filters.Offset)), nil
 }
 
 func (r *ReportRepository) Update(ctx context.Context, report *models.Report) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.reports[report.ID] = report
-	return nil
+	stored := clone(report)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.reports[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.reports[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReportRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.reports, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.reports, id)
+
+		// Entries and report groupings belong to their report
+		for entryID, entry := range d.entries {
+			if entry.ReportID == id {
+				delete(d.entries, entryID)
+			}
+		}
+		for reportGroupingID, reportGrouping := range d.reportGroupings {
+			if reportGrouping.ReportID == id {
+				delete(d.reportGroupings, reportGroupingID)
+			}
+		}
+		return nil
+	})
 }
 
 type UserRepository struct {
@@ -71,10 +94,11 @@ type UserRepository struct {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.users[user.ID] = user
-	return nil
+	stored := clone(user)
+	return r.db.write(func(d *Database) error {
+		d.users[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
@@ -84,7 +108,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User,
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return user, nil
+	return clone(user), nil
 }
 
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -92,24 +116,33 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	defer r.db.mu.RUnlock()
 	for _, user := range r.db.users {
 		if user.Email == email {
-			return user, nil
+			return clone(user), nil
 		}
 	}
 	return nil, ErrNotFound
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.users[user.ID] = user
-	return nil
+	stored := clone(user)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.users[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.users[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.users, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.users, id)
+		for tokenID, token := range d.apiTokens {
+			if token.UserID == id {
+				delete(d.apiTokens, tokenID)
+			}
+		}
+		return nil
+	})
 }
 
 type GroupingRepository struct {
@@ -117,10 +150,11 @@ type GroupingRepository struct {
 }
 
 func (r *GroupingRepository) Create(ctx context.Context, grouping *models.Group) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.groupings[grouping.ID] = grouping
-	return nil
+	stored := clone(grouping)
+	return r.db.write(func(d *Database) error {
+		d.groupings[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *GroupingRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
@@ -130,7 +164,7 @@ func (r *GroupingRepository) GetByID(ctx context.Context, id string) (*models.Gr
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return grouping, nil
+	return clone(grouping), nil
 }
 
 func (r *GroupingRepository) List(ctx context.Context) ([]*models.Group, error) {
@@ -140,21 +174,289 @@ func (r *GroupingRepository) List(ctx context.Context) ([]*models.Group, error)
 	for _, grouping := range r.db.groupings {
 		groupings = append(groupings, grouping)
 	}
-	return groupings, nil
+
+	sort.Slice(groupings, func(i, j int) bool {
+		if groupings[i].Name != groupings[j].Name {
+			return groupings[i].Name < groupings[j].Name
+		}
+		return groupings[i].ID < groupings[j].ID
+	})
+	return cloneAll(groupings), nil
 }
 
 func (r *GroupingRepository) Update(ctx context.Context, grouping *models.Group) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.groupings[grouping.ID] = grouping
-	return nil
+	stored := clone(grouping)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.groupings[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.groupings[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *GroupingRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.groupings, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.groupings, id)
+		return nil
+	})
+}
+
+type ReportDiffRepository struct {
+	db *Database
+}
+
+func (r *ReportDiffRepository) Create(ctx context.Context, diff *models.ReportDiff) error {
+	stored := clone(diff)
+	return r.db.write(func(d *Database) error {
+		d.reportDiffs[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportDiffRepository) GetByID(ctx context.Context, id string) (*models.ReportDiff, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	diff, exists := r.db.reportDiffs[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(diff), nil
+}
+
+func (r *ReportDiffRepository) List(ctx context.Context, filters repositories.ReportDiffFilters) ([]*models.ReportDiff, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var diffs []*models.ReportDiff
+	for _, diff := range r.db.reportDiffs {
+		if filters.UserID != "" && diff.UserID != filters.UserID {
+			continue
+		}
+		if filters.ReportID != "" && diff.BaseReportID != filters.ReportID && diff.CompareReportID != filters.ReportID {
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+
+	// Newest first
+	sort.Slice(diffs, func(i, j int) bool {
+		if !diffs[i].CreatedAt.Equal(diffs[j].CreatedAt) {
+			return diffs[i].CreatedAt.After(diffs[j].CreatedAt)
+		}
+		return diffs[i].ID > diffs[j].ID
+	})
+	return cloneAll(paginate(diffs, filters.Limit, filters.Offset)), nil
+}
+
+func (r *ReportDiffRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.reportDiffs, id)
+		delete(d.diffEntries, id)
+		return nil
+	})
+}
+
+func (r *ReportDiffRepository) CreateEntry(ctx context.Context, entry *models.ReportDiffEntry) error {
+	stored := clone(entry)
+	return r.db.write(func(d *Database) error {
+		d.diffEntries[stored.ReportDiffID] = append(d.diffEntries[stored.ReportDiffID], stored)
+		return nil
+	})
+}
+
+func (r *ReportDiffRepository) ListEntries(ctx context.Context, diffID string) ([]*models.ReportDiffEntry, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	entries := cloneAll(slices.Clone(r.db.diffEntries[diffID]))
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ChangeType != entries[j].ChangeType {
+			return entries[i].ChangeType < entries[j].ChangeType
+		}
+		if entries[i].URL != entries[j].URL {
+			return entries[i].URL < entries[j].URL
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
+
+type GroupingRuleRepository struct {
+	db *Database
+}
+
+func (r *GroupingRuleRepository) Create(ctx context.Context, rule *models.GroupingRule) error {
+	stored := clone(rule)
+	return r.db.write(func(d *Database) error {
+		d.groupingRules[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *GroupingRuleRepository) GetByID(ctx context.Context, id string) (*models.GroupingRule, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	rule, exists := r.db.groupingRules[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(rule), nil
+}
+
+func (r *GroupingRuleRepository) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingRule, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var rules []*models.GroupingRule
+	for _, rule := range r.db.groupingRules {
+		if rule.UserID == userID {
+			rules = append(rules, rule)
+		}
+	}
+
+	// Highest priority first, then oldest first
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return cloneAll(rules), nil
+}
+
+func (r *GroupingRuleRepository) Update(ctx context.Context, rule *models.GroupingRule) error {
+	stored := clone(rule)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.groupingRules[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.groupingRules[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *GroupingRuleRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.groupingRules, id)
+		return nil
+	})
+}
+
+type GroupingOverrideRepository struct {
+	db *Database
+}
+
+func (r *GroupingOverrideRepository) Create(ctx context.Context, override *models.GroupingOverride) error {
+	stored := clone(override)
+	return r.db.write(func(d *Database) error {
+		d.groupingOverrides[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *GroupingOverrideRepository) GetByURL(ctx context.Context, userID, url string) (*models.GroupingOverride, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	for _, override := range r.db.groupingOverrides {
+		if override.UserID == userID && override.URL == url {
+			return clone(override), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *GroupingOverrideRepository) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingOverride, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var overrides []*models.GroupingOverride
+	for _, override := range r.db.groupingOverrides {
+		if override.UserID == userID {
+			overrides = append(overrides, override)
+		}
+	}
+
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].URL < overrides[j].URL })
+	return cloneAll(overrides), nil
+}
+
+func (r *GroupingOverrideRepository) Update(ctx context.Context, override *models.GroupingOverride) error {
+	stored := clone(override)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.groupingOverrides[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.groupingOverrides[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *GroupingOverrideRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.groupingOverrides, id)
+		return nil
+	})
+}
+
+type ReportGroupingRepository struct {
+	db *Database
+}
+
+func (r *ReportGroupingRepository) Create(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	stored := clone(reportGrouping)
+	return r.db.write(func(d *Database) error {
+		d.reportGroupings[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportGroupingRepository) GetByID(ctx context.Context, id string) (*models.ReportGrouping, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	reportGrouping, exists := r.db.reportGroupings[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(reportGrouping), nil
+}
+
+func (r *ReportGroupingRepository) ListByReportID(ctx context.Context, reportID string) ([]*models.ReportGrouping, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var reportGroupings []*models.ReportGrouping
+	for _, reportGrouping := range r.db.reportGroupings {
+		if reportGrouping.ReportID == reportID {
+			reportGroupings = append(reportGroupings, reportGrouping)
+		}
+	}
+
+	sort.Slice(reportGroupings, func(i, j int) bool {
+		if !reportGroupings[i].CreatedAt.Equal(reportGroupings[j].CreatedAt) {
+			return reportGroupings[i].CreatedAt.Before(reportGroupings[j].CreatedAt)
+		}
+		return reportGroupings[i].ID < reportGroupings[j].ID
+	})
+	return cloneAll(reportGroupings), nil
+}
+
+func (r *ReportGroupingRepository) Update(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	stored := clone(reportGrouping)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.reportGroupings[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.reportGroupings[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportGroupingRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.reportGroupings, id)
+		return nil
+	})
 }
 
 type ReportJobRepository struct {
@@ -162,10 +464,11 @@ type ReportJobRepository struct {
 }
 
 func (r *ReportJobRepository) Create(ctx context.Context, job *models.ReportJob) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.jobs[job.ID] = job
-	return nil
+	stored := clone(job)
+	return r.db.write(func(d *Database) error {
+		d.jobs[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReportJobRepository) GetByID(ctx context.Context, id string) (*models.ReportJob, error) {
@@ -175,7 +478,7 @@ func (r *ReportJobRepository) GetByID(ctx context.Context, id string) (*models.R
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return job, nil
+	return clone(job), nil
 }
 
 func (r *ReportJobRepository) List(ctx context.Context, filters repositories.JobFilters) ([]*models.ReportJob, error) {
@@ -183,23 +486,171 @@ func (r *ReportJobRepository) List(ctx context.Context, filters repositories.Job
 	defer r.db.mu.RUnlock()
 	var jobs []*models.ReportJob
 	for _, job := range r.db.jobs {
+		if filters.Status != "" && job.Status != filters.Status {
+			continue
+		}
+		if filters.UserID != "" && job.UserID != filters.UserID {
+			continue
+		}
+		if filters.JobType != "" && job.JobType != filters.JobType {
+			continue
+		}
+		if filters.CompletedBefore != nil && (job.CompletedAt == nil || !job.CompletedAt.Before(*filters.CompletedBefore)) {
+			continue
+		}
 		jobs = append(jobs, job)
 	}
-	return jobs, nil
+
+	// Newest first, unless asked for oldest first
+	sort.Slice(jobs, func(i, j int) bool {
+		a, b := jobs[i], jobs[j]
+		if filters.OldestFirst {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return cloneAll(paginate(jobs, filters.Limit, filters.Offset)), nil
 }
 
 func (r *ReportJobRepository) Update(ctx context.Context, job *models.ReportJob) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.jobs[job.ID] = job
-	return nil
+	stored := clone(job)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.jobs[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.jobs[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReportJobRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.jobs, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.jobs, id)
+		return nil
+	})
+}
+
+func (r *ReportJobRepository) Finish(ctx context.Context, job *models.ReportJob) error {
+	stored := clone(job)
+	return r.db.write(func(d *Database) error {
+		if current, exists := d.jobs[stored.ID]; !exists || current.Status != models.ReportJobStatusRunning {
+			return ErrNotFound
+		}
+		d.jobs[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportJobRepository) Claim(ctx context.Context, id string, startedAt time.Time) (*models.ReportJob, error) {
+	var claimed *models.ReportJob
+	err := r.db.write(func(d *Database) error {
+		job, exists := d.jobs[id]
+		if !exists || job.Status != models.ReportJobStatusPending {
+			return ErrNotFound
+		}
+		job = clone(job)
+		job.Status = models.ReportJobStatusRunning
+		job.StartedAt = &startedAt
+		job.ErrorMessage = nil
+		job.CompletedAt = nil
+		job.UpdatedAt = startedAt
+		d.jobs[id] = job
+		claimed = job
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone(claimed), nil
+}
+
+type ReportScheduleRepository struct {
+	db *Database
+}
+
+func (r *ReportScheduleRepository) Create(ctx context.Context, schedule *models.ReportSchedule) error {
+	stored := clone(schedule)
+	return r.db.write(func(d *Database) error {
+		d.schedules[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	schedule, exists := r.db.schedules[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(schedule), nil
+}
+
+func (r *ReportScheduleRepository) List(ctx context.Context, filters repositories.ScheduleFilters) ([]*models.ReportSchedule, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var schedules []*models.ReportSchedule
+	for _, schedule := range r.db.schedules {
+		if filters.UserID != "" && schedule.UserID != filters.UserID {
+			continue
+		}
+		if schedule.IsPaused && !filters.IncludePaused {
+			continue
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return cloneAll(paginate(schedules, filters.Limit, filters.Offset)), nil
+}
+
+func (r *ReportScheduleRepository) ListDue(ctx context.Context, before time.Time) ([]*models.ReportSchedule, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var schedules []*models.ReportSchedule
+	for _, schedule := range r.db.schedules {
+		if schedule.IsPaused || schedule.NextRunAt == nil || schedule.NextRunAt.After(before) {
+			continue
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	// Longest overdue first
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].NextRunAt.Equal(*schedules[j].NextRunAt) {
+			return schedules[i].NextRunAt.Before(*schedules[j].NextRunAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return cloneAll(schedules), nil
+}
+
+func (r *ReportScheduleRepository) Update(ctx context.Context, schedule *models.ReportSchedule) error {
+	stored := clone(schedule)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.schedules[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.schedules[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *ReportScheduleRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.schedules, id)
+		return nil
+	})
 }
 
 type ReleaseRepository struct {
@@ -207,10 +658,11 @@ type ReleaseRepository struct {
 }
 
 func (r *ReleaseRepository) Create(ctx context.Context, release *models.Release) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.releases[release.ID] = release
-	return nil
+	stored := clone(release)
+	return r.db.write(func(d *Database) error {
+		d.releases[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReleaseRepository) GetByID(ctx context.Context, id string) (*models.Release, error) {
@@ -220,7 +672,7 @@ func (r *ReleaseRepository) GetByID(ctx context.Context, id string) (*models.Rel
 	if !exists {
 		return nil, ErrNotFound
 	}
-	return release, nil
+	return clone(release), nil
 }
 
 func (r *ReleaseRepository) List(ctx context.Context, filters repositories.ReleaseFilters) ([]*models.Release, error) {
@@ -228,22 +680,106 @@ func (r *ReleaseRepository) List(ctx context.Context, filters repositories.Relea
 	defer r.db.mu.RUnlock()
 	var releases []*models.Release
 	for _, release := range r.db.releases {
+		if filters.UserID != "" && release.UserID != filters.UserID {
+			continue
+		}
 		releases = append(releases, release)
 	}
-	return releases, nil
+
+	// Latest release first
+	sort.Slice(releases, func(i, j int) bool {
+		if !releases[i].ReleaseDate.Equal(releases[j].ReleaseDate) {
+			return releases[i].ReleaseDate.After(releases[j].ReleaseDate)
+		}
+		return releases[i].ID > releases[j].ID
+	})
+	return cloneAll(paginate(releases, filters.Limit, filters.Offset)), nil
 }
 
 func (r *ReleaseRepository) Update(ctx context.Context, release *models.Release) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	r.db.releases[release.ID] = release
-	return nil
+	stored := clone(release)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.releases[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.releases[stored.ID] = stored
+		return nil
+	})
 }
 
 func (r *ReleaseRepository) Delete(ctx context.Context, id string) error {
-	r.db.mu.Lock()
-	defer r.db.mu.Unlock()
-	delete(r.db.releases, id)
-	return nil
+	return r.db.write(func(d *Database) error {
+		delete(d.releases, id)
+		return nil
+	})
+}
+
+type APITokenRepository struct {
+	db *Database
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	stored := clone(token)
+	return r.db.write(func(d *Database) error {
+		d.apiTokens[stored.ID] = stored
+		return nil
+	})
 }
 
+func (r *APITokenRepository) GetByID(ctx context.Context, id string) (*models.APIToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	token, exists := r.db.apiTokens[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(token), nil
+}
+
+func (r *APITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	for _, token := range r.db.apiTokens {
+		if token.TokenHash == tokenHash {
+			return clone(token), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *APITokenRepository) ListByUserID(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	var tokens []*models.APIToken
+	for _, token := range r.db.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return cloneAll(tokens), nil
+}
+
+func (r *APITokenRepository) Update(ctx context.Context, token *models.APIToken) error {
+	stored := clone(token)
+	return r.db.write(func(d *Database) error {
+		if _, exists := d.apiTokens[stored.ID]; !exists {
+			return ErrNotFound
+		}
+		d.apiTokens[stored.ID] = stored
+		return nil
+	})
+}
+
+func (r *APITokenRepository) Delete(ctx context.Context, id string) error {
+	return r.db.write(func(d *Database) error {
+		delete(d.apiTokens, id)
+		return nil
+	})
+}
@@ -1,91 +1,68 @@
 package mysql
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"jonopens/sitemapper/internal/database/migrate"
+	"jonopens/sitemapper/internal/database/sqlstore"
 	"jonopens/sitemapper/internal/repositories"
 )
 
-// Database implements repositories.Database for MySQL
-type Database struct {
-	db *sql.DB
-	tx *sql.Tx
+// dialect describes MySQL to the shared SQL repositories. MySQL commits DDL
+// statements implicitly, so migrations can't run in a transaction, and the
+// driver runs one statement per call. TEXT columns can only be indexed by
+// prefix, so URL indexes cover the first 255 characters.
+var dialect = sqlstore.Dialect{
+	Dialect: migrate.Dialect{
+		CreateTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at DATETIME(6) NOT NULL
+		)`,
+		Split: true,
+	},
+	Types: sqlstore.ColumnTypes{
+		ID:           "VARCHAR(64)",
+		String:       "VARCHAR(255)",
+		Enum:         "VARCHAR(32)",
+		Key:          "VARCHAR(700)",
+		Hash:         "CHAR(64)",
+		Timestamp:    "DATETIME(6)",
+		Float:        "DOUBLE",
+		JSON:         "JSON",
+		IndexedURL:   "url(255)",
+		TableOptions: " ENGINE=InnoDB",
+	},
 }
 
-// New creates a new MySQL database connection
-func New(connectionString string) (*Database, error) {
-	db, err := sql.Open("mysql", connectionString)
+// New creates a new MySQL database connection from a DSN such as
+// user:password@tcp(localhost:3306)/sitemapper
+func New(connectionString string) (*sqlstore.Database, error) {
+	cfg, err := mysql.ParseDSN(connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MySQL DSN: %w", err)
+	}
+	// Timestamps are scanned into time.Time and stored in UTC (the driver's
+	// default location)
+	cfg.ParseTime = true
+	// Report matched rather than changed rows, so updates that change nothing
+	// aren't mistaken for missing rows
+	cfg.ClientFoundRows = true
+
+	connector, err := mysql.NewConnector(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db := sql.OpenDB(connector)
 
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{db: db}, nil
-}
-
-// Entries returns the entry repository
-func (d *Database) Entries() repositories.EntryRepository {
-	return &EntryRepository{db: d.db, tx: d.tx}
-}
-
-// Reports returns the report repository
-func (d *Database) Reports() repositories.ReportRepository {
-	return &ReportRepository{db: d.db, tx: d.tx}
-}
-
-// Users returns the user repository
-func (d *Database) Users() repositories.UserRepository {
-	return &UserRepository{db: d.db, tx: d.tx}
-}
-
-// Groupings returns the grouping repository
-func (d *Database) Groupings() repositories.GroupingRepository {
-	return &GroupingRepository{db: d.db, tx: d.tx}
-}
-
-// ReportJobs returns the report job repository
-func (d *Database) ReportJobs() repositories.ReportJobRepository {
-	return &ReportJobRepository{db: d.db, tx: d.tx}
-}
-
-// Releases returns the release repository
-func (d *Database) Releases() repositories.ReleaseRepository {
-	return &ReleaseRepository{db: d.db, tx: d.tx}
-}
-
-// BeginTx starts a new transaction
-func (d *Database) BeginTx(ctx context.Context) (repositories.Database, error) {
-	tx, err := d.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &Database{db: d.db, tx: tx}, nil
-}
-
-// Commit commits the transaction
-func (d *Database) Commit() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to commit")
-	}
-	return d.tx.Commit()
-}
-
-// Rollback rolls back the transaction
-func (d *Database) Rollback() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to rollback")
-	}
-	return d.tx.Rollback()
-}
-
-// Close closes the database connection
-func (d *Database) Close() error {
-	return d.db.Close()
+	return sqlstore.New(db, dialect), nil
 }
 
+// Common error, shared by every backend so callers can match it
+var ErrNotFound = repositories.ErrNotFound
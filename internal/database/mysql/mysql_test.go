@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"context"
+	"math"
+	"os"
+	"testing"
+
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/repositories/repotest"
+)
+
+// TestConformance needs a disposable database, named by
+// SITEMAPPER_TEST_MYSQL_URL. Every test drops and recreates its schema.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("SITEMAPPER_TEST_MYSQL_URL")
+	if url == "" {
+		t.Skip("SITEMAPPER_TEST_MYSQL_URL is not set")
+	}
+
+	repotest.Suite{
+		New: func(t *testing.T) repositories.Database {
+			db, err := New(url)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			ctx := context.Background()
+			if _, err := db.Migrator().Down(ctx, math.MaxInt); err != nil {
+				t.Fatalf("Down: %v", err)
+			}
+			if _, err := db.Migrator().Up(ctx); err != nil {
+				t.Fatalf("Up: %v", err)
+			}
+			return db
+		},
+		ErrNotFound: ErrNotFound,
+	}.Run(t)
+}
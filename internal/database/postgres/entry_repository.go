@@ -1,45 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-	"jonopens/sitemapper/internal/repositories"
-)
-
-type EntryRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *EntryRepository) Create(ctx context.Context, entry *models.Entry) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *EntryRepository) GetByID(ctx context.Context, id string) (*models.Entry, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *EntryRepository) List(ctx context.Context, filters repositories.EntryFilters) ([]*models.Entry, error) {
-	// TODO: Implement PostgreSQL-specific list logic
-	return nil, nil
-}
-
-func (r *EntryRepository) Update(ctx context.Context, entry *models.Entry) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *EntryRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
-func (r *EntryRepository) CountByType(ctx context.Context, entryType models.EntryType) (int, error) {
-	// TODO: Implement PostgreSQL-specific count logic
-	return 0, nil
-}
-
@@ -1,39 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-)
-
-type GroupingRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *GroupingRepository) Create(ctx context.Context, grouping *models.Group) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *GroupingRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *GroupingRepository) List(ctx context.Context) ([]*models.Group, error) {
-	// TODO: Implement PostgreSQL-specific list logic
-	return nil, nil
-}
-
-func (r *GroupingRepository) Update(ctx context.Context, grouping *models.Group) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *GroupingRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
@@ -1,22 +1,41 @@
 package postgres
 
 import (
-	"context"
 	"database/sql"
 	"fmt"
 
 	_ "github.com/lib/pq"
+	"jonopens/sitemapper/internal/database/migrate"
+	"jonopens/sitemapper/internal/database/sqlstore"
 	"jonopens/sitemapper/internal/repositories"
 )
 
-// Database implements repositories.Database for PostgreSQL
-type Database struct {
-	db *sql.DB
-	tx *sql.Tx
+// dialect describes PostgreSQL to the shared SQL repositories
+var dialect = sqlstore.Dialect{
+	Dialect: migrate.Dialect{
+		Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+		CreateTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)`,
+		Transactional: true,
+	},
+	Types: sqlstore.ColumnTypes{
+		ID:         "TEXT",
+		String:     "TEXT",
+		Enum:       "TEXT",
+		Key:        "TEXT",
+		Hash:       "TEXT",
+		Timestamp:  "TIMESTAMPTZ",
+		Float:      "DOUBLE PRECISION",
+		JSON:       "JSONB",
+		IndexedURL: "url",
+	},
 }
 
 // New creates a new PostgreSQL database connection
-func New(connectionString string) (*Database, error) {
+func New(connectionString string) (*sqlstore.Database, error) {
 	db, err := sql.Open("postgres", connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -26,66 +45,8 @@ func New(connectionString string) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{db: db}, nil
-}
-
-// Entries returns the entry repository
-func (d *Database) Entries() repositories.EntryRepository {
-	return &EntryRepository{db: d.db, tx: d.tx}
-}
-
-// Reports returns the report repository
-func (d *Database) Reports() repositories.ReportRepository {
-	return &ReportRepository{db: d.db, tx: d.tx}
-}
-
-// Users returns the user repository
-func (d *Database) Users() repositories.UserRepository {
-	return &UserRepository{db: d.db, tx: d.tx}
-}
-
-// Groupings returns the grouping repository
-func (d *Database) Groupings() repositories.GroupingRepository {
-	return &GroupingRepository{db: d.db, tx: d.tx}
-}
-
-// ReportJobs returns the report job repository
-func (d *Database) ReportJobs() repositories.ReportJobRepository {
-	return &ReportJobRepository{db: d.db, tx: d.tx}
-}
-
-// Releases returns the release repository
-func (d *Database) Releases() repositories.ReleaseRepository {
-	return &ReleaseRepository{db: d.db, tx: d.tx}
-}
-
-// BeginTx starts a new transaction
-func (d *Database) BeginTx(ctx context.Context) (repositories.Database, error) {
-	tx, err := d.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &Database{db: d.db, tx: tx}, nil
-}
-
-// Commit commits the transaction
-func (d *Database) Commit() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to commit")
-	}
-	return d.tx.Commit()
-}
-
-// Rollback rolls back the transaction
-func (d *Database) Rollback() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to rollback")
-	}
-	return d.tx.Rollback()
-}
-
-// Close closes the database connection
-func (d *Database) Close() error {
-	return d.db.Close()
+	return sqlstore.New(db, dialect), nil
 }
 
+// Common error, shared by every backend so callers can match it
+var ErrNotFound = repositories.ErrNotFound
@@ -1,40 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-	"jonopens/sitemapper/internal/repositories"
-)
-
-type ReleaseRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReleaseRepository) Create(ctx context.Context, release *models.Release) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *ReleaseRepository) GetByID(ctx context.Context, id string) (*models.Release, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *ReleaseRepository) List(ctx context.Context, filters repositories.ReleaseFilters) ([]*models.Release, error) {
-	// TODO: Implement PostgreSQL-specific list logic
-	return nil, nil
-}
-
-func (r *ReleaseRepository) Update(ctx context.Context, release *models.Release) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *ReleaseRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
@@ -1,40 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-	"jonopens/sitemapper/internal/repositories"
-)
-
-type ReportJobRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReportJobRepository) Create(ctx context.Context, job *models.ReportJob) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *ReportJobRepository) GetByID(ctx context.Context, id string) (*models.ReportJob, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *ReportJobRepository) List(ctx context.Context, filters repositories.JobFilters) ([]*models.ReportJob, error) {
-	// TODO: Implement PostgreSQL-specific list logic
-	return nil, nil
-}
-
-func (r *ReportJobRepository) Update(ctx context.Context, job *models.ReportJob) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *ReportJobRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
@@ -1,45 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-	"jonopens/sitemapper/internal/repositories"
-)
-
-type ReportRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReportRepository) Create(ctx context.Context, report *models.Report) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *ReportRepository) GetByID(ctx context.Context, id string) (*models.Report, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *ReportRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Report, error) {
-	// TODO: Implement PostgreSQL-specific get by user logic
-	return nil, nil
-}
-
-func (r *ReportRepository) List(ctx context.Context, filters repositories.ReportFilters) ([]*models.Report, error) {
-	// TODO: Implement PostgreSQL-specific list logic
-	return nil, nil
-}
-
-func (r *ReportRepository) Update(ctx context.Context, report *models.Report) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *ReportRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
@@ -1,39 +0,0 @@
-package postgres
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-)
-
-type UserRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
-	// TODO: Implement PostgreSQL-specific create logic
-	return nil
-}
-
-func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-	// TODO: Implement PostgreSQL-specific get logic
-	return nil, nil
-}
-
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	// TODO: Implement PostgreSQL-specific get by email logic
-	return nil, nil
-}
-
-func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
-	// TODO: Implement PostgreSQL-specific update logic
-	return nil
-}
-
-func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	// TODO: Implement PostgreSQL-specific delete logic
-	return nil
-}
-
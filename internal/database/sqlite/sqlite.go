@@ -4,88 +4,103 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
+	"jonopens/sitemapper/internal/database/migrate"
+	"jonopens/sitemapper/internal/database/sqlstore"
 	"jonopens/sitemapper/internal/repositories"
 )
 
-// Database implements repositories.Database for SQLite
-type Database struct {
-	db *sql.DB
-	tx *sql.Tx
+// dialect describes SQLite to the shared SQL repositories
+var dialect = sqlstore.Dialect{
+	Dialect: migrate.Dialect{
+		Placeholder: func(n int) string { return fmt.Sprintf("?%d", n) },
+		CreateTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)`,
+		Transactional: true,
+	},
+	UTC: true,
+	Types: sqlstore.ColumnTypes{
+		ID:         "TEXT",
+		String:     "TEXT",
+		Enum:       "TEXT",
+		Key:        "TEXT",
+		Hash:       "TEXT",
+		Timestamp:  "TIMESTAMP",
+		Float:      "REAL",
+		JSON:       "TEXT",
+		IndexedURL: "url",
+	},
 }
 
-// New creates a new SQLite database connection
-func New(connectionString string) (*Database, error) {
-	db, err := sql.Open("sqlite3", connectionString)
+// connectionParams are added to every connection string unless already set:
+// timestamps are read in local time, the journal is write-ahead logged so
+// readers don't block the writer, foreign keys are enforced, and transactions
+// take the write lock up front and wait for it instead of failing
+var connectionParams = []string{
+	"_loc=auto",
+	"_journal_mode=WAL",
+	"_foreign_keys=on",
+	"_busy_timeout=10000",
+	"_txlock=immediate",
+}
+
+// New opens a SQLite database file, creating it and its schema on first
+// open and applying any pending migrations
+func New(connectionString string) (*sqlstore.Database, error) {
+	path, _, _ := strings.Cut(strings.TrimPrefix(connectionString, "file:"), "?")
+	inMemory := path == "" || path == ":memory:" || strings.Contains(connectionString, "mode=memory")
+	if !inMemory {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", withConnectionParams(connectionString))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	// Every connection to an in-memory database is a separate database
+	if inMemory {
+		db.SetMaxOpenConns(1)
+	}
+
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &Database{db: db}, nil
-}
-
-// Entries returns the entry repository
-func (d *Database) Entries() repositories.EntryRepository {
-	return &EntryRepository{db: d.db, tx: d.tx}
-}
-
-// Reports returns the report repository
-func (d *Database) Reports() repositories.ReportRepository {
-	return &ReportRepository{db: d.db, tx: d.tx}
-}
-
-// Users returns the user repository
-func (d *Database) Users() repositories.UserRepository {
-	return &UserRepository{db: d.db, tx: d.tx}
-}
-
-// Groupings returns the grouping repository
-func (d *Database) Groupings() repositories.GroupingRepository {
-	return &GroupingRepository{db: d.db, tx: d.tx}
-}
-
-// ReportJobs returns the report job repository
-func (d *Database) ReportJobs() repositories.ReportJobRepository {
-	return &ReportJobRepository{db: d.db, tx: d.tx}
-}
-
-// Releases returns the release repository
-func (d *Database) Releases() repositories.ReleaseRepository {
-	return &ReleaseRepository{db: d.db, tx: d.tx}
-}
-
-// BeginTx starts a new transaction
-func (d *Database) BeginTx(ctx context.Context) (repositories.Database, error) {
-	tx, err := d.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, err
+	d := sqlstore.New(db, dialect)
+	if _, err := d.Migrator().Up(context.Background()); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
-	return &Database{db: d.db, tx: tx}, nil
-}
 
-// Commit commits the transaction
-func (d *Database) Commit() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to commit")
-	}
-	return d.tx.Commit()
+	return d, nil
 }
 
-// Rollback rolls back the transaction
-func (d *Database) Rollback() error {
-	if d.tx == nil {
-		return fmt.Errorf("no transaction to rollback")
+// withConnectionParams adds connectionParams the connection string doesn't set
+func withConnectionParams(connectionString string) string {
+	for _, param := range connectionParams {
+		key, _, _ := strings.Cut(param, "=")
+		if strings.Contains(connectionString, key+"=") {
+			continue
+		}
+		if strings.Contains(connectionString, "?") {
+			connectionString += "&" + param
+		} else {
+			connectionString += "?" + param
+		}
 	}
-	return d.tx.Rollback()
-}
-
-// Close closes the database connection
-func (d *Database) Close() error {
-	return d.db.Close()
+	return connectionString
 }
 
+// Common error, shared by every backend so callers can match it
+var ErrNotFound = repositories.ErrNotFound
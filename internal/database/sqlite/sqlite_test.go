@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/repositories/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Suite{
+		New: func(t *testing.T) repositories.Database {
+			db, err := New(filepath.Join(t.TempDir(), "sitemapper.db"))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			return db
+		},
+		ErrNotFound: ErrNotFound,
+	}.Run(t)
+}
+
+func TestConformanceInMemory(t *testing.T) {
+	repotest.Suite{
+		New: func(t *testing.T) repositories.Database {
+			db, err := New(":memory:")
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			return db
+		},
+		ErrNotFound: ErrNotFound,
+	}.Run(t)
+}
@@ -1,171 +0,0 @@
-package sqlite
-
-import (
-	"context"
-	"database/sql"
-
-	"jonopens/sitemapper/internal/models"
-	"jonopens/sitemapper/internal/repositories"
-)
-
-// Repository stubs - implement the same structure as PostgreSQL
-// These are placeholders for actual SQLite-specific implementations
-
-type EntryRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *EntryRepository) Create(ctx context.Context, entry *models.Entry) error {
-	return nil
-}
-
-func (r *EntryRepository) GetByID(ctx context.Context, id string) (*models.Entry, error) {
-	return nil, nil
-}
-
-func (r *EntryRepository) List(ctx context.Context, filters repositories.EntryFilters) ([]*models.Entry, error) {
-	return nil, nil
-}
-
-func (r *EntryRepository) Update(ctx context.Context, entry *models.Entry) error {
-	return nil
-}
-
-func (r *EntryRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
-func (r *EntryRepository) CountByType(ctx context.Context, entryType models.EntryType) (int, error) {
-	return 0, nil
-}
-
-type ReportRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReportRepository) Create(ctx context.Context, report *models.Report) error {
-	return nil
-}
-
-func (r *ReportRepository) GetByID(ctx context.Context, id string) (*models.Report, error) {
-	return nil, nil
-}
-
-func (r *ReportRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Report, error) {
-	return nil, nil
-}
-
-func (r *ReportRepository) List(ctx context.Context, filters repositories.ReportFilters) ([]*models.Report, error) {
-	return nil, nil
-}
-
-func (r *ReportRepository) Update(ctx context.Context, report *models.Report) error {
-	return nil
-}
-
-func (r *ReportRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
-type UserRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
-	return nil
-}
-
-func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
-	return nil, nil
-}
-
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	return nil, nil
-}
-
-func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
-	return nil
-}
-
-func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
-type GroupingRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *GroupingRepository) Create(ctx context.Context, grouping *models.Group) error {
-	return nil
-}
-
-func (r *GroupingRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
-	return nil, nil
-}
-
-func (r *GroupingRepository) List(ctx context.Context) ([]*models.Group, error) {
-	return nil, nil
-}
-
-func (r *GroupingRepository) Update(ctx context.Context, grouping *models.Group) error {
-	return nil
-}
-
-func (r *GroupingRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
-type ReportJobRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReportJobRepository) Create(ctx context.Context, job *models.ReportJob) error {
-	return nil
-}
-
-func (r *ReportJobRepository) GetByID(ctx context.Context, id string) (*models.ReportJob, error) {
-	return nil, nil
-}
-
-func (r *ReportJobRepository) List(ctx context.Context, filters repositories.JobFilters) ([]*models.ReportJob, error) {
-	return nil, nil
-}
-
-func (r *ReportJobRepository) Update(ctx context.Context, job *models.ReportJob) error {
-	return nil
-}
-
-func (r *ReportJobRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
-type ReleaseRepository struct {
-	db *sql.DB
-	tx *sql.Tx
-}
-
-func (r *ReleaseRepository) Create(ctx context.Context, release *models.Release) error {
-	return nil
-}
-
-func (r *ReleaseRepository) GetByID(ctx context.Context, id string) (*models.Release, error) {
-	return nil, nil
-}
-
-func (r *ReleaseRepository) List(ctx context.Context, filters repositories.ReleaseFilters) ([]*models.Release, error) {
-	return nil, nil
-}
-
-func (r *ReleaseRepository) Update(ctx context.Context, release *models.Release) error {
-	return nil
-}
-
-func (r *ReleaseRepository) Delete(ctx context.Context, id string) error {
-	return nil
-}
-
@@ -0,0 +1,95 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+type APITokenRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const apiTokenColumns = `id, user_id, name, prefix, token_hash, scopes, expires_at, revoked_at, created_at, updated_at`
+
+func apiTokenArgs(token *models.APIToken) []any {
+	return []any{
+		token.ID, token.UserID, token.Name, token.Prefix, token.TokenHash, strings.Join(token.Scopes, " "),
+		token.ExpiresAt, token.RevokedAt, token.CreatedAt, token.UpdatedAt,
+	}
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, token *models.APIToken) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO api_tokens (`+apiTokenColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		apiTokenArgs(token)...)
+	return err
+}
+
+func (r *APITokenRepository) GetByID(ctx context.Context, id string) (*models.APIToken, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE id = ?`, id)
+	token, err := scanAPIToken(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return token, nil
+}
+
+func (r *APITokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash)
+	token, err := scanAPIToken(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return token, nil
+}
+
+func (r *APITokenRepository) ListByUserID(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens
+		WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *APITokenRepository) Update(ctx context.Context, token *models.APIToken) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE api_tokens SET
+		user_id = ?, name = ?, prefix = ?, token_hash = ?, scopes = ?,
+		expires_at = ?, revoked_at = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		idLast(apiTokenArgs(token))...)
+}
+
+func (r *APITokenRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM api_tokens WHERE id = ?`, id)
+	return err
+}
+
+func scanAPIToken(row scanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.TokenHash, &scopes,
+		&token.ExpiresAt, &token.RevokedAt, &token.CreatedAt, &token.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
@@ -0,0 +1,184 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type EntryRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const entryColumns = `id, report_id, grouping_id, type, url, normalized_url,
+	last_modified, change_freq, priority, extensions, is_valid, validation_error,
+	http_status_code, is_live, response_time_ms, liveness_checked_at, liveness_error,
+	final_url, redirect_chain, selection_reason, created_at, updated_at`
+
+func (r *EntryRepository) Create(ctx context.Context, entry *models.Entry) error {
+	args, err := entryArgs(entry)
+	if err != nil {
+		return err
+	}
+	_, err = conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO entries (`+entryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		args...)
+	return err
+}
+
+// entryPlaceholders is the VALUES tuple of one entry row
+var entryPlaceholders = "(" + strings.TrimSuffix(strings.Repeat("?, ", 22), ", ") + ")"
+
+// entryBatchSize is the number of rows per multi-row INSERT, keeping each
+// statement under the parameter limit of every dialect (32766 in SQLite)
+const entryBatchSize = 500
+
+// CreateBatch inserts entries with multi-row INSERT statements of up to
+// entryBatchSize rows each. Outside a transaction it opens one, so a failed
+// batch stores nothing.
+func (r *EntryRepository) CreateBatch(ctx context.Context, entries []*models.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if r.tx == nil {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := (&EntryRepository{db: r.db, tx: tx, dialect: r.dialect}).CreateBatch(ctx, entries); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	for start := 0; start < len(entries); start += entryBatchSize {
+		chunk := entries[start:min(start+entryBatchSize, len(entries))]
+
+		tuples := make([]string, 0, len(chunk))
+		var args []any
+		for _, entry := range chunk {
+			values, err := entryArgs(entry)
+			if err != nil {
+				return err
+			}
+			tuples = append(tuples, entryPlaceholders)
+			args = append(args, values...)
+		}
+
+		_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO entries (`+entryColumns+`)
+		VALUES `+strings.Join(tuples, ", "), args...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EntryRepository) GetByID(ctx context.Context, id string) (*models.Entry, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+entryColumns+` FROM entries WHERE id = ?`, id)
+	entry, err := scanEntry(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return entry, nil
+}
+
+func (r *EntryRepository) List(ctx context.Context, filters repositories.EntryFilters) ([]*models.Entry, error) {
+	var b queryBuilder
+	if filters.ReportID != "" {
+		b.where("report_id = ?", filters.ReportID)
+	}
+	if filters.Type != nil {
+		b.where("type = ?", *filters.Type)
+	}
+	query, args := b.build(`SELECT `+entryColumns+` FROM entries`, "url, id", filters.Limit, filters.Offset)
+
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.Entry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *EntryRepository) Update(ctx context.Context, entry *models.Entry) error {
+	args, err := entryArgs(entry)
+	if err != nil {
+		return err
+	}
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE entries SET
+		report_id = ?, grouping_id = ?, type = ?, url = ?, normalized_url = ?,
+		last_modified = ?, change_freq = ?, priority = ?, extensions = ?, is_valid = ?,
+		validation_error = ?, http_status_code = ?, is_live = ?, response_time_ms = ?,
+		liveness_checked_at = ?, liveness_error = ?, final_url = ?, redirect_chain = ?,
+		selection_reason = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`, idLast(args)...)
+}
+
+func (r *EntryRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM entries WHERE id = ?`, id)
+	return err
+}
+
+func (r *EntryRepository) CountByType(ctx context.Context, entryType models.EntryType) (int, error) {
+	var count int
+	err := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT COUNT(*) FROM entries WHERE type = ?`, entryType).Scan(&count)
+	return count, err
+}
+
+// entryArgs returns the column values of an entry in entryColumns order
+func entryArgs(entry *models.Entry) ([]any, error) {
+	extensions, err := jsonValue(entry.Extensions, entry.Extensions == nil)
+	if err != nil {
+		return nil, err
+	}
+	redirectChain, err := jsonValue(entry.RedirectChain, len(entry.RedirectChain) == 0)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		entry.ID, entry.ReportID, entry.GroupingID, entry.Type, entry.URL, entry.NormalizedURL,
+		entry.LastModified, entry.ChangeFreq, entry.Priority, extensions, entry.IsValid, entry.ValidationError,
+		entry.HTTPStatusCode, entry.IsLive, entry.ResponseTimeMs, entry.LivenessCheckedAt, entry.LivenessError,
+		entry.FinalURL, redirectChain, entry.SelectionReason, entry.CreatedAt, entry.UpdatedAt,
+	}, nil
+}
+
+func scanEntry(row scanner) (*models.Entry, error) {
+	var entry models.Entry
+	var extensions, redirectChain []byte
+	err := row.Scan(
+		&entry.ID, &entry.ReportID, &entry.GroupingID, &entry.Type, &entry.URL, &entry.NormalizedURL,
+		&entry.LastModified, &entry.ChangeFreq, &entry.Priority, &extensions, &entry.IsValid, &entry.ValidationError,
+		&entry.HTTPStatusCode, &entry.IsLive, &entry.ResponseTimeMs, &entry.LivenessCheckedAt, &entry.LivenessError,
+		&entry.FinalURL, &redirectChain, &entry.SelectionReason, &entry.CreatedAt, &entry.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(extensions) > 0 {
+		entry.Extensions = &models.EntryExtensions{}
+		if err := scanJSON(extensions, entry.Extensions); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanJSON(redirectChain, &entry.RedirectChain); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
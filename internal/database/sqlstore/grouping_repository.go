@@ -0,0 +1,71 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+type GroupingRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const groupingColumns = `id, user_id, name, description, created_at, updated_at`
+
+func (r *GroupingRepository) Create(ctx context.Context, grouping *models.Group) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO groupings (`+groupingColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		grouping.ID, grouping.UserID, grouping.Name, grouping.Description, grouping.CreatedAt, grouping.UpdatedAt)
+	return err
+}
+
+func (r *GroupingRepository) GetByID(ctx context.Context, id string) (*models.Group, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+groupingColumns+` FROM groupings WHERE id = ?`, id)
+	grouping, err := scanGrouping(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return grouping, nil
+}
+
+func (r *GroupingRepository) List(ctx context.Context) ([]*models.Group, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+groupingColumns+` FROM groupings ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groupings []*models.Group
+	for rows.Next() {
+		grouping, err := scanGrouping(rows)
+		if err != nil {
+			return nil, err
+		}
+		groupings = append(groupings, grouping)
+	}
+	return groupings, rows.Err()
+}
+
+func (r *GroupingRepository) Update(ctx context.Context, grouping *models.Group) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE groupings SET
+		user_id = ?, name = ?, description = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		grouping.UserID, grouping.Name, grouping.Description, grouping.CreatedAt, grouping.UpdatedAt, grouping.ID)
+}
+
+func (r *GroupingRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM groupings WHERE id = ?`, id)
+	return err
+}
+
+func scanGrouping(row scanner) (*models.Group, error) {
+	var grouping models.Group
+	err := row.Scan(&grouping.ID, &grouping.UserID, &grouping.Name, &grouping.Description, &grouping.CreatedAt, &grouping.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &grouping, nil
+}
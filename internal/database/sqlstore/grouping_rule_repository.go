@@ -0,0 +1,137 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+type GroupingRuleRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const groupingRuleColumns = `id, user_id, grouping_id, type, pattern, priority, created_at, updated_at`
+
+func (r *GroupingRuleRepository) Create(ctx context.Context, rule *models.GroupingRule) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO grouping_rules (`+groupingRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ID, rule.UserID, rule.GroupingID, rule.Type, rule.Pattern, rule.Priority, rule.CreatedAt, rule.UpdatedAt)
+	return err
+}
+
+func (r *GroupingRuleRepository) GetByID(ctx context.Context, id string) (*models.GroupingRule, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+groupingRuleColumns+` FROM grouping_rules WHERE id = ?`, id)
+	rule, err := scanGroupingRule(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return rule, nil
+}
+
+func (r *GroupingRuleRepository) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingRule, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+groupingRuleColumns+` FROM grouping_rules
+		WHERE user_id = ? ORDER BY priority DESC, created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.GroupingRule
+	for rows.Next() {
+		rule, err := scanGroupingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func (r *GroupingRuleRepository) Update(ctx context.Context, rule *models.GroupingRule) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE grouping_rules SET
+		user_id = ?, grouping_id = ?, type = ?, pattern = ?, priority = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		rule.UserID, rule.GroupingID, rule.Type, rule.Pattern, rule.Priority, rule.CreatedAt, rule.UpdatedAt, rule.ID)
+}
+
+func (r *GroupingRuleRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM grouping_rules WHERE id = ?`, id)
+	return err
+}
+
+func scanGroupingRule(row scanner) (*models.GroupingRule, error) {
+	var rule models.GroupingRule
+	err := row.Scan(&rule.ID, &rule.UserID, &rule.GroupingID, &rule.Type, &rule.Pattern, &rule.Priority, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+type GroupingOverrideRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const groupingOverrideColumns = `id, user_id, url, grouping_id, created_at, updated_at`
+
+func (r *GroupingOverrideRepository) Create(ctx context.Context, override *models.GroupingOverride) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO grouping_overrides (`+groupingOverrideColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		override.ID, override.UserID, override.URL, override.GroupingID, override.CreatedAt, override.UpdatedAt)
+	return err
+}
+
+func (r *GroupingOverrideRepository) GetByURL(ctx context.Context, userID, url string) (*models.GroupingOverride, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+groupingOverrideColumns+` FROM grouping_overrides
+		WHERE user_id = ? AND url = ?`, userID, url)
+	override, err := scanGroupingOverride(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return override, nil
+}
+
+func (r *GroupingOverrideRepository) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingOverride, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+groupingOverrideColumns+` FROM grouping_overrides
+		WHERE user_id = ? ORDER BY url`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []*models.GroupingOverride
+	for rows.Next() {
+		override, err := scanGroupingOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, rows.Err()
+}
+
+func (r *GroupingOverrideRepository) Update(ctx context.Context, override *models.GroupingOverride) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE grouping_overrides SET
+		user_id = ?, url = ?, grouping_id = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		override.UserID, override.URL, override.GroupingID, override.CreatedAt, override.UpdatedAt, override.ID)
+}
+
+func (r *GroupingOverrideRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM grouping_overrides WHERE id = ?`, id)
+	return err
+}
+
+func scanGroupingOverride(row scanner) (*models.GroupingOverride, error) {
+	var override models.GroupingOverride
+	err := row.Scan(&override.ID, &override.UserID, &override.URL, &override.GroupingID, &override.CreatedAt, &override.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &override, nil
+}
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS releases;
+DROP TABLE IF EXISTS report_jobs;
+DROP TABLE IF EXISTS report_schedules;
+DROP TABLE IF EXISTS grouping_overrides;
+DROP TABLE IF EXISTS grouping_rules;
+DROP TABLE IF EXISTS report_diff_entries;
+DROP TABLE IF EXISTS report_diffs;
+DROP TABLE IF EXISTS report_groupings;
+DROP TABLE IF EXISTS entries;
+DROP TABLE IF EXISTS groupings;
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS users;
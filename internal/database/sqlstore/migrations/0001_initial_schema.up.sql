@@ -0,0 +1,204 @@
+-- Initial sitemapper schema
+--
+-- Column types come from each backend's sqlstore.ColumnTypes. MySQL can only
+-- index TEXT columns by prefix, so URL indexes use IndexedURL; override URLs
+-- are unique per user and need the fully indexed Key type.
+
+CREATE TABLE users (
+    id          {{.ID}} PRIMARY KEY,
+    email       {{.String}} NOT NULL UNIQUE,
+    name        {{.String}} NOT NULL,
+    phone       TEXT,
+    slack_id    TEXT,
+    created_at  {{.Timestamp}} NOT NULL,
+    updated_at  {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE TABLE reports (
+    id                   {{.ID}} PRIMARY KEY,
+    user_id              {{.ID}} NOT NULL,
+    entry_count          INTEGER NOT NULL DEFAULT 0,
+    stored_entry_count   INTEGER NOT NULL DEFAULT 0,
+    valid_entry_count    INTEGER NOT NULL DEFAULT 0,
+    invalid_entry_count  INTEGER NOT NULL DEFAULT 0,
+    live_entry_count     INTEGER NOT NULL DEFAULT 0,
+    down_entry_count     INTEGER NOT NULL DEFAULT 0,
+    grouping_count       INTEGER NOT NULL DEFAULT 0,
+    ungrouped_count      INTEGER NOT NULL DEFAULT 0,
+    child_sitemap_count  INTEGER NOT NULL DEFAULT 0,
+    is_fully_stored      BOOLEAN NOT NULL DEFAULT TRUE,
+    sampling_strategy    {{.Enum}} NOT NULL,
+    sampling_rate        {{.Float}},
+    created_at           {{.Timestamp}} NOT NULL,
+    updated_at           {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_reports_user_created ON reports (user_id, created_at DESC);
+
+CREATE TABLE groupings (
+    id           {{.ID}} PRIMARY KEY,
+    user_id      {{.ID}} NOT NULL,
+    name         {{.String}} NOT NULL,
+    description  TEXT,
+    created_at   {{.Timestamp}} NOT NULL,
+    updated_at   {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_groupings_user ON groupings (user_id);
+
+CREATE TABLE entries (
+    id                   {{.ID}} PRIMARY KEY,
+    report_id            {{.ID}} NOT NULL,
+    grouping_id          {{.ID}},
+    type                 {{.Enum}} NOT NULL,
+    url                  TEXT NOT NULL,
+    normalized_url       TEXT,
+    last_modified        {{.Timestamp}},
+    change_freq          {{.Enum}},
+    priority             {{.Float}},
+    extensions           {{.JSON}},
+    is_valid             BOOLEAN NOT NULL,
+    validation_error     TEXT,
+    http_status_code     INTEGER,
+    is_live              BOOLEAN,
+    response_time_ms     INTEGER,
+    liveness_checked_at  {{.Timestamp}},
+    liveness_error       TEXT,
+    final_url            TEXT,
+    redirect_chain       {{.JSON}},
+    selection_reason     {{.Enum}} NOT NULL,
+    created_at           {{.Timestamp}} NOT NULL,
+    updated_at           {{.Timestamp}} NOT NULL,
+    FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE CASCADE
+){{.TableOptions}};
+
+CREATE INDEX idx_entries_report_url ON entries (report_id, {{.IndexedURL}});
+CREATE INDEX idx_entries_url ON entries ({{.IndexedURL}});
+CREATE INDEX idx_entries_grouping ON entries (grouping_id);
+
+CREATE TABLE report_groupings (
+    id                   {{.ID}} PRIMARY KEY,
+    report_id            {{.ID}} NOT NULL,
+    grouping_id          {{.ID}} NOT NULL,
+    total_entry_count    INTEGER NOT NULL DEFAULT 0,
+    stored_entry_count   INTEGER NOT NULL DEFAULT 0,
+    live_entry_count     INTEGER NOT NULL DEFAULT 0,
+    down_entry_count     INTEGER NOT NULL DEFAULT 0,
+    valid_entry_count    INTEGER NOT NULL DEFAULT 0,
+    invalid_entry_count  INTEGER NOT NULL DEFAULT 0,
+    min_url              TEXT,
+    max_url              TEXT,
+    created_at           {{.Timestamp}} NOT NULL,
+    updated_at           {{.Timestamp}} NOT NULL,
+    FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE CASCADE
+){{.TableOptions}};
+
+CREATE INDEX idx_report_groupings_report ON report_groupings (report_id);
+
+CREATE TABLE report_diffs (
+    id                 {{.ID}} PRIMARY KEY,
+    user_id            {{.ID}} NOT NULL,
+    base_report_id     {{.ID}} NOT NULL,
+    compare_report_id  {{.ID}} NOT NULL,
+    entries_added      INTEGER NOT NULL DEFAULT 0,
+    entries_removed    INTEGER NOT NULL DEFAULT 0,
+    entries_changed    INTEGER NOT NULL DEFAULT 0,
+    created_at         {{.Timestamp}} NOT NULL,
+    updated_at         {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_report_diffs_user_created ON report_diffs (user_id, created_at DESC);
+CREATE INDEX idx_report_diffs_base ON report_diffs (base_report_id);
+CREATE INDEX idx_report_diffs_compare ON report_diffs (compare_report_id);
+
+CREATE TABLE report_diff_entries (
+    id              {{.ID}} PRIMARY KEY,
+    report_diff_id  {{.ID}} NOT NULL,
+    url             TEXT NOT NULL,
+    change_type     {{.Enum}} NOT NULL,
+    fields          {{.JSON}},
+    created_at      {{.Timestamp}} NOT NULL,
+    FOREIGN KEY (report_diff_id) REFERENCES report_diffs (id) ON DELETE CASCADE
+){{.TableOptions}};
+
+CREATE INDEX idx_report_diff_entries_diff ON report_diff_entries (report_diff_id, change_type, {{.IndexedURL}});
+
+CREATE TABLE grouping_rules (
+    id           {{.ID}} PRIMARY KEY,
+    user_id      {{.ID}} NOT NULL,
+    grouping_id  {{.ID}} NOT NULL,
+    type         {{.Enum}} NOT NULL,
+    pattern      TEXT NOT NULL,
+    priority     INTEGER NOT NULL DEFAULT 0,
+    created_at   {{.Timestamp}} NOT NULL,
+    updated_at   {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_grouping_rules_user ON grouping_rules (user_id, priority DESC);
+
+CREATE TABLE grouping_overrides (
+    id           {{.ID}} PRIMARY KEY,
+    user_id      {{.ID}} NOT NULL,
+    url          {{.Key}} NOT NULL,
+    grouping_id  {{.ID}} NOT NULL,
+    created_at   {{.Timestamp}} NOT NULL,
+    updated_at   {{.Timestamp}} NOT NULL,
+    UNIQUE (user_id, url)
+){{.TableOptions}};
+
+CREATE TABLE report_schedules (
+    id                           {{.ID}} PRIMARY KEY,
+    user_id                      {{.ID}} NOT NULL,
+    name                         {{.String}} NOT NULL DEFAULT '',
+    source_location              TEXT NOT NULL,
+    cron_expression              {{.String}} NOT NULL,
+    timezone                     {{.String}} NOT NULL,
+    should_check_entry_liveness  BOOLEAN NOT NULL DEFAULT FALSE,
+    max_stored_entries           INTEGER NOT NULL DEFAULT 0,
+    jitter_seconds               INTEGER NOT NULL DEFAULT 0,
+    catch_up                     BOOLEAN NOT NULL DEFAULT TRUE,
+    is_paused                    BOOLEAN NOT NULL DEFAULT FALSE,
+    last_run_at                  {{.Timestamp}},
+    next_run_at                  {{.Timestamp}},
+    created_at                   {{.Timestamp}} NOT NULL,
+    updated_at                   {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_report_schedules_user ON report_schedules (user_id, created_at);
+CREATE INDEX idx_report_schedules_due ON report_schedules (is_paused, next_run_at);
+
+CREATE TABLE report_jobs (
+    id                              {{.ID}} PRIMARY KEY,
+    user_id                         {{.ID}} NOT NULL,
+    report_id                       {{.ID}},
+    report_schedule_id              {{.ID}},
+    compression_format              {{.Enum}},
+    source_location                 TEXT NOT NULL,
+    job_type                        {{.Enum}} NOT NULL,
+    should_check_entry_liveness     BOOLEAN NOT NULL DEFAULT FALSE,
+    should_check_for_valid_entries  BOOLEAN NOT NULL DEFAULT FALSE,
+    max_stored_entries              INTEGER NOT NULL DEFAULT 0,
+    timeout_seconds                 INTEGER NOT NULL DEFAULT 0,
+    status                          {{.Enum}} NOT NULL,
+    error_message                   TEXT,
+    started_at                      {{.Timestamp}},
+    completed_at                    {{.Timestamp}},
+    created_at                      {{.Timestamp}} NOT NULL,
+    updated_at                      {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_report_jobs_status_created ON report_jobs (status, created_at);
+CREATE INDEX idx_report_jobs_user_created ON report_jobs (user_id, created_at DESC);
+
+CREATE TABLE releases (
+    id                  {{.ID}} PRIMARY KEY,
+    user_id             {{.ID}} NOT NULL,
+    release_date        {{.Timestamp}} NOT NULL,
+    version             {{.String}},
+    release_notes       TEXT,
+    report_schedule_id  {{.ID}},
+    created_at          {{.Timestamp}} NOT NULL,
+    updated_at          {{.Timestamp}} NOT NULL
+){{.TableOptions}};
+
+CREATE INDEX idx_releases_user_date ON releases (user_id, release_date DESC);
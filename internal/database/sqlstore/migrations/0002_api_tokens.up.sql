@@ -0,0 +1,17 @@
+-- API tokens for the REST API; only a SHA-256 hash of each token is stored
+
+CREATE TABLE api_tokens (
+    id          {{.ID}} PRIMARY KEY,
+    user_id     {{.ID}} NOT NULL,
+    name        {{.String}} NOT NULL,
+    prefix      {{.Enum}} NOT NULL,
+    token_hash  {{.Hash}} NOT NULL UNIQUE,
+    scopes      TEXT NOT NULL,
+    expires_at  {{.Timestamp}},
+    revoked_at  {{.Timestamp}},
+    created_at  {{.Timestamp}} NOT NULL,
+    updated_at  {{.Timestamp}} NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+){{.TableOptions}};
+
+CREATE INDEX idx_api_tokens_user ON api_tokens (user_id);
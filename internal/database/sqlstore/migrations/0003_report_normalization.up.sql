@@ -0,0 +1,3 @@
+-- Records the normalization rules a report's normalized URLs were computed with
+
+ALTER TABLE reports ADD COLUMN normalization TEXT;
@@ -0,0 +1,84 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type ReleaseRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const releaseColumns = `id, user_id, release_date, version, release_notes, report_schedule_id, created_at, updated_at`
+
+func (r *ReleaseRepository) Create(ctx context.Context, release *models.Release) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO releases (`+releaseColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		release.ID, release.UserID, release.ReleaseDate, release.Version, release.ReleaseNotes,
+		release.ReportScheduleID, release.CreatedAt, release.UpdatedAt)
+	return err
+}
+
+func (r *ReleaseRepository) GetByID(ctx context.Context, id string) (*models.Release, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+releaseColumns+` FROM releases WHERE id = ?`, id)
+	release, err := scanRelease(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return release, nil
+}
+
+func (r *ReleaseRepository) List(ctx context.Context, filters repositories.ReleaseFilters) ([]*models.Release, error) {
+	var b queryBuilder
+	if filters.UserID != "" {
+		b.where("user_id = ?", filters.UserID)
+	}
+	query, args := b.build(`SELECT `+releaseColumns+` FROM releases`, "release_date DESC, id DESC", filters.Limit, filters.Offset)
+
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releases []*models.Release
+	for rows.Next() {
+		release, err := scanRelease(rows)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	return releases, rows.Err()
+}
+
+func (r *ReleaseRepository) Update(ctx context.Context, release *models.Release) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE releases SET
+		user_id = ?, release_date = ?, version = ?, release_notes = ?, report_schedule_id = ?,
+		created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		release.UserID, release.ReleaseDate, release.Version, release.ReleaseNotes,
+		release.ReportScheduleID, release.CreatedAt, release.UpdatedAt, release.ID)
+}
+
+func (r *ReleaseRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM releases WHERE id = ?`, id)
+	return err
+}
+
+func scanRelease(row scanner) (*models.Release, error) {
+	var release models.Release
+	err := row.Scan(
+		&release.ID, &release.UserID, &release.ReleaseDate, &release.Version, &release.ReleaseNotes,
+		&release.ReportScheduleID, &release.CreatedAt, &release.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
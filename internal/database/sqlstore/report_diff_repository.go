@@ -0,0 +1,116 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type ReportDiffRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const reportDiffColumns = `id, user_id, base_report_id, compare_report_id,
+	entries_added, entries_removed, entries_changed, created_at, updated_at`
+
+const reportDiffEntryColumns = `id, report_diff_id, url, change_type, fields, created_at`
+
+func (r *ReportDiffRepository) Create(ctx context.Context, diff *models.ReportDiff) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO report_diffs (`+reportDiffColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		diff.ID, diff.UserID, diff.BaseReportID, diff.CompareReportID,
+		diff.EntriesAdded, diff.EntriesRemoved, diff.EntriesChanged, diff.CreatedAt, diff.UpdatedAt)
+	return err
+}
+
+func (r *ReportDiffRepository) GetByID(ctx context.Context, id string) (*models.ReportDiff, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+reportDiffColumns+` FROM report_diffs WHERE id = ?`, id)
+	diff, err := scanReportDiff(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return diff, nil
+}
+
+func (r *ReportDiffRepository) List(ctx context.Context, filters repositories.ReportDiffFilters) ([]*models.ReportDiff, error) {
+	var b queryBuilder
+	if filters.UserID != "" {
+		b.where("user_id = ?", filters.UserID)
+	}
+	if filters.ReportID != "" {
+		b.where("(base_report_id = ? OR compare_report_id = ?)", filters.ReportID, filters.ReportID)
+	}
+	query, args := b.build(`SELECT `+reportDiffColumns+` FROM report_diffs`, "created_at DESC, id DESC", filters.Limit, filters.Offset)
+
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var diffs []*models.ReportDiff
+	for rows.Next() {
+		diff, err := scanReportDiff(rows)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, rows.Err()
+}
+
+func (r *ReportDiffRepository) Delete(ctx context.Context, id string) error {
+	// Diff entries are removed by ON DELETE CASCADE
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM report_diffs WHERE id = ?`, id)
+	return err
+}
+
+func (r *ReportDiffRepository) CreateEntry(ctx context.Context, entry *models.ReportDiffEntry) error {
+	fields, err := jsonValue(entry.Fields, len(entry.Fields) == 0)
+	if err != nil {
+		return err
+	}
+	_, err = conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO report_diff_entries (`+reportDiffEntryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.ReportDiffID, entry.URL, entry.ChangeType, fields, entry.CreatedAt)
+	return err
+}
+
+func (r *ReportDiffRepository) ListEntries(ctx context.Context, diffID string) ([]*models.ReportDiffEntry, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+reportDiffEntryColumns+` FROM report_diff_entries
+		WHERE report_diff_id = ? ORDER BY change_type, url, id`, diffID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.ReportDiffEntry
+	for rows.Next() {
+		var entry models.ReportDiffEntry
+		var fields []byte
+		if err := rows.Scan(&entry.ID, &entry.ReportDiffID, &entry.URL, &entry.ChangeType, &fields, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := scanJSON(fields, &entry.Fields); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+func scanReportDiff(row scanner) (*models.ReportDiff, error) {
+	var diff models.ReportDiff
+	err := row.Scan(
+		&diff.ID, &diff.UserID, &diff.BaseReportID, &diff.CompareReportID,
+		&diff.EntriesAdded, &diff.EntriesRemoved, &diff.EntriesChanged, &diff.CreatedAt, &diff.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
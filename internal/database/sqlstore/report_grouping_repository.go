@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+type ReportGroupingRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const reportGroupingColumns = `id, report_id, grouping_id, total_entry_count, stored_entry_count,
+	live_entry_count, down_entry_count, valid_entry_count, invalid_entry_count, min_url, max_url,
+	created_at, updated_at`
+
+func (r *ReportGroupingRepository) Create(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO report_groupings (`+reportGroupingColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reportGroupingArgs(reportGrouping)...)
+	return err
+}
+
+func (r *ReportGroupingRepository) GetByID(ctx context.Context, id string) (*models.ReportGrouping, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+reportGroupingColumns+` FROM report_groupings WHERE id = ?`, id)
+	reportGrouping, err := scanReportGrouping(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return reportGrouping, nil
+}
+
+func (r *ReportGroupingRepository) ListByReportID(ctx context.Context, reportID string) ([]*models.ReportGrouping, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, `SELECT `+reportGroupingColumns+` FROM report_groupings
+		WHERE report_id = ? ORDER BY created_at, id`, reportID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reportGroupings []*models.ReportGrouping
+	for rows.Next() {
+		reportGrouping, err := scanReportGrouping(rows)
+		if err != nil {
+			return nil, err
+		}
+		reportGroupings = append(reportGroupings, reportGrouping)
+	}
+	return reportGroupings, rows.Err()
+}
+
+func (r *ReportGroupingRepository) Update(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE report_groupings SET
+		report_id = ?, grouping_id = ?, total_entry_count = ?, stored_entry_count = ?,
+		live_entry_count = ?, down_entry_count = ?, valid_entry_count = ?, invalid_entry_count = ?,
+		min_url = ?, max_url = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`, idLast(reportGroupingArgs(reportGrouping))...)
+}
+
+func (r *ReportGroupingRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM report_groupings WHERE id = ?`, id)
+	return err
+}
+
+// reportGroupingArgs returns the column values of a report grouping in
+// reportGroupingColumns order
+func reportGroupingArgs(rg *models.ReportGrouping) []any {
+	return []any{
+		rg.ID, rg.ReportID, rg.GroupingID, rg.TotalEntryCount, rg.StoredEntryCount,
+		rg.LiveEntryCount, rg.DownEntryCount, rg.ValidEntryCount, rg.InvalidEntryCount, rg.MinURL, rg.MaxURL,
+		rg.CreatedAt, rg.UpdatedAt,
+	}
+}
+
+func scanReportGrouping(row scanner) (*models.ReportGrouping, error) {
+	var rg models.ReportGrouping
+	err := row.Scan(
+		&rg.ID, &rg.ReportID, &rg.GroupingID, &rg.TotalEntryCount, &rg.StoredEntryCount,
+		&rg.LiveEntryCount, &rg.DownEntryCount, &rg.ValidEntryCount, &rg.InvalidEntryCount, &rg.MinURL, &rg.MaxURL,
+		&rg.CreatedAt, &rg.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rg, nil
+}
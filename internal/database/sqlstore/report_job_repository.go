@@ -0,0 +1,137 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type ReportJobRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const reportJobColumns = `id, user_id, report_id, report_schedule_id, compression_format,
+	source_location, job_type, should_check_entry_liveness, should_check_for_valid_entries,
+	max_stored_entries, timeout_seconds, status, error_message, started_at, completed_at,
+	created_at, updated_at`
+
+func (r *ReportJobRepository) Create(ctx context.Context, job *models.ReportJob) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO report_jobs (`+reportJobColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reportJobArgs(job)...)
+	return err
+}
+
+func (r *ReportJobRepository) GetByID(ctx context.Context, id string) (*models.ReportJob, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+reportJobColumns+` FROM report_jobs WHERE id = ?`, id)
+	job, err := scanReportJob(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return job, nil
+}
+
+func (r *ReportJobRepository) List(ctx context.Context, filters repositories.JobFilters) ([]*models.ReportJob, error) {
+	var b queryBuilder
+	if filters.Status != "" {
+		b.where("status = ?", filters.Status)
+	}
+	if filters.UserID != "" {
+		b.where("user_id = ?", filters.UserID)
+	}
+	if filters.JobType != "" {
+		b.where("job_type = ?", filters.JobType)
+	}
+	if filters.CompletedBefore != nil {
+		b.where("completed_at < ?", *filters.CompletedBefore)
+	}
+	orderBy := "created_at DESC, id DESC"
+	if filters.OldestFirst {
+		orderBy = "created_at, id"
+	}
+	query, args := b.build(`SELECT `+reportJobColumns+` FROM report_jobs`, orderBy, filters.Limit, filters.Offset)
+
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.ReportJob
+	for rows.Next() {
+		job, err := scanReportJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *ReportJobRepository) Update(ctx context.Context, job *models.ReportJob) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE report_jobs SET
+		user_id = ?, report_id = ?, report_schedule_id = ?, compression_format = ?,
+		source_location = ?, job_type = ?, should_check_entry_liveness = ?,
+		should_check_for_valid_entries = ?, max_stored_entries = ?, timeout_seconds = ?,
+		status = ?, error_message = ?, started_at = ?, completed_at = ?,
+		created_at = ?, updated_at = ?
+		WHERE id = ?`, idLast(reportJobArgs(job))...)
+}
+
+func (r *ReportJobRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM report_jobs WHERE id = ?`, id)
+	return err
+}
+
+// Claim moves the job to running with one conditional UPDATE, so the database
+// decides which of several concurrent claims wins
+func (r *ReportJobRepository) Claim(ctx context.Context, id string, startedAt time.Time) (*models.ReportJob, error) {
+	err := execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE report_jobs SET
+		status = ?, started_at = ?, error_message = NULL, completed_at = NULL, updated_at = ?
+		WHERE id = ? AND status = ?`,
+		models.ReportJobStatusRunning, startedAt, startedAt, id, models.ReportJobStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Finish is Update conditional on the job still running
+func (r *ReportJobRepository) Finish(ctx context.Context, job *models.ReportJob) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE report_jobs SET
+		user_id = ?, report_id = ?, report_schedule_id = ?, compression_format = ?,
+		source_location = ?, job_type = ?, should_check_entry_liveness = ?,
+		should_check_for_valid_entries = ?, max_stored_entries = ?, timeout_seconds = ?,
+		status = ?, error_message = ?, started_at = ?, completed_at = ?,
+		created_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?`, append(idLast(reportJobArgs(job)), models.ReportJobStatusRunning)...)
+}
+
+// reportJobArgs returns the column values of a job in reportJobColumns order
+func reportJobArgs(job *models.ReportJob) []any {
+	return []any{
+		job.ID, job.UserID, job.ReportID, job.ReportScheduleID, job.CompressionFormat,
+		job.SourceLocation, job.JobType, job.ShouldCheckEntryLiveness, job.ShouldCheckForValidEntries,
+		job.MaxStoredEntries, job.TimeoutSeconds, job.Status, job.ErrorMessage, job.StartedAt, job.CompletedAt,
+		job.CreatedAt, job.UpdatedAt,
+	}
+}
+
+func scanReportJob(row scanner) (*models.ReportJob, error) {
+	var job models.ReportJob
+	err := row.Scan(
+		&job.ID, &job.UserID, &job.ReportID, &job.ReportScheduleID, &job.CompressionFormat,
+		&job.SourceLocation, &job.JobType, &job.ShouldCheckEntryLiveness, &job.ShouldCheckForValidEntries,
+		&job.MaxStoredEntries, &job.TimeoutSeconds, &job.Status, &job.ErrorMessage, &job.StartedAt, &job.CompletedAt,
+		&job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type ReportRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const reportColumns = `id, user_id, entry_count, stored_entry_count, valid_entry_count,
+	invalid_entry_count, live_entry_count, down_entry_count, grouping_count, ungrouped_count,
+	child_sitemap_count, is_fully_stored, sampling_strategy, sampling_rate, normalization, created_at, updated_at`
+
+func (r *ReportRepository) Create(ctx context.Context, report *models.Report) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO reports (`+reportColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reportArgs(report)...)
+	return err
+}
+
+func (r *ReportRepository) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+reportColumns+` FROM reports WHERE id = ?`, id)
+	report, err := scanReport(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return report, nil
+}
+
+func (r *ReportRepository) GetByUserID(ctx context.Context, userID string) ([]*models.Report, error) {
+	return r.List(ctx, repositories.ReportFilters{UserID: userID})
+}
+
+func (r *ReportRepository) List(ctx context.Context, filters repositories.ReportFilters) ([]*models.Report, error) {
+	var b queryBuilder
+	if filters.UserID != "" {
+		b.where("user_id = ?", filters.UserID)
+	}
+	query, args := b.build(`SELECT `+reportColumns+` FROM reports`, "created_at DESC, id DESC", filters.Limit, filters.Offset)
+
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func (r *ReportRepository) Update(ctx context.Context, report *models.Report) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE reports SET
+		user_id = ?, entry_count = ?, stored_entry_count = ?, valid_entry_count = ?,
+		invalid_entry_count = ?, live_entry_count = ?, down_entry_count = ?, grouping_count = ?,
+		ungrouped_count = ?, child_sitemap_count = ?, is_fully_stored = ?,
+		sampling_strategy = ?, sampling_rate = ?, normalization = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`, idLast(reportArgs(report))...)
+}
+
+func (r *ReportRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM reports WHERE id = ?`, id)
+	return err
+}
+
+// reportArgs returns the column values of a report in reportColumns order
+func reportArgs(report *models.Report) []any {
+	return []any{
+		report.ID, report.UserID, report.EntryCount, report.StoredEntryCount, report.ValidEntryCount,
+		report.InvalidEntryCount, report.LiveEntryCount, report.DownEntryCount, report.GroupingCount, report.UngroupedCount,
+		report.ChildSitemapCount, report.IsFullyStored, report.SamplingStrategy, report.SamplingRate, report.Normalization, report.CreatedAt, report.UpdatedAt,
+	}
+}
+
+func scanReport(row scanner) (*models.Report, error) {
+	var report models.Report
+	err := row.Scan(
+		&report.ID, &report.UserID, &report.EntryCount, &report.StoredEntryCount, &report.ValidEntryCount,
+		&report.InvalidEntryCount, &report.LiveEntryCount, &report.DownEntryCount, &report.GroupingCount, &report.UngroupedCount,
+		&report.ChildSitemapCount, &report.IsFullyStored, &report.SamplingStrategy, &report.SamplingRate, &report.Normalization, &report.CreatedAt, &report.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
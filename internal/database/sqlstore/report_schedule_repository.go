@@ -0,0 +1,107 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+type ReportScheduleRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const reportScheduleColumns = `id, user_id, name, source_location, cron_expression, timezone,
+	should_check_entry_liveness, max_stored_entries, jitter_seconds, catch_up, is_paused,
+	last_run_at, next_run_at, created_at, updated_at`
+
+func (r *ReportScheduleRepository) Create(ctx context.Context, schedule *models.ReportSchedule) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO report_schedules (`+reportScheduleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reportScheduleArgs(schedule)...)
+	return err
+}
+
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+reportScheduleColumns+` FROM report_schedules WHERE id = ?`, id)
+	schedule, err := scanReportSchedule(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return schedule, nil
+}
+
+func (r *ReportScheduleRepository) List(ctx context.Context, filters repositories.ScheduleFilters) ([]*models.ReportSchedule, error) {
+	var b queryBuilder
+	if filters.UserID != "" {
+		b.where("user_id = ?", filters.UserID)
+	}
+	if !filters.IncludePaused {
+		b.where("NOT is_paused")
+	}
+	query, args := b.build(`SELECT `+reportScheduleColumns+` FROM report_schedules`, "created_at, id", filters.Limit, filters.Offset)
+	return r.query(ctx, query, args...)
+}
+
+func (r *ReportScheduleRepository) ListDue(ctx context.Context, before time.Time) ([]*models.ReportSchedule, error) {
+	return r.query(ctx, `SELECT `+reportScheduleColumns+` FROM report_schedules
+		WHERE NOT is_paused AND next_run_at <= ? ORDER BY next_run_at, id`, before)
+}
+
+func (r *ReportScheduleRepository) Update(ctx context.Context, schedule *models.ReportSchedule) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE report_schedules SET
+		user_id = ?, name = ?, source_location = ?, cron_expression = ?, timezone = ?,
+		should_check_entry_liveness = ?, max_stored_entries = ?, jitter_seconds = ?, catch_up = ?,
+		is_paused = ?, last_run_at = ?, next_run_at = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`, idLast(reportScheduleArgs(schedule))...)
+}
+
+func (r *ReportScheduleRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM report_schedules WHERE id = ?`, id)
+	return err
+}
+
+func (r *ReportScheduleRepository) query(ctx context.Context, query string, args ...any) ([]*models.ReportSchedule, error) {
+	rows, err := conn(r.db, r.tx, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*models.ReportSchedule
+	for rows.Next() {
+		schedule, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
+
+// reportScheduleArgs returns the column values of a schedule in
+// reportScheduleColumns order
+func reportScheduleArgs(s *models.ReportSchedule) []any {
+	return []any{
+		s.ID, s.UserID, s.Name, s.SourceLocation, s.CronExpression, s.Timezone,
+		s.ShouldCheckEntryLiveness, s.MaxStoredEntries, s.JitterSeconds, s.CatchUp, s.IsPaused,
+		s.LastRunAt, s.NextRunAt, s.CreatedAt, s.UpdatedAt,
+	}
+}
+
+func scanReportSchedule(row scanner) (*models.ReportSchedule, error) {
+	var s models.ReportSchedule
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.Name, &s.SourceLocation, &s.CronExpression, &s.Timezone,
+		&s.ShouldCheckEntryLiveness, &s.MaxStoredEntries, &s.JitterSeconds, &s.CatchUp, &s.IsPaused,
+		&s.LastRunAt, &s.NextRunAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,369 @@
+// Package sqlstore implements repositories.Database on database/sql for every
+// SQL backend. The postgres, sqlite and mysql packages open the connection and
+// describe their Dialect; queries and schema migrations are shared.
+//
+// Queries are written with ? placeholders and rebound to the dialect's style
+// when they run. UPDATE statements bind the row's id last, matching the
+// placeholder order of UPDATE ... SET ... WHERE id = ?.
+package sqlstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"jonopens/sitemapper/internal/database/migrate"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Dialect holds the SQL differences between backends. The embedded
+// migrate.Dialect's Placeholder also binds repository queries; nil keeps ?.
+type Dialect struct {
+	migrate.Dialect
+
+	// UTC converts time arguments to UTC, for backends that store timestamps
+	// as text, which only sorts and compares correctly in one zone
+	UTC bool
+
+	// Types are the column types the schema migrations are rendered with
+	Types ColumnTypes
+}
+
+// ColumnTypes fill in the column types of the migration templates
+type ColumnTypes struct {
+	ID        string // primary and foreign keys
+	String    string // short strings: names, emails, versions, cron expressions
+	Enum      string // statuses, types and other short values
+	Key       string // URLs in unique keys
+	Hash      string // hex SHA-256 hashes
+	Timestamp string
+	Float     string
+	JSON      string
+
+	// IndexedURL is the url column in an index, e.g. a prefix where only
+	// part of a TEXT column can be indexed
+	IndexedURL string
+
+	// TableOptions follow the closing parenthesis of CREATE TABLE
+	TableOptions string
+}
+
+// Database implements repositories.Database on a database/sql connection
+type Database struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+// New wraps an open connection
+func New(db *sql.DB, dialect Dialect) *Database {
+	return &Database{db: db, dialect: dialect}
+}
+
+// Entries returns the entry repository
+func (d *Database) Entries() repositories.EntryRepository {
+	return &EntryRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// Reports returns the report repository
+func (d *Database) Reports() repositories.ReportRepository {
+	return &ReportRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// Users returns the user repository
+func (d *Database) Users() repositories.UserRepository {
+	return &UserRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// Groupings returns the grouping repository
+func (d *Database) Groupings() repositories.GroupingRepository {
+	return &GroupingRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// ReportDiffs returns the report diff repository
+func (d *Database) ReportDiffs() repositories.ReportDiffRepository {
+	return &ReportDiffRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// GroupingRules returns the grouping rule repository
+func (d *Database) GroupingRules() repositories.GroupingRuleRepository {
+	return &GroupingRuleRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// GroupingOverrides returns the grouping override repository
+func (d *Database) GroupingOverrides() repositories.GroupingOverrideRepository {
+	return &GroupingOverrideRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// ReportGroupings returns the report grouping repository
+func (d *Database) ReportGroupings() repositories.ReportGroupingRepository {
+	return &ReportGroupingRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// ReportJobs returns the report job repository
+func (d *Database) ReportJobs() repositories.ReportJobRepository {
+	return &ReportJobRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// ReportSchedules returns the report schedule repository
+func (d *Database) ReportSchedules() repositories.ReportScheduleRepository {
+	return &ReportScheduleRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// Releases returns the release repository
+func (d *Database) Releases() repositories.ReleaseRepository {
+	return &ReleaseRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// APITokens returns the API token repository
+func (d *Database) APITokens() repositories.APITokenRepository {
+	return &APITokenRepository{db: d.db, tx: d.tx, dialect: d.dialect}
+}
+
+// BeginTx starts a new transaction
+func (d *Database) BeginTx(ctx context.Context) (repositories.Database, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Database{db: d.db, tx: tx, dialect: d.dialect}, nil
+}
+
+// Commit commits the transaction
+func (d *Database) Commit() error {
+	if d.tx == nil {
+		return fmt.Errorf("no transaction to commit")
+	}
+	return d.tx.Commit()
+}
+
+// Rollback rolls back the transaction
+func (d *Database) Rollback() error {
+	if d.tx == nil {
+		return fmt.Errorf("no transaction to rollback")
+	}
+	return d.tx.Rollback()
+}
+
+// Close closes the database connection
+func (d *Database) Close() error {
+	return d.db.Close()
+}
+
+// Migrator returns the schema migrator for the shared migrations, rendered
+// with the dialect's column types
+func (d *Database) Migrator() *migrate.Migrator {
+	migrations, err := loadMigrations(d.dialect.Types)
+	if err != nil {
+		// The migrations are embedded at build time
+		panic(err)
+	}
+	return migrate.New(d.db, migrations, d.dialect.Dialect)
+}
+
+// loadMigrations reads the embedded migrations and fills in their column types
+func loadMigrations(types ColumnTypes) ([]migrate.Migration, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	render := func(name, script string) (string, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(script)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, types); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	for i, m := range migrations {
+		name := fmt.Sprintf("%04d_%s", m.Version, m.Name)
+		if migrations[i].Up, err = render(name+".up", m.Up); err != nil {
+			return nil, err
+		}
+		if migrations[i].Down, err = render(name+".down", m.Down); err != nil {
+			return nil, err
+		}
+	}
+	return migrations, nil
+}
+
+// Common error, shared by every backend so callers can match it
+var ErrNotFound = repositories.ErrNotFound
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// querier runs statements with their placeholders and arguments adapted to
+// the dialect
+type querier struct {
+	q       execer
+	dialect Dialect
+}
+
+func (q querier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return q.q.ExecContext(ctx, q.dialect.rebind(query), q.dialect.args(args)...)
+}
+
+func (q querier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return q.q.QueryContext(ctx, q.dialect.rebind(query), q.dialect.args(args)...)
+}
+
+func (q querier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return q.q.QueryRowContext(ctx, q.dialect.rebind(query), q.dialect.args(args)...)
+}
+
+// rebind replaces the ? placeholders of query with the dialect's
+func (d Dialect) rebind(query string) string {
+	if d.Placeholder == nil {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for {
+		before, after, found := strings.Cut(query, "?")
+		sb.WriteString(before)
+		if !found {
+			return sb.String()
+		}
+		n++
+		sb.WriteString(d.Placeholder(n))
+		query = after
+	}
+}
+
+// args converts time arguments to UTC if the dialect needs it
+func (d Dialect) args(args []any) []any {
+	if !d.UTC {
+		return args
+	}
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[i] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				args[i] = t.UTC()
+			}
+		}
+	}
+	return args
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// conn returns the transaction if one is open, otherwise the database
+func conn(db *sql.DB, tx *sql.Tx, dialect Dialect) querier {
+	if tx != nil {
+		return querier{q: tx, dialect: dialect}
+	}
+	return querier{q: db, dialect: dialect}
+}
+
+// notFound maps sql.ErrNoRows to ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// execOne runs an UPDATE of a single row, returning ErrNotFound if no row
+// matched. Backends must count matched rather than changed rows (the mysql
+// package sets ClientFoundRows), so unchanged rows still count.
+func execOne(ctx context.Context, q querier, query string, args ...any) error {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// idLast moves the leading id argument to the end, matching the placeholder
+// order of UPDATE ... SET ... WHERE id = ?
+func idLast(args []any) []any {
+	return append(args[1:len(args):len(args)], args[0])
+}
+
+// queryBuilder collects WHERE conditions and their arguments
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// where adds a condition with one argument per ? in it
+func (b *queryBuilder) where(condition string, args ...any) {
+	b.args = append(b.args, args...)
+	b.conditions = append(b.conditions, condition)
+}
+
+// build appends the WHERE clause, orderBy and pagination to query
+func (b *queryBuilder) build(query, orderBy string, limit, offset int) (string, []any) {
+	if len(b.conditions) > 0 {
+		query += " WHERE " + strings.Join(b.conditions, " AND ")
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	if limit > 0 || offset > 0 {
+		// SQLite and MySQL only accept OFFSET after LIMIT, and have no common
+		// way to spell "no limit"
+		if limit <= 0 {
+			limit = math.MaxInt
+		}
+		b.args = append(b.args, limit)
+		query += " LIMIT ?"
+	}
+	if offset > 0 {
+		b.args = append(b.args, offset)
+		query += " OFFSET ?"
+	}
+	return query, b.args
+}
+
+// jsonValue encodes v for a JSON column, storing empty values as NULL
+func jsonValue(v any, empty bool) (any, error) {
+	if empty {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// scanJSON decodes a JSON column into v, leaving v unchanged for NULL
+func scanJSON(data []byte, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
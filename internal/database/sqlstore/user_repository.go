@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+type UserRepository struct {
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect Dialect
+}
+
+const userColumns = `id, email, name, phone, slack_id, created_at, updated_at`
+
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.Email, user.Name, user.Phone, user.SlackID, user.CreatedAt, user.UpdatedAt)
+	return err
+}
+
+func (r *UserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+	return scanUser(row)
+}
+
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	row := conn(r.db, r.tx, r.dialect).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email)
+	return scanUser(row)
+}
+
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	return execOne(ctx, conn(r.db, r.tx, r.dialect), `UPDATE users SET
+		email = ?, name = ?, phone = ?, slack_id = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		user.Email, user.Name, user.Phone, user.SlackID, user.CreatedAt, user.UpdatedAt, user.ID)
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	_, err := conn(r.db, r.tx, r.dialect).ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	return err
+}
+
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.Name, &user.Phone, &user.SlackID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &user, nil
+}
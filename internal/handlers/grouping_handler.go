@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"jonopens/sitemapper/internal/services"
+)
+
+// listGroupings lists the user's groupings by name
+func (s *Server) listGroupings(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	groupings, err := services.NewGroupingService(s.scoped(r)).ListGroupings(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, paginate(groupings, p))
+}
+
+func (s *Server) getGrouping(w http.ResponseWriter, r *http.Request) {
+	grouping, err := services.NewGroupingService(s.scoped(r)).GetGrouping(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, grouping)
+}
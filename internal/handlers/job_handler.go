@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+// jobRequest is the body of POST /api/v1/jobs; unset options take the
+// server defaults
+type jobRequest struct {
+	URL              string `json:"url"`
+	CheckLiveness    *bool  `json:"check_liveness"`
+	Validate         *bool  `json:"validate"`
+	MaxStoredEntries *int   `json:"max_stored_entries"`
+	TimeoutSeconds   int    `json:"timeout_seconds"`
+}
+
+// createJob queues a job that fetches a sitemap URL and stores it as a new
+// report. The job runs in the background; poll GET /api/v1/jobs/{id} for its
+// status and report ID.
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	var req jobRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateSourceURL(req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.TimeoutSeconds < 0 {
+		writeError(w, http.StatusBadRequest, "timeout_seconds must not be negative")
+		return
+	}
+
+	job := &models.ReportJob{
+		UserID:                     s.userID(r),
+		SourceLocation:             req.URL,
+		JobType:                    models.JobTypeURL,
+		ShouldCheckEntryLiveness:   s.opts.CheckLiveness,
+		ShouldCheckForValidEntries: true,
+		MaxStoredEntries:           s.opts.MaxStoredEntries,
+		TimeoutSeconds:             req.TimeoutSeconds,
+	}
+	if req.CheckLiveness != nil {
+		job.ShouldCheckEntryLiveness = *req.CheckLiveness
+	}
+	if req.Validate != nil {
+		job.ShouldCheckForValidEntries = *req.Validate
+	}
+	if req.MaxStoredEntries != nil {
+		if *req.MaxStoredEntries < 0 {
+			writeError(w, http.StatusBadRequest, "max_stored_entries must not be negative")
+			return
+		}
+		job.MaxStoredEntries = *req.MaxStoredEntries
+	}
+
+	if err := s.jobs.QueueJob(r.Context(), job); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// listJobs lists the user's jobs, newest first, optionally only those with
+// one status
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	jobs, err := s.scoped(r).ReportJobs().List(r.Context(), repositories.JobFilters{
+		Status: r.URL.Query().Get("status"),
+		UserID: s.userID(r),
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, jobs)
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.scoped(r).ReportJobs().GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// cancelJob and retryJob go through the shared job service, so a running job
+// stops right away, once the job is known to be the user's
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.scoped(r).ReportJobs().GetByID(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	job, err := s.jobs.CancelJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) retryJob(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.scoped(r).ReportJobs().GetByID(r.Context(), r.PathValue("id")); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	job, err := s.jobs.RetryJob(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// validateSourceURL checks that a sitemap source sent by a client is an
+// absolute http or https URL; the server never reads local files for clients
+func validateSourceURL(source string) error {
+	if source == "" {
+		return fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tokenKey
+)
+
+// RequireToken authenticates requests with a bearer API token that grants
+// scope, and passes the token's user to next in the request context.
+// Requests without a valid token get 401; tokens without the scope get 403.
+func RequireToken(auth *services.AuthService, scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plaintext, ok := bearerToken(r)
+		if !ok {
+			unauthorized(w, "missing bearer token")
+			return
+		}
+
+		user, token, err := auth.Authenticate(r.Context(), plaintext)
+		if errors.Is(err, services.ErrInvalidToken) {
+			unauthorized(w, err.Error())
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, "failed to authenticate: "+err.Error())
+			return
+		}
+		if !services.HasScope(token, scope) {
+			WriteError(w, http.StatusForbidden, services.ErrMissingScope.Error()+": "+scope)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey, user)
+		ctx = context.WithValue(ctx, tokenKey, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UserFromContext returns the authenticated user, or nil outside RequireToken
+func UserFromContext(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey).(*models.User)
+	return user
+}
+
+// TokenFromContext returns the token a request authenticated with, or nil
+// outside RequireToken
+func TokenFromContext(ctx context.Context) *models.APIToken {
+	token, _ := ctx.Value(tokenKey).(*models.APIToken)
+	return token
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="sitemapper"`)
+	WriteError(w, http.StatusUnauthorized, message)
+}
+
+// WriteJSON writes v as an indented JSON response body with the given status
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// WriteError writes a JSON body of the form {"error": message}
+func WriteError(w http.ResponseWriter, status int, message string) {
+	WriteJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"jonopens/sitemapper/internal/repositories"
+)
+
+// listReleases lists the user's releases, newest first
+func (s *Server) listReleases(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	releases, err := s.scoped(r).Releases().List(r.Context(), repositories.ReleaseFilters{
+		UserID: s.userID(r),
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, releases)
+}
+
+func (s *Server) getRelease(w http.ResponseWriter, r *http.Request) {
+	release, err := s.scoped(r).Releases().GetByID(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, release)
+}
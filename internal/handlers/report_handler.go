@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+)
+
+// listReports lists the user's reports, newest first
+func (s *Server) listReports(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reports, err := s.scoped(r).Reports().List(r.Context(), repositories.ReportFilters{
+		UserID: s.userID(r),
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, reports)
+}
+
+func (s *Server) getReport(w http.ResponseWriter, r *http.Request) {
+	report, err := services.NewReportService(s.scoped(r)).GetReport(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// listReportEntries lists the stored entries of a report by URL, optionally
+// only those of one type (url or sitemap)
+func (s *Server) listReportEntries(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filters := repositories.EntryFilters{
+		ReportID: r.PathValue("id"),
+		Limit:    p.Limit,
+		Offset:   p.Offset,
+	}
+	if v := r.URL.Query().Get("type"); v != "" {
+		entryType := models.EntryType(v)
+		if entryType != models.EntryTypeURL && entryType != models.EntryTypeSitemap {
+			writeError(w, http.StatusBadRequest, "type must be url or sitemap")
+			return
+		}
+		filters.Type = &entryType
+	}
+
+	// An unknown report is a 404, not an empty list
+	if _, err := s.scoped(r).Reports().GetByID(r.Context(), filters.ReportID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	entries, err := s.scoped(r).Entries().List(r.Context(), filters)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, entries)
+}
+
+// listReportGroupings lists the per-group totals of a report
+func (s *Server) listReportGroupings(w http.ResponseWriter, r *http.Request) {
+	p, err := parsePagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reportID := r.PathValue("id")
+	if _, err := s.scoped(r).Reports().GetByID(r.Context(), reportID); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	groupings, err := s.scoped(r).ReportGroupings().ListByReportID(r.Context(), reportID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writePage(w, p, paginate(groupings, p))
+}
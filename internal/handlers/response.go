@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"jonopens/sitemapper/internal/handlers/middleware"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+// page is the response body of every list endpoint. NextOffset is set when
+// the page is full, so there may be more items.
+type page struct {
+	Data       any  `json:"data"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	Count      int  `json:"count"`
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+// pagination holds the limit and offset query parameters of a list request
+type pagination struct {
+	Limit  int
+	Offset int
+}
+
+// parsePagination reads limit and offset, defaulting to the first
+// defaultPageLimit items
+func parsePagination(r *http.Request) (pagination, error) {
+	p := pagination{Limit: defaultPageLimit}
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		p.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return p, fmt.Errorf("offset must be a non-negative integer")
+		}
+		p.Offset = offset
+	}
+	return p, nil
+}
+
+// writePage writes one page of a list
+func writePage[T any](w http.ResponseWriter, p pagination, items []T) {
+	if items == nil {
+		items = []T{}
+	}
+	body := page{Data: items, Limit: p.Limit, Offset: p.Offset, Count: len(items)}
+	if len(items) == p.Limit {
+		next := p.Offset + p.Limit
+		body.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// paginate applies a pagination to a list that was loaded in full
+func paginate[T any](items []T, p pagination) []T {
+	if p.Offset >= len(items) {
+		return nil
+	}
+	items = items[p.Offset:]
+	if len(items) > p.Limit {
+		items = items[:p.Limit]
+	}
+	return items
+}
+
+// decodeJSON decodes a JSON request body into v, rejecting unknown fields
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// Handlers write responses the same way the auth middleware does
+var (
+	writeJSON  = middleware.WriteJSON
+	writeError = middleware.WriteError
+)
+
+// writeServiceError writes an error returned by a service or repository,
+// choosing the status code from the errors it wraps
+func writeServiceError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, repositories.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrJobNotClaimable),
+		errors.Is(err, services.ErrJobNotCancellable),
+		errors.Is(err, services.ErrJobNotRetryable):
+		status = http.StatusConflict
+	}
+	writeError(w, status, err.Error())
+}
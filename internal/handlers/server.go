@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"jonopens/sitemapper/internal/handlers/middleware"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/internal/services"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+// Options configures the API server
+type Options struct {
+	MaxUploadSize    int64               // cap on decompressed bytes per sitemap
+	MaxStoredEntries int                 // default sampling threshold (0 = store all)
+	CheckLiveness    bool                // check liveness on every snapshot by default
+	LivenessRate     float64             // liveness requests per second per host
+	WorkerCount      int                 // concurrent liveness requests
+	GroupDepth       int                 // path segments used for automatic grouping
+	UploadDir        string              // where uploaded sitemaps wait for their jobs
+	Normalizer       *sitemap.Normalizer // records the canonical form of every URL when set
+}
+
+// Server serves the REST API. It uses the same services and database as the
+// CLI; every response body is JSON. Every endpoint but health needs an API
+// token, and only sees the records of the token's user.
+type Server struct {
+	db      repositories.Database
+	auth    *services.AuthService
+	jobs    *services.JobService
+	uploads *services.UploadService
+	opts    Options
+	mux     *http.ServeMux
+}
+
+// NewServer creates an API server. jobs is shared with the job runner, so
+// cancelling a job over the API stops it right away.
+func NewServer(db repositories.Database, jobs *services.JobService, opts Options) *Server {
+	s := &Server{
+		db:      db,
+		auth:    services.NewAuthService(db),
+		jobs:    jobs,
+		uploads: services.NewUploadService(opts.UploadDir, opts.MaxUploadSize),
+		opts:    opts,
+		mux:     http.NewServeMux(),
+	}
+	s.routes()
+	return s
+}
+
+// routes registers every endpoint
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /api/v1/health", s.health)
+
+	s.mux.Handle("POST /api/v1/sitemaps", s.write(s.createSnapshot))
+
+	s.mux.Handle("GET /api/v1/reports", s.read(s.listReports))
+	s.mux.Handle("GET /api/v1/reports/{id}", s.read(s.getReport))
+	s.mux.Handle("GET /api/v1/reports/{id}/entries", s.read(s.listReportEntries))
+	s.mux.Handle("GET /api/v1/reports/{id}/groupings", s.read(s.listReportGroupings))
+
+	s.mux.Handle("GET /api/v1/groupings", s.read(s.listGroupings))
+	s.mux.Handle("GET /api/v1/groupings/{id}", s.read(s.getGrouping))
+
+	s.mux.Handle("GET /api/v1/releases", s.read(s.listReleases))
+	s.mux.Handle("GET /api/v1/releases/{id}", s.read(s.getRelease))
+
+	s.mux.Handle("POST /api/v1/jobs", s.write(s.createJob))
+	s.mux.Handle("GET /api/v1/jobs", s.read(s.listJobs))
+	s.mux.Handle("GET /api/v1/jobs/{id}", s.read(s.getJob))
+	s.mux.Handle("POST /api/v1/jobs/{id}/cancel", s.write(s.cancelJob))
+	s.mux.Handle("POST /api/v1/jobs/{id}/retry", s.write(s.retryJob))
+
+	s.mux.Handle("POST /api/v1/uploads", s.write(s.createUpload))
+
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	})
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// read requires a token with the read scope
+func (s *Server) read(h http.HandlerFunc) http.Handler {
+	return middleware.RequireToken(s.auth, models.TokenScopeRead, h)
+}
+
+// write requires a token with the write scope
+func (s *Server) write(h http.HandlerFunc) http.Handler {
+	return middleware.RequireToken(s.auth, models.TokenScopeWrite, h)
+}
+
+// userID returns the ID of the user a request authenticated as
+func (s *Server) userID(r *http.Request) string {
+	return middleware.UserFromContext(r.Context()).ID
+}
+
+// scoped returns the database as seen by the user a request authenticated
+// as; handlers read and write through it so users only see their own records
+func (s *Server) scoped(r *http.Request) repositories.Database {
+	return repositories.ForUser(s.db, s.userID(r))
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/database/memory"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+const testSitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/</loc></url>
+  <url><loc>https://example.com/blog/a</loc></url>
+  <url><loc>https://example.com/blog/b</loc></url>
+</urlset>`
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	db := memory.New()
+	uploadDir := t.TempDir()
+	jobs := services.NewJobService(db, services.JobOptions{RemoteOnly: true, MaxUploadSize: 1 << 20, UploadDir: uploadDir})
+	return NewServer(db, jobs, Options{GroupDepth: 1, MaxUploadSize: 1 << 20, UploadDir: uploadDir})
+}
+
+// testClient sends requests to a test server as one user
+type testClient struct {
+	t     *testing.T
+	s     *Server
+	token string
+}
+
+// newClient creates a user and a client with a token for it
+func newClient(t *testing.T, s *Server, userID string, scopes ...string) *testClient {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.auth.CreateUser(ctx, &models.User{ID: userID, Email: userID + "@example.com"}); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	_, token, err := s.auth.IssueToken(ctx, userID, "test", scopes, time.Hour)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	return &testClient{t: t, s: s, token: token}
+}
+
+// do sends a request to the server and decodes the JSON response into v
+func (c *testClient) do(method, target, contentType, body string, v any) int {
+	t := c.t
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	rec := httptest.NewRecorder()
+	c.s.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("%s %s: Content-Type = %q", method, target, got)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: decoding %s: %v", method, target, rec.Body, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestSnapshotAndReports(t *testing.T) {
+	c := newClient(t, newTestServer(t), "default")
+
+	var created snapshotResponse
+	if code := c.do("POST", "/api/v1/sitemaps", "application/xml", testSitemap, &created); code != http.StatusCreated {
+		t.Fatalf("upload: status %d", code)
+	}
+	if created.Report.EntryCount != 3 || created.Report.UserID != "default" {
+		t.Errorf("upload: got report %+v", created.Report)
+	}
+	if len(created.Groupings) == 0 || created.Groupings[0].Name == "" {
+		t.Errorf("upload: got groupings %+v", created.Groupings)
+	}
+	reportID := created.Report.ID
+
+	var reports struct {
+		Data  []*models.Report `json:"data"`
+		Count int              `json:"count"`
+	}
+	if code := c.do("GET", "/api/v1/reports", "", "", &reports); code != http.StatusOK {
+		t.Fatalf("list reports: status %d", code)
+	}
+	if reports.Count != 1 || reports.Data[0].ID != reportID {
+		t.Errorf("list reports: got %+v", reports)
+	}
+
+	var entries struct {
+		Data       []*models.Entry `json:"data"`
+		NextOffset *int            `json:"next_offset"`
+	}
+	if code := c.do("GET", "/api/v1/reports/"+reportID+"/entries?limit=2", "", "", &entries); code != http.StatusOK {
+		t.Fatalf("list entries: status %d", code)
+	}
+	if len(entries.Data) != 2 || entries.NextOffset == nil || *entries.NextOffset != 2 {
+		t.Errorf("list entries: got %d entries, next offset %v", len(entries.Data), entries.NextOffset)
+	}
+	entries.NextOffset = nil
+	if code := c.do("GET", "/api/v1/reports/"+reportID+"/entries?offset=2", "", "", &entries); code != http.StatusOK {
+		t.Fatalf("list entries: status %d", code)
+	}
+	if len(entries.Data) != 1 || entries.NextOffset != nil {
+		t.Errorf("list last entries: got %d entries, next offset %v", len(entries.Data), entries.NextOffset)
+	}
+
+	for _, target := range []string{"/api/v1/reports/missing", "/api/v1/reports/missing/entries", "/api/v1/jobs/missing", "/api/v1/nothing"} {
+		if code := c.do("GET", target, "", "", nil); code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", target, code)
+		}
+	}
+	if code := c.do("GET", "/api/v1/reports?limit=0", "", "", nil); code != http.StatusBadRequest {
+		t.Errorf("limit=0: status %d, want 400", code)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	c := newClient(t, newTestServer(t), "default")
+
+	// Clients can't make the server read local files
+	for _, body := range []string{`{"url": "/etc/passwd"}`, `{"url": "file:///etc/passwd"}`, `{}`, `{"url": "https://example.com/", "bogus": 1}`} {
+		if code := c.do("POST", "/api/v1/jobs", "application/json", body, nil); code != http.StatusBadRequest {
+			t.Errorf("create job %s: status %d, want 400", body, code)
+		}
+	}
+	if code := c.do("POST", "/api/v1/sitemaps", "application/json", `{"url": "/etc/passwd"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("fetch a local file: status %d, want 400", code)
+	}
+
+	var job models.ReportJob
+	if code := c.do("POST", "/api/v1/jobs", "application/json", `{"url": "https://example.com/sitemap.xml", "max_stored_entries": 10}`, &job); code != http.StatusAccepted {
+		t.Fatalf("create job: status %d", code)
+	}
+	if job.ID == "" || job.Status != models.ReportJobStatusPending || job.MaxStoredEntries != 10 {
+		t.Errorf("create job: got %+v", job)
+	}
+
+	var polled models.ReportJob
+	if code := c.do("GET", "/api/v1/jobs/"+job.ID, "", "", &polled); code != http.StatusOK || polled.ID != job.ID {
+		t.Errorf("poll job: status %d, got %+v", code, polled)
+	}
+	if code := c.do("POST", "/api/v1/jobs/"+job.ID+"/retry", "", "", nil); code != http.StatusConflict {
+		t.Errorf("retry a pending job: status %d, want 409", code)
+	}
+	if code := c.do("POST", "/api/v1/jobs/"+job.ID+"/cancel", "", "", &polled); code != http.StatusOK || polled.Status != models.ReportJobStatusCancelled {
+		t.Errorf("cancel job: status %d, got %s", code, polled.Status)
+	}
+	if code := c.do("POST", "/api/v1/jobs/"+job.ID+"/cancel", "", "", nil); code != http.StatusConflict {
+		t.Errorf("cancel a cancelled job: status %d, want 409", code)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	s := newTestServer(t)
+	owner := newClient(t, s, "owner")
+	other := newClient(t, s, "other")
+	reader := newClient(t, s, "reader", models.TokenScopeRead)
+
+	var created snapshotResponse
+	if code := owner.do("POST", "/api/v1/sitemaps", "application/xml", testSitemap, &created); code != http.StatusCreated {
+		t.Fatalf("upload: status %d", code)
+	}
+	reportID := created.Report.ID
+	var job models.ReportJob
+	if code := owner.do("POST", "/api/v1/jobs", "application/json", `{"url": "https://example.com/sitemap.xml"}`, &job); code != http.StatusAccepted {
+		t.Fatalf("create job: status %d", code)
+	}
+
+	anonymous := &testClient{t: t, s: s}
+	if code := anonymous.do("GET", "/api/v1/health", "", "", nil); code != http.StatusOK {
+		t.Errorf("health without a token: status %d, want 200", code)
+	}
+	forged := &testClient{t: t, s: s, token: services.TokenPrefix + "forged"}
+	for _, c := range []*testClient{anonymous, forged} {
+		if code := c.do("GET", "/api/v1/reports", "", "", nil); code != http.StatusUnauthorized {
+			t.Errorf("list reports with token %q: status %d, want 401", c.token, code)
+		}
+	}
+	if code := reader.do("POST", "/api/v1/jobs", "application/json", `{"url": "https://example.com/sitemap.xml"}`, nil); code != http.StatusForbidden {
+		t.Errorf("create job with a read-only token: status %d, want 403", code)
+	}
+
+	// Other users' records look like they don't exist
+	var reports struct {
+		Count int `json:"count"`
+	}
+	if code := other.do("GET", "/api/v1/reports", "", "", &reports); code != http.StatusOK || reports.Count != 0 {
+		t.Errorf("list reports as another user: status %d, count %d", code, reports.Count)
+	}
+	for _, target := range []string{"/api/v1/reports/" + reportID, "/api/v1/reports/" + reportID + "/entries", "/api/v1/jobs/" + job.ID} {
+		if code := other.do("GET", target, "", "", nil); code != http.StatusNotFound {
+			t.Errorf("GET %s as another user: status %d, want 404", target, code)
+		}
+	}
+	if code := other.do("POST", "/api/v1/jobs/"+job.ID+"/cancel", "", "", nil); code != http.StatusNotFound {
+		t.Errorf("cancel another user's job: status %d, want 404", code)
+	}
+	if code := owner.do("GET", "/api/v1/reports/"+reportID, "", "", nil); code != http.StatusOK {
+		t.Errorf("get own report: status %d, want 200", code)
+	}
+
+	// Revoked tokens stop working
+	_, token, err := s.auth.Authenticate(context.Background(), owner.token)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+	if _, err := s.auth.RevokeToken(context.Background(), token.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if code := owner.do("GET", "/api/v1/reports", "", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("list reports with a revoked token: status %d, want 401", code)
+	}
+}
+
+func TestUploads(t *testing.T) {
+	s := newTestServer(t)
+	c := newClient(t, s, "default")
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write([]byte(testSitemap))
+	zw.Close()
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	mw.WriteField("note", "skipped")
+	fw, _ := mw.CreateFormFile("file", "sitemap.xml")
+	fw.Write([]byte(testSitemap))
+	mw.Close()
+
+	uploads := []struct {
+		name, contentType, body string
+	}{
+		{"raw gzip", "application/gzip", gzipped.String()},
+		{"multipart", mw.FormDataContentType(), multipartBody.String()},
+	}
+	for _, upload := range uploads {
+		var job models.ReportJob
+		if code := c.do("POST", "/api/v1/uploads?max_stored_entries=10", upload.contentType, upload.body, &job); code != http.StatusAccepted {
+			t.Fatalf("upload %s: status %d", upload.name, code)
+		}
+		if job.JobType != models.JobTypeUpload || job.Status != models.ReportJobStatusPending || job.MaxStoredEntries != 10 {
+			t.Errorf("upload %s: got %+v", upload.name, job)
+		}
+	}
+
+	ran, err := s.jobs.RunPending(context.Background())
+	if err != nil || len(ran) != 2 {
+		t.Fatalf("RunPending() = %d jobs, %v", len(ran), err)
+	}
+	for _, job := range ran {
+		if job.Status != models.ReportJobStatusCompleted || job.ReportID == nil {
+			t.Fatalf("job %s: status %s, error %v", job.ID, job.Status, job.ErrorMessage)
+		}
+		var report models.Report
+		if code := c.do("GET", "/api/v1/reports/"+*job.ReportID, "", "", &report); code != http.StatusOK || report.EntryCount != 3 {
+			t.Errorf("report of job %s: status %d, %d entries", job.ID, code, report.EntryCount)
+		}
+		if _, err := os.Stat(job.SourceLocation); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("upload of completed job %s was not removed: %v", job.ID, err)
+		}
+	}
+
+	// Child sitemaps of an uploaded index are still never read from disk; the
+	// refused child is recorded as an invalid entry
+	index := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>/etc/passwd</loc></sitemap>
+</sitemapindex>`
+	if code := c.do("POST", "/api/v1/uploads", "application/xml", index, nil); code != http.StatusAccepted {
+		t.Fatalf("upload index: status %d", code)
+	}
+	ran, err = s.jobs.RunPending(context.Background())
+	if err != nil || len(ran) != 1 {
+		t.Fatalf("RunPending() = %d jobs, %v", len(ran), err)
+	}
+	var report models.Report
+	if ran[0].ReportID == nil {
+		t.Fatalf("index with a local child: job %s, error %v", ran[0].Status, ran[0].ErrorMessage)
+	}
+	if code := c.do("GET", "/api/v1/reports/"+*ran[0].ReportID, "", "", &report); code != http.StatusOK || report.InvalidEntryCount != 1 || report.ValidEntryCount != 0 {
+		t.Errorf("index with a local child: status %d, report %+v", code, report)
+	}
+
+	if code := c.do("POST", "/api/v1/uploads", "application/xml", strings.Repeat("x", 1<<20+1), nil); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized upload: status %d, want 413", code)
+	}
+	for _, body := range []string{"", "--x\r\nContent-Disposition: form-data; name=\"note\"\r\n\r\nhi\r\n--x--\r\n"} {
+		contentType := "application/xml"
+		if body != "" {
+			contentType = "multipart/form-data; boundary=x"
+		}
+		if code := c.do("POST", "/api/v1/uploads", contentType, body, nil); code != http.StatusBadRequest {
+			t.Errorf("upload %q: status %d, want 400", body, code)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+// snapshotRequest is the JSON body of POST /api/v1/sitemaps when the server
+// should fetch the sitemap itself; unset options take the server defaults
+type snapshotRequest struct {
+	URL              string `json:"url"`
+	CheckLiveness    *bool  `json:"check_liveness"`
+	Validate         *bool  `json:"validate"`
+	MaxStoredEntries *int   `json:"max_stored_entries"`
+}
+
+// snapshotResponse is the report stored for a sitemap, with its group totals
+type snapshotResponse struct {
+	Report    *models.Report     `json:"report"`
+	Groupings []snapshotGrouping `json:"groupings"`
+}
+
+type snapshotGrouping struct {
+	Name string `json:"name"`
+	*models.ReportGrouping
+}
+
+// createSnapshot stores a sitemap as a new report and returns it. A JSON
+// body names a URL for the server to fetch; any other body is the sitemap
+// itself (XML, gzip or zip), with options in the query string. Sitemap
+// indexes are resolved and every child sitemap is stored, as with `track`.
+func (s *Server) createSnapshot(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		s.fetchSnapshot(w, r)
+		return
+	}
+	s.uploadSnapshot(w, r)
+}
+
+// fetchSnapshot fetches the sitemap URL named in the request body
+func (s *Server) fetchSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req snapshotRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateSourceURL(req.URL); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := s.snapshotOptions()
+	if req.CheckLiveness != nil {
+		opts.CheckLiveness = *req.CheckLiveness
+	}
+	if req.Validate != nil {
+		opts.SkipValidation = !*req.Validate
+	}
+	if req.MaxStoredEntries != nil {
+		if *req.MaxStoredEntries < 0 {
+			writeError(w, http.StatusBadRequest, "max_stored_entries must not be negative")
+			return
+		}
+		opts.MaxStored = *req.MaxStoredEntries
+	}
+
+	sourceService := services.NewSourceService(s.opts.MaxUploadSize).RemoteOnly()
+	_, sources, closeSources, err := sourceService.Open(r.Context(), req.URL)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Sprintf("failed to read sitemap: %v", err))
+		return
+	}
+	defer closeSources()
+
+	s.saveSnapshot(w, r, sourceService, sources, req.URL, opts)
+}
+
+// uploadSnapshot reads the sitemap from the request body
+func (s *Server) uploadSnapshot(w http.ResponseWriter, r *http.Request) {
+	opts := s.snapshotOptions()
+	if err := parseSnapshotQuery(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "upload"
+	}
+
+	body := r.Body
+	if s.opts.MaxUploadSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadSize)
+	}
+	decompressor := services.NewDecompressionService(s.opts.MaxUploadSize)
+	_, sources, err := decompressor.Open(body, r.Header.Get("Content-Encoding"), name)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("failed to read sitemap: %v", err))
+		return
+	}
+	defer func() {
+		for _, source := range sources {
+			source.Close()
+		}
+	}()
+
+	// Child sitemaps of an uploaded index are fetched, but only over HTTP
+	sourceService := services.NewSourceService(s.opts.MaxUploadSize).RemoteOnly()
+	s.saveSnapshot(w, r, sourceService, sources, name, opts)
+}
+
+// saveSnapshot stores opened sitemap sources and writes the new report
+func (s *Server) saveSnapshot(w http.ResponseWriter, r *http.Request, sourceService *services.SourceService, sources []*services.DecompressedSource, source string, opts services.SnapshotOptions) {
+	snapshotService := services.NewSnapshotService(s.scoped(r), sourceService.Fetcher())
+	snapshot, err := snapshotService.Save(r.Context(), sources, source, s.userID(r), opts)
+	if err != nil {
+		writeServiceError(w, fmt.Errorf("failed to save snapshot: %w", err))
+		return
+	}
+
+	response := snapshotResponse{Report: snapshot.Report, Groupings: []snapshotGrouping{}}
+	for _, rg := range snapshot.Groupings {
+		response.Groupings = append(response.Groupings, snapshotGrouping{
+			Name:           snapshot.GroupNames[rg.GroupingID],
+			ReportGrouping: rg,
+		})
+	}
+	w.Header().Set("Location", "/api/v1/reports/"+snapshot.Report.ID)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// parseSnapshotQuery applies the check_liveness, validate and
+// max_stored_entries query parameters of an upload to opts
+func parseSnapshotQuery(r *http.Request, opts *services.SnapshotOptions) error {
+	query := r.URL.Query()
+	if v := query.Get("check_liveness"); v != "" {
+		checkLiveness, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("check_liveness must be true or false")
+		}
+		opts.CheckLiveness = checkLiveness
+	}
+	if v := query.Get("validate"); v != "" {
+		validate, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("validate must be true or false")
+		}
+		opts.SkipValidation = !validate
+	}
+	if v := query.Get("max_stored_entries"); v != "" {
+		maxStored, err := strconv.Atoi(v)
+		if err != nil || maxStored < 0 {
+			return fmt.Errorf("max_stored_entries must be a non-negative integer")
+		}
+		opts.MaxStored = maxStored
+	}
+	return nil
+}
+
+// snapshotOptions returns the snapshot options for the server defaults
+func (s *Server) snapshotOptions() services.SnapshotOptions {
+	return services.SnapshotOptions{
+		CheckLiveness: s.opts.CheckLiveness,
+		LivenessRate:  s.opts.LivenessRate,
+		WorkerCount:   s.opts.WorkerCount,
+		MaxStored:     s.opts.MaxStoredEntries,
+		GroupDepth:    s.opts.GroupDepth,
+		Normalizer:    s.opts.Normalizer,
+	}
+}
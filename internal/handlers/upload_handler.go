@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/services"
+)
+
+// createUpload stores an uploaded sitemap and queues a job that processes
+// it. The body is multipart/form-data with the sitemap in a "file" part, or
+// the sitemap itself (XML, gzip or zip). Options go in the query string, as
+// for uploads to POST /api/v1/sitemaps, plus timeout_seconds. The job runs in
+// the background; poll GET /api/v1/jobs/{id} for its status and report ID.
+func (s *Server) createUpload(w http.ResponseWriter, r *http.Request) {
+	opts := s.snapshotOptions()
+	if err := parseSnapshotQuery(r, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := r.URL.Query()
+	timeoutSeconds := 0
+	if v := query.Get("timeout_seconds"); v != "" {
+		var err error
+		timeoutSeconds, err = strconv.Atoi(v)
+		if err != nil || timeoutSeconds < 0 {
+			writeError(w, http.StatusBadRequest, "timeout_seconds must be a non-negative integer")
+			return
+		}
+	}
+
+	body := r.Body
+	if s.opts.MaxUploadSize > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.opts.MaxUploadSize)
+	}
+	var blob io.Reader = body
+	name := query.Get("name")
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		r.Body = body
+		part, err := filePart(r)
+		if err != nil {
+			s.writeUploadError(w, err)
+			return
+		}
+		defer part.Close()
+		blob = part
+		if name == "" {
+			name = part.FileName()
+		}
+	}
+	if name == "" {
+		name = "upload.xml"
+	}
+
+	path, err := s.uploads.Store(blob, name)
+	if err != nil {
+		s.writeUploadError(w, fmt.Errorf("failed to store upload: %w", err))
+		return
+	}
+
+	job := &models.ReportJob{
+		UserID:                     s.userID(r),
+		SourceLocation:             path,
+		JobType:                    models.JobTypeUpload,
+		ShouldCheckEntryLiveness:   opts.CheckLiveness,
+		ShouldCheckForValidEntries: !opts.SkipValidation,
+		MaxStoredEntries:           opts.MaxStored,
+		TimeoutSeconds:             timeoutSeconds,
+	}
+	if err := s.jobs.QueueJob(r.Context(), job); err != nil {
+		s.uploads.Remove(path)
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// errBadUpload is returned for multipart bodies without a sitemap
+var errBadUpload = errors.New("invalid multipart body")
+
+// filePart returns the "file" part of a multipart upload, skipping any
+// other parts before it
+func filePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadUpload, err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: no \"file\" part", errBadUpload)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errBadUpload, err)
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// writeUploadError writes an error from reading or storing an upload
+func (s *Server) writeUploadError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr), errors.Is(err, services.ErrUploadTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload is larger than the %d byte limit", s.opts.MaxUploadSize))
+	case errors.Is(err, errBadUpload), errors.Is(err, services.ErrUploadEmpty):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeServiceError(w, err)
+	}
+}
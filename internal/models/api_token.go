@@ -0,0 +1,26 @@
+package models // domain models
+
+import "time"
+
+// API token scopes
+const (
+	TokenScopeRead  = "read"  // read reports, entries, groupings, releases and jobs
+	TokenScopeWrite = "write" // store sitemaps and queue, cancel or retry jobs
+)
+
+// APIToken authenticates REST API requests as a user
+// Only a hash of the token is stored; the token itself is shown once, when it's issued
+type APIToken struct {
+	ID        string   `json:"id"`
+	UserID    string   `json:"user_id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"` // first characters of the token, to tell tokens apart
+	TokenHash string   `json:"-"`      // hex SHA-256 of the token
+	Scopes    []string `json:"scopes"`
+
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // null if the token never expires
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -28,11 +28,18 @@ type Entry struct {
 	Type       EntryType `json:"type"`                  // url or sitemap
 	URL        string    `json:"url"`
 
+	// Canonical form of URL used to match entries across reports; nil when the
+	// snapshot was tracked without normalization or the URL was already canonical
+	NormalizedURL *string `json:"normalized_url,omitempty"`
+
 	// Sitemap metadata fields
 	LastModified *time.Time `json:"last_modified,omitempty"`
 	ChangeFreq   *string    `json:"change_freq,omitempty"`
 	Priority     *float64   `json:"priority,omitempty"`
 
+	// Sitemap protocol extensions (image, video, news, hreflang)
+	Extensions *EntryExtensions `json:"extensions,omitempty"`
+
 	// Validation fields
 	IsValid         bool    `json:"is_valid"`
 	ValidationError *string `json:"validation_error,omitempty"`
@@ -43,6 +50,8 @@ type Entry struct {
 	ResponseTimeMs    *int       `json:"response_time_ms,omitempty"`
 	LivenessCheckedAt *time.Time `json:"liveness_checked_at,omitempty"`
 	LivenessError     *string    `json:"liveness_error,omitempty"`
+	FinalURL          *string    `json:"final_url,omitempty"`      // set when the URL redirects
+	RedirectChain     []string   `json:"redirect_chain,omitempty"` // URLs that answered with a redirect, in order
 
 	// Sampling metadata
 	SelectionReason SelectionReason `json:"selection_reason"`
@@ -0,0 +1,52 @@
+package models // domain models
+
+import "time"
+
+// EntryExtensions holds sitemap protocol extension data attached to an entry
+type EntryExtensions struct {
+	Images     []EntryImage     `json:"images,omitempty"`
+	Videos     []EntryVideo     `json:"videos,omitempty"`
+	News       *EntryNews       `json:"news,omitempty"`
+	Alternates []EntryAlternate `json:"alternates,omitempty"`
+}
+
+// EntryImage is an image annotation from the Google image sitemap extension
+type EntryImage struct {
+	Loc         string  `json:"loc"`
+	Caption     *string `json:"caption,omitempty"`
+	Title       *string `json:"title,omitempty"`
+	GeoLocation *string `json:"geo_location,omitempty"`
+	License     *string `json:"license,omitempty"`
+}
+
+// EntryVideo is a video annotation from the Google video sitemap extension
+type EntryVideo struct {
+	ThumbnailLoc    string     `json:"thumbnail_loc"`
+	Title           string     `json:"title"`
+	Description     string     `json:"description"`
+	ContentLoc      *string    `json:"content_loc,omitempty"`
+	PlayerLoc       *string    `json:"player_loc,omitempty"`
+	DurationSeconds *int       `json:"duration_seconds,omitempty"`
+	Rating          *float64   `json:"rating,omitempty"`
+	ViewCount       *int       `json:"view_count,omitempty"`
+	PublicationDate *time.Time `json:"publication_date,omitempty"`
+	ExpirationDate  *time.Time `json:"expiration_date,omitempty"`
+	FamilyFriendly  *bool      `json:"family_friendly,omitempty"`
+	Live            *bool      `json:"live,omitempty"`
+	Uploader        *string    `json:"uploader,omitempty"`
+	Tags            []string   `json:"tags,omitempty"`
+}
+
+// EntryNews is a news article annotation from the Google news sitemap extension
+type EntryNews struct {
+	PublicationName     string     `json:"publication_name"`
+	PublicationLanguage string     `json:"publication_language"`
+	PublicationDate     *time.Time `json:"publication_date,omitempty"`
+	Title               string     `json:"title"`
+}
+
+// EntryAlternate is an xhtml:link hreflang alternate of an entry
+type EntryAlternate struct {
+	Hreflang string `json:"hreflang"`
+	Href     string `json:"href"`
+}
@@ -0,0 +1,37 @@
+package models // domain models
+
+import "time"
+
+// GroupingRuleType indicates how a grouping rule matches URLs
+type GroupingRuleType string
+
+const (
+	GroupingRuleTypeGlob       GroupingRuleType = "glob"        // glob over the URL path (or full URL if the pattern has a scheme)
+	GroupingRuleTypeRegex      GroupingRuleType = "regex"       // regular expression over the full URL
+	GroupingRuleTypeHost       GroupingRuleType = "host"        // host name, optionally *.example.com
+	GroupingRuleTypeQueryParam GroupingRuleType = "query_param" // query parameter name, or name=value
+)
+
+// GroupingRule assigns URLs matching a pattern to a Group. Rules are evaluated
+// from the highest priority down; the first match wins.
+type GroupingRule struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	GroupingID string           `json:"grouping_id"`
+	Type       GroupingRuleType `json:"type"`
+	Pattern    string           `json:"pattern"`
+	Priority   int              `json:"priority"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+}
+
+// GroupingOverride pins a single URL to a Group, taking precedence over rules
+// and automatic grouping in every report
+type GroupingOverride struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	URL        string    `json:"url"`
+	GroupingID string    `json:"grouping_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -36,6 +36,10 @@ type Report struct {
 	SamplingStrategy SamplingStrategy `json:"sampling_strategy"`
 	SamplingRate     *float64         `json:"sampling_rate,omitempty"` // e.g., 0.1 for 10%
 
+	// Normalization is the sitemap.Normalizer Spec the entries' NormalizedURL
+	// was computed with; nil when the report wasn't normalized
+	Normalization *string `json:"normalization,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
\ No newline at end of file
@@ -19,3 +19,30 @@ type ReportDiff struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// DiffChangeType indicates how an entry differs between two reports
+type DiffChangeType string
+
+const (
+	DiffChangeTypeAdded   DiffChangeType = "added"   // only in the compare report
+	DiffChangeTypeRemoved DiffChangeType = "removed" // only in the base report
+	DiffChangeTypeChanged DiffChangeType = "changed" // in both, with different field values
+)
+
+// DiffFieldChange is a single field whose value differs between two reports.
+// An empty value means the field was absent.
+type DiffFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ReportDiffEntry is one added, removed or changed URL of a ReportDiff
+type ReportDiffEntry struct {
+	ID           string            `json:"id"`
+	ReportDiffID string            `json:"report_diff_id"`
+	URL          string            `json:"url"`
+	ChangeType   DiffChangeType    `json:"change_type"`
+	Fields       []DiffFieldChange `json:"fields,omitempty"` // only for changed entries
+
+	CreatedAt time.Time `json:"created_at"`
+}
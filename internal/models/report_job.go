@@ -16,6 +16,7 @@ type JobType string
 const (
 	JobTypeURL JobType = "url"
 	JobTypeUpload JobType = "upload"
+	JobTypeFile JobType = "file" // a local path, e.g. from a schedule
 )
 
 type CompressionFormat string
@@ -41,10 +42,12 @@ type ReportJob struct {
 	ShouldCheckEntryLiveness   bool `json:"should_check_entry_liveness"`
 	ShouldCheckForValidEntries bool `json:"should_check_for_valid_entries"`
 	MaxStoredEntries           int  `json:"max_stored_entries"` // threshold for sampling (0 = store all)
+	TimeoutSeconds             int  `json:"timeout_seconds"`    // per-job timeout (0 = runner default)
 
 	// Job status
 	Status      string     `json:"status"`
 	ErrorMessage *string   `json:"error_message,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
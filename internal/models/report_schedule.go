@@ -0,0 +1,28 @@
+package models // domain models
+
+import "time"
+
+// ReportSchedule tracks a sitemap on a recurring cron schedule. Every run
+// queues a ReportJob that references the schedule.
+type ReportSchedule struct {
+	ID             string `json:"id"`
+	UserID         string `json:"user_id"`
+	Name           string `json:"name"`
+	SourceLocation string `json:"source_location"` // URL or file path
+	CronExpression string `json:"cron_expression"` // five-field cron or @daily style shorthand
+	Timezone       string `json:"timezone"`        // IANA name the expression is evaluated in
+
+	// Processing configuration copied to every job
+	ShouldCheckEntryLiveness bool `json:"should_check_entry_liveness"`
+	MaxStoredEntries         int  `json:"max_stored_entries"` // threshold for sampling (0 = store all)
+
+	// Run timing
+	JitterSeconds int        `json:"jitter_seconds"` // random delay of up to this many seconds added to every run
+	CatchUp       bool       `json:"catch_up"`       // run once for runs missed while the scheduler was down
+	IsPaused      bool       `json:"is_paused"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"` // null while paused
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -2,18 +2,29 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"jonopens/sitemapper/internal/models"
 )
 
+// ErrNotFound is returned when a record doesn't exist
+var ErrNotFound = errors.New("not found")
+
 // Database aggregates all repositories and provides transaction support
 type Database interface {
 	Entries() EntryRepository
 	Reports() ReportRepository
 	Users() UserRepository
 	Groupings() GroupingRepository
+	ReportGroupings() ReportGroupingRepository
+	ReportDiffs() ReportDiffRepository
+	GroupingRules() GroupingRuleRepository
+	GroupingOverrides() GroupingOverrideRepository
 	ReportJobs() ReportJobRepository
+	ReportSchedules() ReportScheduleRepository
 	Releases() ReleaseRepository
+	APITokens() APITokenRepository
 	
 	// Transaction support
 	BeginTx(ctx context.Context) (Database, error)
@@ -25,6 +36,7 @@ type Database interface {
 // EntryRepository defines the contract for entry data access
 type EntryRepository interface {
 	Create(ctx context.Context, entry *models.Entry) error
+	CreateBatch(ctx context.Context, entries []*models.Entry) error // all or none are stored
 	GetByID(ctx context.Context, id string) (*models.Entry, error)
 	List(ctx context.Context, filters EntryFilters) ([]*models.Entry, error)
 	Update(ctx context.Context, entry *models.Entry) error
@@ -60,6 +72,43 @@ type GroupingRepository interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// ReportDiffRepository defines the contract for report diff data access
+type ReportDiffRepository interface {
+	Create(ctx context.Context, diff *models.ReportDiff) error
+	GetByID(ctx context.Context, id string) (*models.ReportDiff, error)
+	List(ctx context.Context, filters ReportDiffFilters) ([]*models.ReportDiff, error)
+	Delete(ctx context.Context, id string) error
+	CreateEntry(ctx context.Context, entry *models.ReportDiffEntry) error
+	ListEntries(ctx context.Context, diffID string) ([]*models.ReportDiffEntry, error)
+}
+
+// GroupingRuleRepository defines the contract for grouping rule data access
+type GroupingRuleRepository interface {
+	Create(ctx context.Context, rule *models.GroupingRule) error
+	GetByID(ctx context.Context, id string) (*models.GroupingRule, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.GroupingRule, error)
+	Update(ctx context.Context, rule *models.GroupingRule) error
+	Delete(ctx context.Context, id string) error
+}
+
+// GroupingOverrideRepository defines the contract for per-URL grouping override data access
+type GroupingOverrideRepository interface {
+	Create(ctx context.Context, override *models.GroupingOverride) error
+	GetByURL(ctx context.Context, userID, url string) (*models.GroupingOverride, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.GroupingOverride, error)
+	Update(ctx context.Context, override *models.GroupingOverride) error
+	Delete(ctx context.Context, id string) error
+}
+
+// ReportGroupingRepository defines the contract for report grouping data access
+type ReportGroupingRepository interface {
+	Create(ctx context.Context, reportGrouping *models.ReportGrouping) error
+	GetByID(ctx context.Context, id string) (*models.ReportGrouping, error)
+	ListByReportID(ctx context.Context, reportID string) ([]*models.ReportGrouping, error)
+	Update(ctx context.Context, reportGrouping *models.ReportGrouping) error
+	Delete(ctx context.Context, id string) error
+}
+
 // ReportJobRepository defines the contract for report job data access
 type ReportJobRepository interface {
 	Create(ctx context.Context, job *models.ReportJob) error
@@ -67,6 +116,24 @@ type ReportJobRepository interface {
 	List(ctx context.Context, filters JobFilters) ([]*models.ReportJob, error)
 	Update(ctx context.Context, job *models.ReportJob) error
 	Delete(ctx context.Context, id string) error
+	// Claim atomically moves a pending job to running as of startedAt and
+	// returns it. It returns ErrNotFound if no pending job has the ID, so only
+	// one of several concurrent claims of a job succeeds.
+	Claim(ctx context.Context, id string, startedAt time.Time) (*models.ReportJob, error)
+	// Finish stores the outcome of a running job. It returns ErrNotFound if the
+	// job is no longer running, for example because it was cancelled from
+	// another process, so a late outcome never overwrites another one.
+	Finish(ctx context.Context, job *models.ReportJob) error
+}
+
+// ReportScheduleRepository defines the contract for report schedule data access
+type ReportScheduleRepository interface {
+	Create(ctx context.Context, schedule *models.ReportSchedule) error
+	GetByID(ctx context.Context, id string) (*models.ReportSchedule, error)
+	List(ctx context.Context, filters ScheduleFilters) ([]*models.ReportSchedule, error)
+	ListDue(ctx context.Context, before time.Time) ([]*models.ReportSchedule, error) // unpaused, NextRunAt <= before
+	Update(ctx context.Context, schedule *models.ReportSchedule) error
+	Delete(ctx context.Context, id string) error
 }
 
 // ReleaseRepository defines the contract for release data access
@@ -78,6 +145,16 @@ type ReleaseRepository interface {
 	Delete(ctx context.Context, id string) error
 }
 
+// APITokenRepository defines the contract for API token data access
+type APITokenRepository interface {
+	Create(ctx context.Context, token *models.APIToken) error
+	GetByID(ctx context.Context, id string) (*models.APIToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.APIToken, error)
+	Update(ctx context.Context, token *models.APIToken) error
+	Delete(ctx context.Context, id string) error
+}
+
 // Filter types for querying
 type EntryFilters struct {
 	ReportID string
@@ -92,11 +169,28 @@ type ReportFilters struct {
 	Offset int
 }
 
+type ReportDiffFilters struct {
+	UserID   string
+	ReportID string // matches either the base or the compare report
+	Limit    int
+	Offset   int
+}
+
 type JobFilters struct {
-	Status string
-	UserID string
-	Limit  int
-	Offset int
+	Status          string
+	UserID          string
+	JobType         models.JobType
+	CompletedBefore *time.Time // only jobs that completed before this time
+	OldestFirst     bool       // list oldest first instead of newest first
+	Limit           int
+	Offset          int
+}
+
+type ScheduleFilters struct {
+	UserID        string
+	IncludePaused bool
+	Limit         int
+	Offset        int
 }
 
 type ReleaseFilters struct {
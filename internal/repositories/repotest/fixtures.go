@@ -0,0 +1,155 @@
+package repotest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+func newReport(id, userID string, createdAt time.Time) *models.Report {
+	return &models.Report{
+		ID:               id,
+		UserID:           userID,
+		EntryCount:       4,
+		StoredEntryCount: 4,
+		ValidEntryCount:  3,
+		IsFullyStored:    true,
+		SamplingStrategy: models.SamplingStrategyNone,
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+	}
+}
+
+// createReport stores a new report, failing the test if it can't
+func createReport(t *testing.T, db repositories.Database, id, userID string, createdAt time.Time) *models.Report {
+	t.Helper()
+	report := newReport(id, userID, createdAt)
+	if err := db.Reports().Create(context.Background(), report); err != nil {
+		t.Fatalf("Reports().Create(%s): %v", id, err)
+	}
+	return report
+}
+
+func newEntry(id, reportID string, entryType models.EntryType, url string) *models.Entry {
+	return &models.Entry{
+		ID:              id,
+		ReportID:        reportID,
+		Type:            entryType,
+		URL:             url,
+		IsValid:         true,
+		SelectionReason: models.SelectionReasonFullStorage,
+		CreatedAt:       baseTime,
+		UpdatedAt:       baseTime,
+	}
+}
+
+func newReportGrouping(id, reportID, groupingID string, createdAt time.Time) *models.ReportGrouping {
+	return &models.ReportGrouping{
+		ID:               id,
+		ReportID:         reportID,
+		GroupingID:       groupingID,
+		TotalEntryCount:  2,
+		StoredEntryCount: 2,
+		ValidEntryCount:  2,
+		CreatedAt:        createdAt,
+		UpdatedAt:        createdAt,
+	}
+}
+
+func newReportDiff(id, userID, baseReportID, compareReportID string, createdAt time.Time) *models.ReportDiff {
+	return &models.ReportDiff{
+		ID:              id,
+		UserID:          userID,
+		BaseReportID:    baseReportID,
+		CompareReportID: compareReportID,
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+	}
+}
+
+func newGroupingRule(id, userID string, priority int, createdAt time.Time) *models.GroupingRule {
+	return &models.GroupingRule{
+		ID:         id,
+		UserID:     userID,
+		GroupingID: "grouping-1",
+		Type:       models.GroupingRuleTypeGlob,
+		Pattern:    "/docs/**",
+		Priority:   priority,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+}
+
+func newGroupingOverride(id, userID, url string, createdAt time.Time) *models.GroupingOverride {
+	return &models.GroupingOverride{
+		ID:         id,
+		UserID:     userID,
+		URL:        url,
+		GroupingID: "grouping-1",
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+}
+
+func newReportJob(id, userID, status string, createdAt time.Time) *models.ReportJob {
+	return &models.ReportJob{
+		ID:             id,
+		UserID:         userID,
+		SourceLocation: "https://example.com/sitemap.xml",
+		JobType:        models.JobTypeURL,
+		Status:         status,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	}
+}
+
+func newReportSchedule(id, userID string, nextRunAt *time.Time, createdAt time.Time) *models.ReportSchedule {
+	return &models.ReportSchedule{
+		ID:             id,
+		UserID:         userID,
+		Name:           id,
+		SourceLocation: "https://example.com/sitemap.xml",
+		CronExpression: "@daily",
+		Timezone:       "UTC",
+		CatchUp:        true,
+		NextRunAt:      nextRunAt,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+	}
+}
+
+func newRelease(id, userID string, releaseDate time.Time) *models.Release {
+	return &models.Release{
+		ID:          id,
+		UserID:      userID,
+		ReleaseDate: releaseDate,
+		CreatedAt:   baseTime,
+		UpdatedAt:   baseTime,
+	}
+}
+
+func newAPIToken(id, userID string, createdAt time.Time) *models.APIToken {
+	return &models.APIToken{
+		ID:        id,
+		UserID:    userID,
+		Name:      id,
+		Prefix:    "smp_" + id,
+		TokenHash: "hash-" + id,
+		Scopes:    []string{models.TokenScopeRead, models.TokenScopeWrite},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+// ids returns the ID field of every model in a list
+func ids[T any](items []*T) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, reflect.ValueOf(item).Elem().FieldByName("ID").String())
+	}
+	return result
+}
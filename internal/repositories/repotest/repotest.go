@@ -0,0 +1,1293 @@
+// Package repotest is a conformance suite for repositories.Database
+// implementations. Every backend runs the same tests, so services see the
+// same filtering, ordering, pagination and transaction behavior whichever
+// database is configured.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+// Suite runs the conformance tests against one backend
+type Suite struct {
+	// New returns an empty database. It's called once per test, and the
+	// suite closes the database when the test ends.
+	New func(t *testing.T) repositories.Database
+
+	// ErrNotFound is the error the backend returns for missing rows
+	ErrNotFound error
+}
+
+// Run runs every conformance test as a subtest of t
+func (s Suite) Run(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, db repositories.Database)
+	}{
+		{"Entries", s.testEntries},
+		{"EntryBatches", s.testEntryBatches},
+		{"Reports", s.testReports},
+		{"Users", s.testUsers},
+		{"APITokens", s.testAPITokens},
+		{"Groupings", s.testGroupings},
+		{"ReportGroupings", s.testReportGroupings},
+		{"ReportDiffs", s.testReportDiffs},
+		{"GroupingRules", s.testGroupingRules},
+		{"GroupingOverrides", s.testGroupingOverrides},
+		{"ReportJobs", s.testReportJobs},
+		{"ReportJobClaims", s.testReportJobClaims},
+		{"ReportJobClaimRace", s.testReportJobClaimRace},
+		{"ReportSchedules", s.testReportSchedules},
+		{"Releases", s.testReleases},
+		{"Transactions", s.testTransactions},
+		{"Scoped", s.testScoped},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := s.New(t)
+			t.Cleanup(func() { db.Close() })
+			test.run(t, db)
+		})
+	}
+}
+
+// baseTime has microsecond precision, the finest all backends store
+var baseTime = time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.UTC)
+
+// at returns baseTime plus n minutes
+func at(n int) time.Time {
+	return baseTime.Add(time.Duration(n) * time.Minute)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func (s Suite) testEntries(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	first := createReport(t, db, "report-1", "user-1", at(0))
+	second := createReport(t, db, "report-2", "user-1", at(1))
+
+	full := &models.Entry{
+		ID:            "entry-full",
+		ReportID:      first.ID,
+		GroupingID:    ptr("grouping-1"),
+		Type:          models.EntryTypeURL,
+		URL:           "https://example.com/b",
+		NormalizedURL: ptr("https://example.com/b"),
+		LastModified:  ptr(at(-60)),
+		ChangeFreq:    ptr("daily"),
+		Priority:      ptr(0.8),
+		Extensions: &models.EntryExtensions{
+			Images: []models.EntryImage{{Loc: "https://example.com/b.png", Caption: ptr("B")}},
+			News: &models.EntryNews{
+				PublicationName:     "Example",
+				PublicationLanguage: "en",
+				PublicationDate:     ptr(at(-30)),
+				Title:               "B",
+			},
+		},
+		IsValid:           true,
+		HTTPStatusCode:    ptr(200),
+		IsLive:            ptr(true),
+		ResponseTimeMs:    ptr(42),
+		LivenessCheckedAt: ptr(at(2)),
+		FinalURL:          ptr("https://example.com/b/"),
+		RedirectChain:     []string{"https://example.com/b"},
+		SelectionReason:   models.SelectionReasonFullStorage,
+		CreatedAt:         at(0),
+		UpdatedAt:         at(0),
+	}
+	entries := []*models.Entry{
+		full,
+		newEntry("entry-a", first.ID, models.EntryTypeURL, "https://example.com/a"),
+		newEntry("entry-c", first.ID, models.EntryTypeURL, "https://example.com/c"),
+		newEntry("entry-sitemap", first.ID, models.EntryTypeSitemap, "https://example.com/sitemap-1.xml"),
+		newEntry("entry-other", second.ID, models.EntryTypeURL, "https://example.com/a"),
+	}
+	for _, entry := range entries {
+		if err := db.Entries().Create(ctx, entry); err != nil {
+			t.Fatalf("Create(%s): %v", entry.ID, err)
+		}
+	}
+
+	got, err := db.Entries().GetByID(ctx, full.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, full)
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.Entries().GetByID(ctx, "missing")))
+
+	list, err := db.Entries().List(ctx, repositories.EntryFilters{ReportID: first.ID})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(ReportID)", ids(list), "entry-a", "entry-full", "entry-c", "entry-sitemap")
+
+	urlType := models.EntryTypeURL
+	list, err = db.Entries().List(ctx, repositories.EntryFilters{ReportID: first.ID, Type: &urlType})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(ReportID, Type)", ids(list), "entry-a", "entry-full", "entry-c")
+
+	list, err = db.Entries().List(ctx, repositories.EntryFilters{ReportID: first.ID, Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Limit, Offset)", ids(list), "entry-full", "entry-c")
+
+	list, err = db.Entries().List(ctx, repositories.EntryFilters{ReportID: first.ID, Offset: 3})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Offset)", ids(list), "entry-sitemap")
+
+	list, err = db.Entries().List(ctx, repositories.EntryFilters{ReportID: first.ID, Offset: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Offset past end)", ids(list))
+
+	count, err := db.Entries().CountByType(ctx, models.EntryTypeURL)
+	if err != nil {
+		t.Fatalf("CountByType: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("CountByType(url) = %d, want 4", count)
+	}
+
+	updated := *full
+	updated.GroupingID = nil
+	updated.IsLive = ptr(false)
+	updated.HTTPStatusCode = ptr(404)
+	updated.Extensions = nil
+	updated.RedirectChain = nil
+	updated.UpdatedAt = at(5)
+	if err := db.Entries().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.Entries().GetByID(ctx, full.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.Entries().Update(ctx, newEntry("missing", first.ID, models.EntryTypeURL, "https://example.com/x")))
+
+	if err := db.Entries().Delete(ctx, "entry-a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.Entries().GetByID(ctx, "entry-a")))
+	if err := db.Entries().Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete(missing) = %v, want nil", err)
+	}
+
+	// Entries belong to their report
+	if err := db.Reports().Delete(ctx, first.ID); err != nil {
+		t.Fatalf("Reports().Delete: %v", err)
+	}
+	list, err = db.Entries().List(ctx, repositories.EntryFilters{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List after report Delete", ids(list), "entry-other")
+}
+
+func (s Suite) testEntryBatches(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	report := createReport(t, db, "report-1", "user-1", at(0))
+
+	if err := db.Entries().CreateBatch(ctx, nil); err != nil {
+		t.Fatalf("CreateBatch(empty): %v", err)
+	}
+
+	// Large enough to span several statements in the SQL backends
+	entries := make([]*models.Entry, 1201)
+	for i := range entries {
+		entries[i] = newEntry(fmt.Sprintf("entry-%04d", i), report.ID, models.EntryTypeURL, fmt.Sprintf("https://example.com/%04d", i))
+	}
+	last := entries[len(entries)-1]
+	last.Priority = ptr(0.3)
+	last.LastModified = ptr(at(-5))
+	last.Extensions = &models.EntryExtensions{Alternates: []models.EntryAlternate{{Hreflang: "de", Href: "https://example.com/de"}}}
+	last.RedirectChain = []string{"https://example.com/old"}
+	if err := db.Entries().CreateBatch(ctx, entries); err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+
+	list, err := db.Entries().List(ctx, repositories.EntryFilters{ReportID: report.ID})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != len(entries) {
+		t.Fatalf("List returned %d entries, want %d", len(list), len(entries))
+	}
+	for i, entry := range list {
+		assertEqual(t, "List after CreateBatch", entry, entries[i])
+		if t.Failed() {
+			break
+		}
+	}
+
+	// A batch in a rolled back transaction leaves nothing behind
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	other := createReport(t, tx, "report-2", "user-1", at(1))
+	batch := []*models.Entry{
+		newEntry("entry-rolled-back-1", other.ID, models.EntryTypeURL, "https://example.com/a"),
+		newEntry("entry-rolled-back-2", other.ID, models.EntryTypeURL, "https://example.com/b"),
+	}
+	if err := tx.Entries().CreateBatch(ctx, batch); err != nil {
+		t.Fatalf("CreateBatch in transaction: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Rollback", getErr(db.Entries().GetByID(ctx, "entry-rolled-back-1")))
+}
+
+func (s Suite) testReports(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	createReport(t, db, "report-1", "user-1", at(0))
+	createReport(t, db, "report-2", "user-2", at(1))
+	createReport(t, db, "report-3", "user-1", at(2))
+	// Same creation time as report-3; ties are broken by ID
+	report := createReport(t, db, "report-4", "user-1", at(2))
+
+	got, err := db.Reports().GetByID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, report)
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.Reports().GetByID(ctx, "missing")))
+
+	list, err := db.Reports().List(ctx, repositories.ReportFilters{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List", ids(list), "report-4", "report-3", "report-2", "report-1")
+
+	list, err = db.Reports().List(ctx, repositories.ReportFilters{UserID: "user-1", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(UserID, Limit, Offset)", ids(list), "report-3", "report-1")
+
+	list, err = db.Reports().GetByUserID(ctx, "user-2")
+	if err != nil {
+		t.Fatalf("GetByUserID: %v", err)
+	}
+	assertIDs(t, "GetByUserID", ids(list), "report-2")
+
+	updated := *report
+	updated.EntryCount = 10
+	updated.StoredEntryCount = 5
+	updated.IsFullyStored = false
+	updated.SamplingStrategy = models.SamplingStrategyStratified
+	updated.SamplingRate = ptr(0.5)
+	updated.Normalization = ptr("trailing_slash,www")
+	updated.UpdatedAt = at(3)
+	if err := db.Reports().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.Reports().GetByID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.Reports().Update(ctx, newReport("missing", "user-1", at(0))))
+
+	if err := db.Reports().Delete(ctx, report.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.Reports().GetByID(ctx, report.ID)))
+	if err := db.Reports().Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete(missing) = %v, want nil", err)
+	}
+}
+
+func (s Suite) testUsers(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	user := &models.User{
+		ID:        "user-1",
+		Email:     "ada@example.com",
+		Name:      "Ada",
+		Phone:     ptr("+15555550100"),
+		CreatedAt: at(0),
+		UpdatedAt: at(0),
+	}
+	other := &models.User{ID: "user-2", Email: "grace@example.com", Name: "Grace", CreatedAt: at(1), UpdatedAt: at(1)}
+	for _, u := range []*models.User{user, other} {
+		if err := db.Users().Create(ctx, u); err != nil {
+			t.Fatalf("Create(%s): %v", u.ID, err)
+		}
+	}
+
+	got, err := db.Users().GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, user)
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.Users().GetByID(ctx, "missing")))
+
+	got, err = db.Users().GetByEmail(ctx, other.Email)
+	if err != nil {
+		t.Fatalf("GetByEmail: %v", err)
+	}
+	assertEqual(t, "GetByEmail", got, other)
+	s.assertNotFound(t, "GetByEmail(missing)", getErr(db.Users().GetByEmail(ctx, "missing@example.com")))
+
+	updated := *user
+	updated.Name = "Ada Lovelace"
+	updated.Phone = nil
+	updated.SlackID = ptr("U123")
+	updated.UpdatedAt = at(2)
+	if err := db.Users().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.Users().GetByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.Users().Update(ctx, &models.User{ID: "missing", Email: "missing@example.com"}))
+
+	if err := db.Users().Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.Users().GetByID(ctx, user.ID)))
+}
+
+func (s Suite) testAPITokens(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	for i, id := range []string{"user-1", "user-2"} {
+		user := &models.User{ID: id, Email: id + "@example.com", Name: id, CreatedAt: at(i), UpdatedAt: at(i)}
+		if err := db.Users().Create(ctx, user); err != nil {
+			t.Fatalf("Users().Create(%s): %v", id, err)
+		}
+	}
+
+	tokens := []*models.APIToken{
+		newAPIToken("token-b", "user-1", at(1)),
+		newAPIToken("token-a", "user-1", at(1)),
+		newAPIToken("token-c", "user-1", at(0)),
+		newAPIToken("token-d", "user-2", at(0)),
+	}
+	tokens[0].ExpiresAt = ptr(at(60))
+	for _, token := range tokens {
+		if err := db.APITokens().Create(ctx, token); err != nil {
+			t.Fatalf("Create(%s): %v", token.ID, err)
+		}
+	}
+
+	got, err := db.APITokens().GetByID(ctx, "token-b")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, tokens[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.APITokens().GetByID(ctx, "missing")))
+
+	got, err = db.APITokens().GetByHash(ctx, tokens[3].TokenHash)
+	if err != nil {
+		t.Fatalf("GetByHash: %v", err)
+	}
+	assertEqual(t, "GetByHash", got, tokens[3])
+	s.assertNotFound(t, "GetByHash(missing)", getErr(db.APITokens().GetByHash(ctx, "missing")))
+
+	list, err := db.APITokens().ListByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+	assertIDs(t, "ListByUserID", ids(list), "token-c", "token-a", "token-b")
+
+	updated := *tokens[0]
+	updated.Scopes = []string{models.TokenScopeRead}
+	updated.RevokedAt = ptr(at(5))
+	updated.UpdatedAt = at(5)
+	if err := db.APITokens().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.APITokens().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.APITokens().Update(ctx, newAPIToken("missing", "user-1", at(0))))
+
+	if err := db.APITokens().Delete(ctx, "token-a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.APITokens().GetByID(ctx, "token-a")))
+
+	// Deleting a user deletes their tokens
+	if err := db.Users().Delete(ctx, "user-2"); err != nil {
+		t.Fatalf("Users().Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after deleting the user", getErr(db.APITokens().GetByID(ctx, "token-d")))
+}
+
+func (s Suite) testGroupings(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	groupings := []*models.Group{
+		{ID: "grouping-2", UserID: "user-1", Name: "/blog", Description: ptr("Blog posts"), CreatedAt: at(0), UpdatedAt: at(0)},
+		{ID: "grouping-1", UserID: "user-1", Name: "/docs", CreatedAt: at(1), UpdatedAt: at(1)},
+		{ID: "grouping-3", UserID: "user-1", Name: "/blog", CreatedAt: at(2), UpdatedAt: at(2)},
+	}
+	for _, grouping := range groupings {
+		if err := db.Groupings().Create(ctx, grouping); err != nil {
+			t.Fatalf("Create(%s): %v", grouping.ID, err)
+		}
+	}
+
+	got, err := db.Groupings().GetByID(ctx, "grouping-2")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, groupings[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.Groupings().GetByID(ctx, "missing")))
+
+	list, err := db.Groupings().List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List", ids(list), "grouping-2", "grouping-3", "grouping-1")
+
+	updated := *groupings[1]
+	updated.Name = "/api"
+	updated.Description = ptr("API reference")
+	updated.UpdatedAt = at(3)
+	if err := db.Groupings().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.Groupings().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.Groupings().Update(ctx, &models.Group{ID: "missing"}))
+
+	if err := db.Groupings().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.Groupings().GetByID(ctx, updated.ID)))
+}
+
+func (s Suite) testReportGroupings(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	report := createReport(t, db, "report-1", "user-1", at(0))
+	other := createReport(t, db, "report-2", "user-1", at(1))
+
+	reportGroupings := []*models.ReportGrouping{
+		newReportGrouping("rg-2", report.ID, "grouping-1", at(0)),
+		newReportGrouping("rg-1", report.ID, "grouping-2", at(0)),
+		newReportGrouping("rg-0", report.ID, "grouping-3", at(1)),
+		newReportGrouping("rg-other", other.ID, "grouping-1", at(0)),
+	}
+	reportGroupings[0].MinURL = ptr("https://example.com/a")
+	reportGroupings[0].MaxURL = ptr("https://example.com/z")
+	for _, rg := range reportGroupings {
+		if err := db.ReportGroupings().Create(ctx, rg); err != nil {
+			t.Fatalf("Create(%s): %v", rg.ID, err)
+		}
+	}
+
+	got, err := db.ReportGroupings().GetByID(ctx, "rg-2")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, reportGroupings[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.ReportGroupings().GetByID(ctx, "missing")))
+
+	list, err := db.ReportGroupings().ListByReportID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("ListByReportID: %v", err)
+	}
+	assertIDs(t, "ListByReportID", ids(list), "rg-1", "rg-2", "rg-0")
+
+	updated := *reportGroupings[0]
+	updated.TotalEntryCount = 12
+	updated.LiveEntryCount = 11
+	updated.DownEntryCount = 1
+	updated.MinURL = nil
+	updated.UpdatedAt = at(2)
+	if err := db.ReportGroupings().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.ReportGroupings().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.ReportGroupings().Update(ctx, newReportGrouping("missing", report.ID, "grouping-1", at(0))))
+
+	if err := db.ReportGroupings().Delete(ctx, "rg-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.ReportGroupings().GetByID(ctx, "rg-1")))
+
+	// Report groupings belong to their report
+	if err := db.Reports().Delete(ctx, report.ID); err != nil {
+		t.Fatalf("Reports().Delete: %v", err)
+	}
+	list, err = db.ReportGroupings().ListByReportID(ctx, report.ID)
+	if err != nil {
+		t.Fatalf("ListByReportID: %v", err)
+	}
+	assertIDs(t, "ListByReportID after report Delete", ids(list))
+	list, err = db.ReportGroupings().ListByReportID(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("ListByReportID: %v", err)
+	}
+	assertIDs(t, "ListByReportID(other report)", ids(list), "rg-other")
+}
+
+func (s Suite) testReportDiffs(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	diffs := []*models.ReportDiff{
+		newReportDiff("diff-1", "user-1", "report-1", "report-2", at(0)),
+		newReportDiff("diff-2", "user-1", "report-2", "report-3", at(1)),
+		newReportDiff("diff-3", "user-2", "report-4", "report-5", at(2)),
+		newReportDiff("diff-4", "user-1", "report-1", "report-3", at(2)),
+	}
+	diffs[0].EntriesAdded = 3
+	diffs[0].EntriesRemoved = 2
+	diffs[0].EntriesChanged = 1
+	for _, diff := range diffs {
+		if err := db.ReportDiffs().Create(ctx, diff); err != nil {
+			t.Fatalf("Create(%s): %v", diff.ID, err)
+		}
+	}
+
+	got, err := db.ReportDiffs().GetByID(ctx, "diff-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, diffs[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.ReportDiffs().GetByID(ctx, "missing")))
+
+	list, err := db.ReportDiffs().List(ctx, repositories.ReportDiffFilters{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List", ids(list), "diff-4", "diff-3", "diff-2", "diff-1")
+
+	list, err = db.ReportDiffs().List(ctx, repositories.ReportDiffFilters{UserID: "user-1", ReportID: "report-2"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(UserID, ReportID)", ids(list), "diff-2", "diff-1")
+
+	list, err = db.ReportDiffs().List(ctx, repositories.ReportDiffFilters{UserID: "user-1", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(UserID, Limit, Offset)", ids(list), "diff-2")
+
+	diffEntries := []*models.ReportDiffEntry{
+		{ID: "de-1", ReportDiffID: "diff-1", URL: "https://example.com/b", ChangeType: models.DiffChangeTypeRemoved, CreatedAt: at(0)},
+		{ID: "de-2", ReportDiffID: "diff-1", URL: "https://example.com/a", ChangeType: models.DiffChangeTypeAdded, CreatedAt: at(0)},
+		{
+			ID:           "de-3",
+			ReportDiffID: "diff-1",
+			URL:          "https://example.com/c",
+			ChangeType:   models.DiffChangeTypeChanged,
+			Fields:       []models.DiffFieldChange{{Field: "priority", Old: "0.5", New: "0.8"}},
+			CreatedAt:    at(0),
+		},
+		{ID: "de-4", ReportDiffID: "diff-1", URL: "https://example.com/0", ChangeType: models.DiffChangeTypeRemoved, CreatedAt: at(0)},
+		{ID: "de-5", ReportDiffID: "diff-2", URL: "https://example.com/a", ChangeType: models.DiffChangeTypeAdded, CreatedAt: at(1)},
+	}
+	for _, entry := range diffEntries {
+		if err := db.ReportDiffs().CreateEntry(ctx, entry); err != nil {
+			t.Fatalf("CreateEntry(%s): %v", entry.ID, err)
+		}
+	}
+
+	listed, err := db.ReportDiffs().ListEntries(ctx, "diff-1")
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+	assertIDs(t, "ListEntries", ids(listed), "de-2", "de-3", "de-4", "de-1")
+	if len(listed) == 4 {
+		assertEqual(t, "ListEntries changed entry", listed[1], diffEntries[2])
+	}
+
+	// Diff entries belong to their diff
+	if err := db.ReportDiffs().Delete(ctx, "diff-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.ReportDiffs().GetByID(ctx, "diff-1")))
+	listed, err = db.ReportDiffs().ListEntries(ctx, "diff-1")
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+	assertIDs(t, "ListEntries after Delete", ids(listed))
+	listed, err = db.ReportDiffs().ListEntries(ctx, "diff-2")
+	if err != nil {
+		t.Fatalf("ListEntries: %v", err)
+	}
+	assertIDs(t, "ListEntries(other diff)", ids(listed), "de-5")
+}
+
+func (s Suite) testGroupingRules(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	rules := []*models.GroupingRule{
+		newGroupingRule("rule-1", "user-1", 0, at(0)),
+		newGroupingRule("rule-2", "user-1", 10, at(1)),
+		newGroupingRule("rule-4", "user-1", 0, at(-1)),
+		newGroupingRule("rule-3", "user-1", 0, at(-1)),
+		newGroupingRule("rule-other", "user-2", 100, at(0)),
+	}
+	for _, rule := range rules {
+		if err := db.GroupingRules().Create(ctx, rule); err != nil {
+			t.Fatalf("Create(%s): %v", rule.ID, err)
+		}
+	}
+
+	got, err := db.GroupingRules().GetByID(ctx, "rule-2")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, rules[1])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.GroupingRules().GetByID(ctx, "missing")))
+
+	// Highest priority first, then oldest first
+	list, err := db.GroupingRules().ListByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+	assertIDs(t, "ListByUserID", ids(list), "rule-2", "rule-3", "rule-4", "rule-1")
+
+	updated := *rules[0]
+	updated.Type = models.GroupingRuleTypeRegex
+	updated.Pattern = `^https://example\.com/docs/`
+	updated.Priority = 20
+	updated.UpdatedAt = at(2)
+	if err := db.GroupingRules().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.GroupingRules().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.GroupingRules().Update(ctx, newGroupingRule("missing", "user-1", 0, at(0))))
+
+	if err := db.GroupingRules().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.GroupingRules().GetByID(ctx, updated.ID)))
+}
+
+func (s Suite) testGroupingOverrides(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	overrides := []*models.GroupingOverride{
+		newGroupingOverride("override-1", "user-1", "https://example.com/b", at(0)),
+		newGroupingOverride("override-2", "user-1", "https://example.com/a", at(1)),
+		newGroupingOverride("override-3", "user-2", "https://example.com/b", at(2)),
+	}
+	for _, override := range overrides {
+		if err := db.GroupingOverrides().Create(ctx, override); err != nil {
+			t.Fatalf("Create(%s): %v", override.ID, err)
+		}
+	}
+
+	got, err := db.GroupingOverrides().GetByURL(ctx, "user-2", "https://example.com/b")
+	if err != nil {
+		t.Fatalf("GetByURL: %v", err)
+	}
+	assertEqual(t, "GetByURL", got, overrides[2])
+	s.assertNotFound(t, "GetByURL(missing)", getErr(db.GroupingOverrides().GetByURL(ctx, "user-2", "https://example.com/a")))
+
+	list, err := db.GroupingOverrides().ListByUserID(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+	assertIDs(t, "ListByUserID", ids(list), "override-2", "override-1")
+
+	updated := *overrides[0]
+	updated.GroupingID = "grouping-2"
+	updated.UpdatedAt = at(3)
+	if err := db.GroupingOverrides().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.GroupingOverrides().GetByURL(ctx, updated.UserID, updated.URL)
+	if err != nil {
+		t.Fatalf("GetByURL: %v", err)
+	}
+	assertEqual(t, "GetByURL after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.GroupingOverrides().Update(ctx, newGroupingOverride("missing", "user-1", "https://example.com/x", at(0))))
+
+	if err := db.GroupingOverrides().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByURL after Delete", getErr(db.GroupingOverrides().GetByURL(ctx, updated.UserID, updated.URL)))
+}
+
+func (s Suite) testReportJobs(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	jobs := []*models.ReportJob{
+		newReportJob("job-1", "user-1", models.ReportJobStatusPending, at(0)),
+		newReportJob("job-2", "user-2", models.ReportJobStatusPending, at(1)),
+		newReportJob("job-3", "user-1", models.ReportJobStatusCompleted, at(2)),
+		newReportJob("job-4", "user-1", models.ReportJobStatusPending, at(3)),
+	}
+	gzip := models.CompressionFormatGzip
+	jobs[0].CompressionFormat = &gzip
+	jobs[0].ReportScheduleID = ptr("schedule-1")
+	jobs[0].TimeoutSeconds = 600
+	jobs[2].CompletedAt = ptr(at(2))
+	jobs[3].JobType = models.JobTypeUpload
+	for _, job := range jobs {
+		if err := db.ReportJobs().Create(ctx, job); err != nil {
+			t.Fatalf("Create(%s): %v", job.ID, err)
+		}
+	}
+
+	got, err := db.ReportJobs().GetByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, jobs[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.ReportJobs().GetByID(ctx, "missing")))
+
+	list, err := db.ReportJobs().List(ctx, repositories.JobFilters{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List", ids(list), "job-4", "job-3", "job-2", "job-1")
+
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{Status: models.ReportJobStatusPending, UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Status, UserID)", ids(list), "job-4", "job-1")
+
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Limit, Offset)", ids(list), "job-3", "job-2")
+
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{Status: models.ReportJobStatusPending, OldestFirst: true, Limit: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(OldestFirst)", ids(list), "job-1", "job-2")
+
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{JobType: models.JobTypeUpload})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(JobType)", ids(list), "job-4")
+
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{CompletedBefore: ptr(at(3))})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(CompletedBefore)", ids(list), "job-3")
+	list, err = db.ReportJobs().List(ctx, repositories.JobFilters{CompletedBefore: ptr(at(2))})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(CompletedBefore, exclusive)", ids(list))
+
+	updated := *jobs[0]
+	updated.Status = models.ReportJobStatusFailed
+	updated.ReportID = ptr("report-1")
+	updated.ErrorMessage = ptr("fetch failed")
+	updated.StartedAt = ptr(at(1))
+	updated.CompletedAt = ptr(at(2))
+	updated.UpdatedAt = at(2)
+	if err := db.ReportJobs().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.ReportJobs().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.ReportJobs().Update(ctx, newReportJob("missing", "user-1", models.ReportJobStatusPending, at(0))))
+
+	if err := db.ReportJobs().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.ReportJobs().GetByID(ctx, updated.ID)))
+}
+
+func (s Suite) testReportJobClaims(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	pending := newReportJob("job-1", "user-1", models.ReportJobStatusPending, at(0))
+	pending.ErrorMessage = ptr("returned to pending")
+	for _, job := range []*models.ReportJob{
+		pending,
+		newReportJob("job-2", "user-1", models.ReportJobStatusCompleted, at(1)),
+	} {
+		if err := db.ReportJobs().Create(ctx, job); err != nil {
+			t.Fatalf("Create(%s): %v", job.ID, err)
+		}
+	}
+
+	claimed, err := db.ReportJobs().Claim(ctx, "job-1", at(5))
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+	want := *pending
+	want.Status = models.ReportJobStatusRunning
+	want.StartedAt = ptr(at(5))
+	want.ErrorMessage = nil
+	want.UpdatedAt = at(5)
+	assertEqual(t, "Claim", claimed, &want)
+	got, err := db.ReportJobs().GetByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Claim", got, &want)
+
+	s.assertNotFound(t, "Claim(running)", getErr(db.ReportJobs().Claim(ctx, "job-1", at(6))))
+	s.assertNotFound(t, "Claim(completed)", getErr(db.ReportJobs().Claim(ctx, "job-2", at(6))))
+	s.assertNotFound(t, "Claim(missing)", getErr(db.ReportJobs().Claim(ctx, "missing", at(6))))
+
+	finished := want
+	finished.Status = models.ReportJobStatusCompleted
+	finished.CompletedAt = ptr(at(7))
+	finished.UpdatedAt = at(7)
+	if err := db.ReportJobs().Finish(ctx, &finished); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	got, err = db.ReportJobs().GetByID(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Finish", got, &finished)
+
+	// Only a running job can be finished, so a second outcome is refused
+	late := finished
+	late.Status = models.ReportJobStatusFailed
+	s.assertNotFound(t, "Finish(completed)", db.ReportJobs().Finish(ctx, &late))
+	s.assertNotFound(t, "Finish(missing)", db.ReportJobs().Finish(ctx, newReportJob("missing", "user-1", models.ReportJobStatusFailed, at(8))))
+}
+
+// testReportJobClaimRace claims one job from many goroutines at once, as
+// runners in separate processes would; exactly one claim may succeed
+func (s Suite) testReportJobClaimRace(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	if err := db.ReportJobs().Create(ctx, newReportJob("job-1", "user-1", models.ReportJobStatusPending, at(0))); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	const claimers = 8
+	errs := make(chan error, claimers)
+	start := make(chan struct{})
+	for i := range claimers {
+		go func() {
+			<-start
+			_, err := db.ReportJobs().Claim(ctx, "job-1", at(i+1))
+			errs <- err
+		}()
+	}
+	close(start)
+
+	won := 0
+	for range claimers {
+		err := <-errs
+		switch {
+		case err == nil:
+			won++
+		case errors.Is(err, s.ErrNotFound):
+		default:
+			t.Errorf("Claim: %v", err)
+		}
+	}
+	if won != 1 {
+		t.Errorf("%d of %d concurrent claims succeeded, want 1", won, claimers)
+	}
+}
+
+func (s Suite) testReportSchedules(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	schedules := []*models.ReportSchedule{
+		newReportSchedule("schedule-1", "user-1", ptr(at(10)), at(0)),
+		newReportSchedule("schedule-2", "user-1", ptr(at(5)), at(1)),
+		newReportSchedule("schedule-3", "user-2", ptr(at(5)), at(1)),
+		newReportSchedule("schedule-4", "user-1", nil, at(2)),
+		newReportSchedule("schedule-5", "user-1", ptr(at(20)), at(3)),
+	}
+	schedules[0].LastRunAt = ptr(at(-50))
+	schedules[0].JitterSeconds = 30
+	schedules[0].CatchUp = false
+	schedules[3].IsPaused = true
+	for _, schedule := range schedules {
+		if err := db.ReportSchedules().Create(ctx, schedule); err != nil {
+			t.Fatalf("Create(%s): %v", schedule.ID, err)
+		}
+	}
+
+	got, err := db.ReportSchedules().GetByID(ctx, "schedule-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, schedules[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.ReportSchedules().GetByID(ctx, "missing")))
+
+	list, err := db.ReportSchedules().List(ctx, repositories.ScheduleFilters{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(UserID)", ids(list), "schedule-1", "schedule-2", "schedule-5")
+
+	list, err = db.ReportSchedules().List(ctx, repositories.ScheduleFilters{IncludePaused: true, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(IncludePaused, Limit, Offset)", ids(list), "schedule-3", "schedule-4")
+
+	// Longest overdue first; the due time is inclusive
+	due, err := db.ReportSchedules().ListDue(ctx, at(10))
+	if err != nil {
+		t.Fatalf("ListDue: %v", err)
+	}
+	assertIDs(t, "ListDue", ids(due), "schedule-2", "schedule-3", "schedule-1")
+
+	// Times are compared as instants, whatever their location
+	tokyo := time.FixedZone("JST", 9*60*60)
+	due, err = db.ReportSchedules().ListDue(ctx, at(7).In(tokyo))
+	if err != nil {
+		t.Fatalf("ListDue: %v", err)
+	}
+	assertIDs(t, "ListDue(JST)", ids(due), "schedule-2", "schedule-3")
+
+	updated := *schedules[0]
+	updated.IsPaused = true
+	updated.NextRunAt = nil
+	updated.LastRunAt = ptr(at(10))
+	updated.UpdatedAt = at(10)
+	if err := db.ReportSchedules().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.ReportSchedules().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.ReportSchedules().Update(ctx, newReportSchedule("missing", "user-1", nil, at(0))))
+
+	if err := db.ReportSchedules().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.ReportSchedules().GetByID(ctx, updated.ID)))
+}
+
+func (s Suite) testReleases(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	releases := []*models.Release{
+		newRelease("release-1", "user-1", at(0)),
+		newRelease("release-2", "user-1", at(60)),
+		newRelease("release-3", "user-2", at(30)),
+		newRelease("release-4", "user-1", at(60)),
+	}
+	releases[0].Version = ptr("v1.0.0")
+	releases[0].ReleaseNotes = ptr("First release")
+	releases[0].ReportScheduleID = ptr("schedule-1")
+	for _, release := range releases {
+		if err := db.Releases().Create(ctx, release); err != nil {
+			t.Fatalf("Create(%s): %v", release.ID, err)
+		}
+	}
+
+	got, err := db.Releases().GetByID(ctx, "release-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID", got, releases[0])
+	s.assertNotFound(t, "GetByID(missing)", getErr(db.Releases().GetByID(ctx, "missing")))
+
+	// Latest release first
+	list, err := db.Releases().List(ctx, repositories.ReleaseFilters{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(UserID)", ids(list), "release-4", "release-2", "release-1")
+
+	list, err = db.Releases().List(ctx, repositories.ReleaseFilters{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	assertIDs(t, "List(Limit, Offset)", ids(list), "release-3", "release-1")
+
+	updated := *releases[0]
+	updated.Version = ptr("v1.0.1")
+	updated.ReleaseNotes = nil
+	updated.ReleaseDate = at(90)
+	updated.UpdatedAt = at(90)
+	if err := db.Releases().Update(ctx, &updated); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err = db.Releases().GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertEqual(t, "GetByID after Update", got, &updated)
+	s.assertNotFound(t, "Update(missing)", db.Releases().Update(ctx, newRelease("missing", "user-1", at(0))))
+
+	if err := db.Releases().Delete(ctx, updated.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	s.assertNotFound(t, "GetByID after Delete", getErr(db.Releases().GetByID(ctx, updated.ID)))
+}
+
+func (s Suite) testTransactions(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+
+	t.Run("Commit", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("BeginTx: %v", err)
+		}
+		report := createReport(t, tx, "report-committed", "user-1", at(0))
+		if err := tx.Entries().Create(ctx, newEntry("entry-committed", report.ID, models.EntryTypeURL, "https://example.com/a")); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+
+		if _, err := db.Reports().GetByID(ctx, report.ID); err != nil {
+			t.Errorf("GetByID after Commit: %v", err)
+		}
+		if _, err := db.Entries().GetByID(ctx, "entry-committed"); err != nil {
+			t.Errorf("Entries().GetByID after Commit: %v", err)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		kept := createReport(t, db, "report-kept", "user-1", at(0))
+		deleted := createReport(t, db, "report-deleted", "user-1", at(0))
+		if err := db.Entries().Create(ctx, newEntry("entry-deleted", deleted.ID, models.EntryTypeURL, "https://example.com/a")); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		tx, err := db.BeginTx(ctx)
+		if err != nil {
+			t.Fatalf("BeginTx: %v", err)
+		}
+		report := createReport(t, tx, "report-rolled-back", "user-1", at(1))
+		if err := tx.Entries().Create(ctx, newEntry("entry-rolled-back", report.ID, models.EntryTypeURL, "https://example.com/a")); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		changed := *kept
+		changed.EntryCount = 99
+		if err := tx.Reports().Update(ctx, &changed); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if err := tx.Reports().Delete(ctx, deleted.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		// Writes are visible inside the transaction
+		if _, err := tx.Reports().GetByID(ctx, report.ID); err != nil {
+			t.Errorf("GetByID inside transaction: %v", err)
+		}
+		list, err := tx.Entries().List(ctx, repositories.EntryFilters{ReportID: report.ID})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		assertIDs(t, "List inside transaction", ids(list), "entry-rolled-back")
+
+		if err := tx.Rollback(); err != nil {
+			t.Fatalf("Rollback: %v", err)
+		}
+
+		s.assertNotFound(t, "GetByID after Rollback", getErr(db.Reports().GetByID(ctx, report.ID)))
+		s.assertNotFound(t, "Entries().GetByID after Rollback", getErr(db.Entries().GetByID(ctx, "entry-rolled-back")))
+		got, err := db.Reports().GetByID(ctx, kept.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.EntryCount != kept.EntryCount {
+			t.Errorf("EntryCount after Rollback = %d, want %d", got.EntryCount, kept.EntryCount)
+		}
+		if _, err := db.Reports().GetByID(ctx, deleted.ID); err != nil {
+			t.Errorf("GetByID of report deleted then rolled back: %v", err)
+		}
+		if _, err := db.Entries().GetByID(ctx, "entry-deleted"); err != nil {
+			t.Errorf("Entries().GetByID of entry deleted then rolled back: %v", err)
+		}
+	})
+}
+
+// assertNotFound checks that err is the backend's not found error
+func (s Suite) assertNotFound(t *testing.T, name string, err error) {
+	t.Helper()
+	if !errors.Is(err, s.ErrNotFound) {
+		t.Errorf("%s: error = %v, want %v", name, err, s.ErrNotFound)
+	}
+}
+
+// getErr drops the value of a lookup, keeping its error
+func getErr[T any](_ T, err error) error {
+	return err
+}
+
+// assertEqual compares two models field by field, treating times in
+// different locations as equal when they're the same instant
+func assertEqual(t *testing.T, name string, got, want any) {
+	t.Helper()
+	gotValue, wantValue := reflect.ValueOf(got), reflect.ValueOf(want)
+	if reflect.DeepEqual(utc(gotValue).Interface(), utc(wantValue).Interface()) {
+		return
+	}
+	t.Errorf("%s:\n got  %s\n want %s", name, describe(got), describe(want))
+}
+
+// utc returns a deep copy of v with every time converted to UTC
+func utc(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(utc(v.Elem()))
+		return c
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return reflect.ValueOf(t.UTC())
+		}
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(utc(v.Field(i)))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(utc(v.Index(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// describe formats a model with its pointer fields dereferenced
+func describe(v any) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Sprintf("%+v", v)
+	}
+	s := "{"
+	for i := 0; i < rv.NumField(); i++ {
+		if i > 0 {
+			s += " "
+		}
+		field := rv.Field(i)
+		if field.Kind() == reflect.Pointer && !field.IsNil() {
+			field = field.Elem()
+		}
+		s += fmt.Sprintf("%s:%+v", rv.Type().Field(i).Name, field.Interface())
+	}
+	return s + "}"
+}
+
+// assertIDs checks the IDs of a list, in order
+func assertIDs(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+// testScoped checks that a database scoped with repositories.ForUser hides
+// and protects other users' records
+func (s Suite) testScoped(t *testing.T, db repositories.Database) {
+	ctx := context.Background()
+	mine := createReport(t, db, "report-mine", "user-1", at(0))
+	theirs := createReport(t, db, "report-theirs", "user-2", at(1))
+	for _, entry := range []*models.Entry{
+		newEntry("entry-mine", mine.ID, models.EntryTypeURL, "https://example.com/mine"),
+		newEntry("entry-theirs", theirs.ID, models.EntryTypeURL, "https://example.com/theirs"),
+	} {
+		if err := db.Entries().Create(ctx, entry); err != nil {
+			t.Fatalf("Entries().Create(%s): %v", entry.ID, err)
+		}
+	}
+	scoped := repositories.ForUser(db, "user-1")
+
+	reports, err := scoped.Reports().List(ctx, repositories.ReportFilters{UserID: "user-2"})
+	if err != nil || len(reports) != 1 || reports[0].ID != mine.ID {
+		t.Errorf("List() = %v, %v; want only %s", reports, err, mine.ID)
+	}
+	if _, err := scoped.Reports().GetByID(ctx, theirs.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("GetByID(another user's report) error = %v, want ErrNotFound", err)
+	}
+	if _, err := scoped.Entries().GetByID(ctx, "entry-theirs"); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Entries().GetByID(another user's entry) error = %v, want ErrNotFound", err)
+	}
+	if _, err := scoped.Entries().List(ctx, repositories.EntryFilters{ReportID: theirs.ID}); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Entries().List(another user's report) error = %v, want ErrNotFound", err)
+	}
+	if _, err := scoped.Entries().List(ctx, repositories.EntryFilters{}); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("Entries().List(every report) error = %v, want ErrForbidden", err)
+	}
+	if entries, err := scoped.Entries().List(ctx, repositories.EntryFilters{ReportID: mine.ID}); err != nil || len(entries) != 1 {
+		t.Errorf("Entries().List(own report) = %d entries, %v; want 1", len(entries), err)
+	}
+
+	if err := scoped.Reports().Create(ctx, newReport("report-forged", "user-2", at(2))); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("Create(another user's report) error = %v, want ErrForbidden", err)
+	}
+	stolen := *theirs
+	stolen.UserID = "user-1"
+	if err := scoped.Reports().Update(ctx, &stolen); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("Update(another user's report) error = %v, want ErrNotFound", err)
+	}
+	if err := scoped.Entries().Create(ctx, newEntry("entry-forged", theirs.ID, models.EntryTypeURL, "https://example.com/forged")); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("Entries().Create(in another user's report) error = %v, want ErrForbidden", err)
+	}
+	if err := scoped.ReportDiffs().Create(ctx, newReportDiff("diff-forged", "user-1", mine.ID, theirs.ID, at(2))); !errors.Is(err, repositories.ErrForbidden) {
+		t.Errorf("ReportDiffs().Create(against another user's report) error = %v, want ErrForbidden", err)
+	}
+
+	// Deleting another user's report does nothing
+	if err := scoped.Reports().Delete(ctx, theirs.ID); err != nil {
+		t.Errorf("Delete(another user's report) error = %v", err)
+	}
+	if got, err := db.Reports().GetByID(ctx, theirs.ID); err != nil || got.UserID != "user-2" {
+		t.Errorf("another user's report after Delete and Update = %v, %v", got, err)
+	}
+
+	// Another user's pending job can't be claimed
+	if err := db.ReportJobs().Create(ctx, newReportJob("job-theirs", "user-2", models.ReportJobStatusPending, at(0))); err != nil {
+		t.Fatalf("ReportJobs().Create: %v", err)
+	}
+	if _, err := scoped.ReportJobs().Claim(ctx, "job-theirs", at(1)); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("ReportJobs().Claim(another user's job) error = %v, want ErrNotFound", err)
+	}
+
+	// Transactions stay scoped
+	tx, err := scoped.BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx() error = %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Reports().GetByID(ctx, theirs.ID); !errors.Is(err, repositories.ErrNotFound) {
+		t.Errorf("tx GetByID(another user's report) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,667 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+// ErrForbidden is returned when a scoped database is asked to write a record
+// for another user
+var ErrForbidden = errors.New("forbidden")
+
+// ForUser returns a view of db scoped to one user. Lists only return the
+// user's records, and other users' records look like they don't exist: reads
+// return ErrNotFound and deletes do nothing. Creating or updating a record
+// for another user fails with ErrForbidden. Entries, report groupings and
+// diff entries belong to the user who owns their report or diff.
+func ForUser(db Database, userID string) Database {
+	return &scopedDatabase{db: db, userID: userID}
+}
+
+type scopedDatabase struct {
+	db     Database
+	userID string
+}
+
+func (d *scopedDatabase) Entries() EntryRepository {
+	return &scopedEntries{repo: d.db.Entries(), reports: d.db.Reports(), userID: d.userID}
+}
+
+func (d *scopedDatabase) Reports() ReportRepository {
+	return &scopedReports{repo: d.db.Reports(), userID: d.userID}
+}
+
+func (d *scopedDatabase) Users() UserRepository {
+	return &scopedUsers{repo: d.db.Users(), userID: d.userID}
+}
+
+func (d *scopedDatabase) Groupings() GroupingRepository {
+	return &scopedGroupings{repo: d.db.Groupings(), userID: d.userID}
+}
+
+func (d *scopedDatabase) ReportGroupings() ReportGroupingRepository {
+	return &scopedReportGroupings{repo: d.db.ReportGroupings(), reports: d.db.Reports(), userID: d.userID}
+}
+
+func (d *scopedDatabase) ReportDiffs() ReportDiffRepository {
+	return &scopedReportDiffs{repo: d.db.ReportDiffs(), reports: d.db.Reports(), userID: d.userID}
+}
+
+func (d *scopedDatabase) GroupingRules() GroupingRuleRepository {
+	return &scopedGroupingRules{repo: d.db.GroupingRules(), userID: d.userID}
+}
+
+func (d *scopedDatabase) GroupingOverrides() GroupingOverrideRepository {
+	return &scopedGroupingOverrides{repo: d.db.GroupingOverrides(), userID: d.userID}
+}
+
+func (d *scopedDatabase) ReportJobs() ReportJobRepository {
+	return &scopedReportJobs{repo: d.db.ReportJobs(), userID: d.userID}
+}
+
+func (d *scopedDatabase) ReportSchedules() ReportScheduleRepository {
+	return &scopedReportSchedules{repo: d.db.ReportSchedules(), userID: d.userID}
+}
+
+func (d *scopedDatabase) Releases() ReleaseRepository {
+	return &scopedReleases{repo: d.db.Releases(), userID: d.userID}
+}
+
+func (d *scopedDatabase) APITokens() APITokenRepository {
+	return &scopedAPITokens{repo: d.db.APITokens(), userID: d.userID}
+}
+
+func (d *scopedDatabase) BeginTx(ctx context.Context) (Database, error) {
+	tx, err := d.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedDatabase{db: tx, userID: d.userID}, nil
+}
+
+func (d *scopedDatabase) Commit() error   { return d.db.Commit() }
+func (d *scopedDatabase) Rollback() error { return d.db.Rollback() }
+func (d *scopedDatabase) Close() error    { return d.db.Close() }
+
+// owned returns the record if it belongs to userID, and ErrNotFound otherwise
+func owned[T any](record *T, err error, owner func(*T) string, userID string) (*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	if owner(record) != userID {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// ownedList drops the records of other users from a list
+func ownedList[T any](records []*T, err error, owner func(*T) string, userID string) ([]*T, error) {
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(records, func(record *T) bool { return owner(record) != userID }), nil
+}
+
+// checkOwner fails with ErrForbidden if a record to be written belongs to
+// another user
+func checkOwner(owner, userID string) error {
+	if owner != userID {
+		return fmt.Errorf("%w: record belongs to user %q", ErrForbidden, owner)
+	}
+	return nil
+}
+
+// updateOwned updates a record if both it and its stored copy are the user's
+func updateOwned[T any](ctx context.Context, get func(context.Context, string) (*T, error), update func(context.Context, *T) error, record *T, id string, owner func(*T) string, userID string) error {
+	if err := checkOwner(owner(record), userID); err != nil {
+		return err
+	}
+	stored, err := get(ctx, id)
+	if _, err := owned(stored, err, owner, userID); err != nil {
+		return err
+	}
+	return update(ctx, record)
+}
+
+// deleteOwned deletes a record if it's the user's; like a missing record,
+// another user's record is left alone without an error
+func deleteOwned[T any](ctx context.Context, get func(context.Context, string) (*T, error), del func(context.Context, string) error, id string, owner func(*T) string, userID string) error {
+	record, err := get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner(record) != userID {
+		return nil
+	}
+	return del(ctx, id)
+}
+
+func reportOwner(r *models.Report) string                     { return r.UserID }
+func userOwner(u *models.User) string                         { return u.ID }
+func groupingOwner(g *models.Group) string                    { return g.UserID }
+func reportDiffOwner(d *models.ReportDiff) string             { return d.UserID }
+func groupingRuleOwner(r *models.GroupingRule) string         { return r.UserID }
+func groupingOverrideOwner(o *models.GroupingOverride) string { return o.UserID }
+func reportJobOwner(j *models.ReportJob) string               { return j.UserID }
+func reportScheduleOwner(s *models.ReportSchedule) string     { return s.UserID }
+func releaseOwner(r *models.Release) string                   { return r.UserID }
+func apiTokenOwner(t *models.APIToken) string                 { return t.UserID }
+
+// ownedReport returns ErrNotFound unless the report exists and is the user's
+func ownedReport(ctx context.Context, reports ReportRepository, reportID, userID string) error {
+	report, err := reports.GetByID(ctx, reportID)
+	_, err = owned(report, err, reportOwner, userID)
+	return err
+}
+
+// forbidMissing turns ErrNotFound into ErrForbidden, for writes that refer
+// to a parent record the user can't see
+func forbidMissing(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %v", ErrForbidden, err)
+	}
+	return err
+}
+
+type scopedEntries struct {
+	repo    EntryRepository
+	reports ReportRepository
+	userID  string
+}
+
+func (r *scopedEntries) Create(ctx context.Context, entry *models.Entry) error {
+	if err := ownedReport(ctx, r.reports, entry.ReportID, r.userID); err != nil {
+		return forbidMissing(err)
+	}
+	return r.repo.Create(ctx, entry)
+}
+
+func (r *scopedEntries) CreateBatch(ctx context.Context, entries []*models.Entry) error {
+	checked := make(map[string]bool)
+	for _, entry := range entries {
+		if checked[entry.ReportID] {
+			continue
+		}
+		if err := ownedReport(ctx, r.reports, entry.ReportID, r.userID); err != nil {
+			return forbidMissing(err)
+		}
+		checked[entry.ReportID] = true
+	}
+	return r.repo.CreateBatch(ctx, entries)
+}
+
+func (r *scopedEntries) GetByID(ctx context.Context, id string) (*models.Entry, error) {
+	entry, err := r.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ownedReport(ctx, r.reports, entry.ReportID, r.userID); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List only lists the entries of one report at a time
+func (r *scopedEntries) List(ctx context.Context, filters EntryFilters) ([]*models.Entry, error) {
+	if filters.ReportID == "" {
+		return nil, fmt.Errorf("%w: entries can only be listed by report", ErrForbidden)
+	}
+	if err := ownedReport(ctx, r.reports, filters.ReportID, r.userID); err != nil {
+		return nil, err
+	}
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedEntries) Update(ctx context.Context, entry *models.Entry) error {
+	if err := ownedReport(ctx, r.reports, entry.ReportID, r.userID); err != nil {
+		return forbidMissing(err)
+	}
+	if _, err := r.GetByID(ctx, entry.ID); err != nil {
+		return err
+	}
+	return r.repo.Update(ctx, entry)
+}
+
+func (r *scopedEntries) Delete(ctx context.Context, id string) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return r.repo.Delete(ctx, id)
+}
+
+// CountByType counts entries across every report, so it isn't available to
+// a single user
+func (r *scopedEntries) CountByType(ctx context.Context, entryType models.EntryType) (int, error) {
+	return 0, fmt.Errorf("%w: entries can only be counted across all users", ErrForbidden)
+}
+
+type scopedReports struct {
+	repo   ReportRepository
+	userID string
+}
+
+func (r *scopedReports) Create(ctx context.Context, report *models.Report) error {
+	if err := checkOwner(report.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, report)
+}
+
+func (r *scopedReports) GetByID(ctx context.Context, id string) (*models.Report, error) {
+	report, err := r.repo.GetByID(ctx, id)
+	return owned(report, err, reportOwner, r.userID)
+}
+
+func (r *scopedReports) GetByUserID(ctx context.Context, userID string) ([]*models.Report, error) {
+	if userID != r.userID {
+		return nil, nil
+	}
+	return r.repo.GetByUserID(ctx, userID)
+}
+
+func (r *scopedReports) List(ctx context.Context, filters ReportFilters) ([]*models.Report, error) {
+	filters.UserID = r.userID
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedReports) Update(ctx context.Context, report *models.Report) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, report, report.ID, reportOwner, r.userID)
+}
+
+func (r *scopedReports) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, reportOwner, r.userID)
+}
+
+// scopedUsers only gives access to the user's own record
+type scopedUsers struct {
+	repo   UserRepository
+	userID string
+}
+
+func (r *scopedUsers) Create(ctx context.Context, user *models.User) error {
+	if err := checkOwner(user.ID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, user)
+}
+
+func (r *scopedUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	user, err := r.repo.GetByID(ctx, id)
+	return owned(user, err, userOwner, r.userID)
+}
+
+func (r *scopedUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.repo.GetByEmail(ctx, email)
+	return owned(user, err, userOwner, r.userID)
+}
+
+func (r *scopedUsers) Update(ctx context.Context, user *models.User) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, user, user.ID, userOwner, r.userID)
+}
+
+func (r *scopedUsers) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, userOwner, r.userID)
+}
+
+type scopedGroupings struct {
+	repo   GroupingRepository
+	userID string
+}
+
+func (r *scopedGroupings) Create(ctx context.Context, grouping *models.Group) error {
+	if err := checkOwner(grouping.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, grouping)
+}
+
+func (r *scopedGroupings) GetByID(ctx context.Context, id string) (*models.Group, error) {
+	grouping, err := r.repo.GetByID(ctx, id)
+	return owned(grouping, err, groupingOwner, r.userID)
+}
+
+func (r *scopedGroupings) List(ctx context.Context) ([]*models.Group, error) {
+	groupings, err := r.repo.List(ctx)
+	return ownedList(groupings, err, groupingOwner, r.userID)
+}
+
+func (r *scopedGroupings) Update(ctx context.Context, grouping *models.Group) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, grouping, grouping.ID, groupingOwner, r.userID)
+}
+
+func (r *scopedGroupings) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, groupingOwner, r.userID)
+}
+
+type scopedReportGroupings struct {
+	repo    ReportGroupingRepository
+	reports ReportRepository
+	userID  string
+}
+
+func (r *scopedReportGroupings) Create(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	if err := ownedReport(ctx, r.reports, reportGrouping.ReportID, r.userID); err != nil {
+		return forbidMissing(err)
+	}
+	return r.repo.Create(ctx, reportGrouping)
+}
+
+func (r *scopedReportGroupings) GetByID(ctx context.Context, id string) (*models.ReportGrouping, error) {
+	reportGrouping, err := r.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ownedReport(ctx, r.reports, reportGrouping.ReportID, r.userID); err != nil {
+		return nil, err
+	}
+	return reportGrouping, nil
+}
+
+func (r *scopedReportGroupings) ListByReportID(ctx context.Context, reportID string) ([]*models.ReportGrouping, error) {
+	if err := ownedReport(ctx, r.reports, reportID, r.userID); err != nil {
+		return nil, err
+	}
+	return r.repo.ListByReportID(ctx, reportID)
+}
+
+func (r *scopedReportGroupings) Update(ctx context.Context, reportGrouping *models.ReportGrouping) error {
+	if err := ownedReport(ctx, r.reports, reportGrouping.ReportID, r.userID); err != nil {
+		return forbidMissing(err)
+	}
+	if _, err := r.GetByID(ctx, reportGrouping.ID); err != nil {
+		return err
+	}
+	return r.repo.Update(ctx, reportGrouping)
+}
+
+func (r *scopedReportGroupings) Delete(ctx context.Context, id string) error {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return r.repo.Delete(ctx, id)
+}
+
+type scopedReportDiffs struct {
+	repo    ReportDiffRepository
+	reports ReportRepository
+	userID  string
+}
+
+func (r *scopedReportDiffs) Create(ctx context.Context, diff *models.ReportDiff) error {
+	if err := checkOwner(diff.UserID, r.userID); err != nil {
+		return err
+	}
+	for _, reportID := range []string{diff.BaseReportID, diff.CompareReportID} {
+		if err := ownedReport(ctx, r.reports, reportID, r.userID); err != nil {
+			return forbidMissing(err)
+		}
+	}
+	return r.repo.Create(ctx, diff)
+}
+
+func (r *scopedReportDiffs) GetByID(ctx context.Context, id string) (*models.ReportDiff, error) {
+	diff, err := r.repo.GetByID(ctx, id)
+	return owned(diff, err, reportDiffOwner, r.userID)
+}
+
+func (r *scopedReportDiffs) List(ctx context.Context, filters ReportDiffFilters) ([]*models.ReportDiff, error) {
+	filters.UserID = r.userID
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedReportDiffs) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, reportDiffOwner, r.userID)
+}
+
+func (r *scopedReportDiffs) CreateEntry(ctx context.Context, entry *models.ReportDiffEntry) error {
+	if _, err := r.GetByID(ctx, entry.ReportDiffID); err != nil {
+		return forbidMissing(err)
+	}
+	return r.repo.CreateEntry(ctx, entry)
+}
+
+func (r *scopedReportDiffs) ListEntries(ctx context.Context, diffID string) ([]*models.ReportDiffEntry, error) {
+	if _, err := r.GetByID(ctx, diffID); err != nil {
+		return nil, err
+	}
+	return r.repo.ListEntries(ctx, diffID)
+}
+
+type scopedGroupingRules struct {
+	repo   GroupingRuleRepository
+	userID string
+}
+
+func (r *scopedGroupingRules) Create(ctx context.Context, rule *models.GroupingRule) error {
+	if err := checkOwner(rule.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, rule)
+}
+
+func (r *scopedGroupingRules) GetByID(ctx context.Context, id string) (*models.GroupingRule, error) {
+	rule, err := r.repo.GetByID(ctx, id)
+	return owned(rule, err, groupingRuleOwner, r.userID)
+}
+
+func (r *scopedGroupingRules) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingRule, error) {
+	if userID != r.userID {
+		return nil, nil
+	}
+	return r.repo.ListByUserID(ctx, userID)
+}
+
+func (r *scopedGroupingRules) Update(ctx context.Context, rule *models.GroupingRule) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, rule, rule.ID, groupingRuleOwner, r.userID)
+}
+
+func (r *scopedGroupingRules) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, groupingRuleOwner, r.userID)
+}
+
+type scopedGroupingOverrides struct {
+	repo   GroupingOverrideRepository
+	userID string
+}
+
+func (r *scopedGroupingOverrides) Create(ctx context.Context, override *models.GroupingOverride) error {
+	if err := checkOwner(override.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, override)
+}
+
+func (r *scopedGroupingOverrides) GetByURL(ctx context.Context, userID, url string) (*models.GroupingOverride, error) {
+	if userID != r.userID {
+		return nil, ErrNotFound
+	}
+	return r.repo.GetByURL(ctx, userID, url)
+}
+
+func (r *scopedGroupingOverrides) ListByUserID(ctx context.Context, userID string) ([]*models.GroupingOverride, error) {
+	if userID != r.userID {
+		return nil, nil
+	}
+	return r.repo.ListByUserID(ctx, userID)
+}
+
+// Update and Delete find overrides through the user's list, since overrides
+// can't be looked up by ID
+func (r *scopedGroupingOverrides) Update(ctx context.Context, override *models.GroupingOverride) error {
+	if err := checkOwner(override.UserID, r.userID); err != nil {
+		return err
+	}
+	if !r.hasOverride(ctx, override.ID) {
+		return ErrNotFound
+	}
+	return r.repo.Update(ctx, override)
+}
+
+func (r *scopedGroupingOverrides) Delete(ctx context.Context, id string) error {
+	if !r.hasOverride(ctx, id) {
+		return nil
+	}
+	return r.repo.Delete(ctx, id)
+}
+
+func (r *scopedGroupingOverrides) hasOverride(ctx context.Context, id string) bool {
+	overrides, err := r.repo.ListByUserID(ctx, r.userID)
+	return err == nil && slices.ContainsFunc(overrides, func(o *models.GroupingOverride) bool { return o.ID == id })
+}
+
+type scopedReportJobs struct {
+	repo   ReportJobRepository
+	userID string
+}
+
+func (r *scopedReportJobs) Create(ctx context.Context, job *models.ReportJob) error {
+	if err := checkOwner(job.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, job)
+}
+
+func (r *scopedReportJobs) GetByID(ctx context.Context, id string) (*models.ReportJob, error) {
+	job, err := r.repo.GetByID(ctx, id)
+	return owned(job, err, reportJobOwner, r.userID)
+}
+
+func (r *scopedReportJobs) List(ctx context.Context, filters JobFilters) ([]*models.ReportJob, error) {
+	filters.UserID = r.userID
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedReportJobs) Update(ctx context.Context, job *models.ReportJob) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, job, job.ID, reportJobOwner, r.userID)
+}
+
+func (r *scopedReportJobs) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, reportJobOwner, r.userID)
+}
+
+func (r *scopedReportJobs) Finish(ctx context.Context, job *models.ReportJob) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Finish, job, job.ID, reportJobOwner, r.userID)
+}
+
+func (r *scopedReportJobs) Claim(ctx context.Context, id string, startedAt time.Time) (*models.ReportJob, error) {
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.repo.Claim(ctx, id, startedAt)
+}
+
+type scopedReportSchedules struct {
+	repo   ReportScheduleRepository
+	userID string
+}
+
+func (r *scopedReportSchedules) Create(ctx context.Context, schedule *models.ReportSchedule) error {
+	if err := checkOwner(schedule.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, schedule)
+}
+
+func (r *scopedReportSchedules) GetByID(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	schedule, err := r.repo.GetByID(ctx, id)
+	return owned(schedule, err, reportScheduleOwner, r.userID)
+}
+
+func (r *scopedReportSchedules) List(ctx context.Context, filters ScheduleFilters) ([]*models.ReportSchedule, error) {
+	filters.UserID = r.userID
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedReportSchedules) ListDue(ctx context.Context, before time.Time) ([]*models.ReportSchedule, error) {
+	schedules, err := r.repo.ListDue(ctx, before)
+	return ownedList(schedules, err, reportScheduleOwner, r.userID)
+}
+
+func (r *scopedReportSchedules) Update(ctx context.Context, schedule *models.ReportSchedule) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, schedule, schedule.ID, reportScheduleOwner, r.userID)
+}
+
+func (r *scopedReportSchedules) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, reportScheduleOwner, r.userID)
+}
+
+type scopedReleases struct {
+	repo   ReleaseRepository
+	userID string
+}
+
+func (r *scopedReleases) Create(ctx context.Context, release *models.Release) error {
+	if err := checkOwner(release.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, release)
+}
+
+func (r *scopedReleases) GetByID(ctx context.Context, id string) (*models.Release, error) {
+	release, err := r.repo.GetByID(ctx, id)
+	return owned(release, err, releaseOwner, r.userID)
+}
+
+func (r *scopedReleases) List(ctx context.Context, filters ReleaseFilters) ([]*models.Release, error) {
+	filters.UserID = r.userID
+	return r.repo.List(ctx, filters)
+}
+
+func (r *scopedReleases) Update(ctx context.Context, release *models.Release) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, release, release.ID, releaseOwner, r.userID)
+}
+
+func (r *scopedReleases) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, releaseOwner, r.userID)
+}
+
+type scopedAPITokens struct {
+	repo   APITokenRepository
+	userID string
+}
+
+func (r *scopedAPITokens) Create(ctx context.Context, token *models.APIToken) error {
+	if err := checkOwner(token.UserID, r.userID); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, token)
+}
+
+func (r *scopedAPITokens) GetByID(ctx context.Context, id string) (*models.APIToken, error) {
+	token, err := r.repo.GetByID(ctx, id)
+	return owned(token, err, apiTokenOwner, r.userID)
+}
+
+func (r *scopedAPITokens) GetByHash(ctx context.Context, tokenHash string) (*models.APIToken, error) {
+	token, err := r.repo.GetByHash(ctx, tokenHash)
+	return owned(token, err, apiTokenOwner, r.userID)
+}
+
+func (r *scopedAPITokens) ListByUserID(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	if userID != r.userID {
+		return nil, nil
+	}
+	return r.repo.ListByUserID(ctx, userID)
+}
+
+func (r *scopedAPITokens) Update(ctx context.Context, token *models.APIToken) error {
+	return updateOwned(ctx, r.repo.GetByID, r.repo.Update, token, token.ID, apiTokenOwner, r.userID)
+}
+
+func (r *scopedAPITokens) Delete(ctx context.Context, id string) error {
+	return deleteOwned(ctx, r.repo.GetByID, r.repo.Delete, id, apiTokenOwner, r.userID)
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+// TokenPrefix starts every API token, so leaked tokens are easy to spot
+const TokenPrefix = "smp_"
+
+// tokenPrefixLength is how much of a token is stored in the clear, to tell
+// tokens apart in listings
+const tokenPrefixLength = 12
+
+// ErrInvalidToken is returned when a token is unknown, revoked or expired
+var ErrInvalidToken = errors.New("invalid API token")
+
+// ErrMissingScope is returned when a token lacks the scope a request needs
+var ErrMissingScope = errors.New("API token is missing a required scope")
+
+// AuthService manages users and the API tokens they authenticate with.
+// Tokens are random; only their SHA-256 hash is stored, so a token can't be
+// recovered from the database once it's issued.
+type AuthService struct {
+	db repositories.Database
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(db repositories.Database) *AuthService {
+	return &AuthService{db: db}
+}
+
+// CreateUser stores a new user, with a generated ID unless one is set
+func (s *AuthService) CreateUser(ctx context.Context, user *models.User) error {
+	if user.Email == "" {
+		return fmt.Errorf("user email is required")
+	}
+	if _, err := s.db.Users().GetByEmail(ctx, user.Email); err == nil {
+		return fmt.Errorf("a user with email %s already exists", user.Email)
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return err
+	}
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	return s.db.Users().Create(ctx, user)
+}
+
+// IssueToken creates a token for a user and returns it with the plaintext
+// token, which is never shown again. ttl of 0 issues a token that never
+// expires; no scopes issues a token with every scope.
+func (s *AuthService) IssueToken(ctx context.Context, userID, name string, scopes []string, ttl time.Duration) (*models.APIToken, string, error) {
+	if _, err := s.db.Users().GetByID(ctx, userID); err != nil {
+		return nil, "", fmt.Errorf("failed to find user %s: %w", userID, err)
+	}
+	if ttl < 0 {
+		return nil, "", fmt.Errorf("token lifetime must not be negative")
+	}
+	if len(scopes) == 0 {
+		scopes = []string{models.TokenScopeRead, models.TokenScopeWrite}
+	}
+	for _, scope := range scopes {
+		if scope != models.TokenScopeRead && scope != models.TokenScopeWrite {
+			return nil, "", fmt.Errorf("unknown token scope %q (want %s or %s)", scope, models.TokenScopeRead, models.TokenScopeWrite)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := TokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	token := &models.APIToken{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plaintext[:tokenPrefixLength],
+		TokenHash: HashToken(plaintext),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+	if err := s.db.APITokens().Create(ctx, token); err != nil {
+		return nil, "", err
+	}
+	return token, plaintext, nil
+}
+
+// RevokeToken stops a token from authenticating. Revoked tokens are kept so
+// listings show when they were revoked.
+func (s *AuthService) RevokeToken(ctx context.Context, id string) (*models.APIToken, error) {
+	token, err := s.db.APITokens().GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return token, nil
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	token.UpdatedAt = now
+	return token, s.db.APITokens().Update(ctx, token)
+}
+
+// ListTokens lists a user's tokens, oldest first
+func (s *AuthService) ListTokens(ctx context.Context, userID string) ([]*models.APIToken, error) {
+	return s.db.APITokens().ListByUserID(ctx, userID)
+}
+
+// Authenticate returns the user and token for a plaintext token. Unknown,
+// revoked and expired tokens all fail with ErrInvalidToken.
+func (s *AuthService) Authenticate(ctx context.Context, plaintext string) (*models.User, *models.APIToken, error) {
+	if !strings.HasPrefix(plaintext, TokenPrefix) {
+		return nil, nil, ErrInvalidToken
+	}
+	token, err := s.db.APITokens().GetByHash(ctx, HashToken(plaintext))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, nil, fmt.Errorf("%w: token was revoked", ErrInvalidToken)
+	}
+	if token.ExpiresAt != nil && !time.Now().Before(*token.ExpiresAt) {
+		return nil, nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+
+	user, err := s.db.Users().GetByID(ctx, token.UserID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: token's user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, token, nil
+}
+
+// HasScope reports whether a token grants a scope
+func HasScope(token *models.APIToken, scope string) bool {
+	return slices.Contains(token.Scopes, scope)
+}
+
+// HashToken returns the hex SHA-256 hash a token is stored under
+func HashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
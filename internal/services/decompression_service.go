@@ -1,2 +1,258 @@
 package services
 
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+// ErrDecompressedSizeExceeded is returned when a source expands beyond the configured limit
+var ErrDecompressedSizeExceeded = errors.New("decompressed size exceeds limit")
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// DecompressedSource is a single sitemap document extracted from a source
+type DecompressedSource struct {
+	Name   string
+	Reader io.Reader
+	closer io.Closer
+}
+
+// Close releases any decompressor held by the source
+func (s *DecompressedSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// DecompressionService detects and expands compressed sitemap sources
+type DecompressionService struct {
+	maxSize int64
+}
+
+// NewDecompressionService creates a new decompression service. maxSize caps the
+// total decompressed bytes read from a single source (0 = unlimited).
+func NewDecompressionService(maxSize int64) *DecompressionService {
+	return &DecompressionService{maxSize: maxSize}
+}
+
+// DetectCompression determines the compression format of a source. Magic bytes
+// in header take precedence; the Content-Encoding header and file name are only
+// consulted when the header does not already look like plain XML. Returns nil
+// for uncompressed sources.
+func (s *DecompressionService) DetectCompression(header []byte, contentEncoding, name string) *models.CompressionFormat {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return compressionFormatPtr(models.CompressionFormatGzip)
+	case bytes.HasPrefix(header, zipMagic):
+		return compressionFormatPtr(models.CompressionFormatZip)
+	case looksLikeXML(header):
+		return nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "gzip", "x-gzip":
+		return compressionFormatPtr(models.CompressionFormatGzip)
+	}
+
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	switch path.Ext(name) {
+	case ".gz", ".gzip":
+		return compressionFormatPtr(models.CompressionFormatGzip)
+	case ".zip":
+		return compressionFormatPtr(models.CompressionFormatZip)
+	}
+
+	return nil
+}
+
+// Open detects the compression of r and returns one source per contained
+// sitemap document. Zip archives expand to one source per file; gzip and
+// uncompressed input yield a single source. The caller must close every
+// returned source and remains responsible for closing r.
+func (s *DecompressionService) Open(r io.Reader, contentEncoding, name string) (*models.CompressionFormat, []*DecompressedSource, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(512)
+	if err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("failed to read source: %w", err)
+	}
+
+	format := s.DetectCompression(header, contentEncoding, name)
+	if format == nil {
+		return nil, []*DecompressedSource{{Name: name, Reader: s.limit(br, new(int64))}}, nil
+	}
+
+	switch *format {
+	case models.CompressionFormatGzip:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return format, nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		source := &DecompressedSource{
+			Name:   trimGzipExt(name),
+			Reader: s.limit(gz, new(int64)),
+			closer: gz,
+		}
+		return format, []*DecompressedSource{source}, nil
+	case models.CompressionFormatZip:
+		sources, err := s.openZip(br, name)
+		return format, sources, err
+	default:
+		return format, nil, fmt.Errorf("unsupported compression format: %s", *format)
+	}
+}
+
+// OpenOne opens r as a single sitemap document. It fails if the source is an
+// archive containing more than one file. The caller must close the returned
+// source and remains responsible for closing r.
+func (s *DecompressionService) OpenOne(r io.Reader, contentEncoding, name string) (*DecompressedSource, error) {
+	_, sources, err := s.Open(r, contentEncoding, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sources) != 1 {
+		closeSources(sources)
+		return nil, fmt.Errorf("expected a single sitemap in %s, found %d", name, len(sources))
+	}
+	return sources[0], nil
+}
+
+// openZip buffers the archive (zip needs random access) and returns a source
+// for every regular file in it. Nested .gz files are decompressed as well.
+func (s *DecompressionService) openZip(r io.Reader, name string) ([]*DecompressedSource, error) {
+	read := new(int64)
+	data, err := io.ReadAll(s.limit(r, read))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	// Reject archives whose declared size is already over the limit
+	var declared uint64
+	for _, file := range archive.File {
+		declared += file.UncompressedSize64
+	}
+	if s.maxSize > 0 && declared > uint64(s.maxSize) {
+		return nil, fmt.Errorf("zip archive declares %d bytes: %w", declared, ErrDecompressedSizeExceeded)
+	}
+
+	// Every file shares one budget so many small members cannot add up past the limit
+	expanded := new(int64)
+	var sources []*DecompressedSource
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			closeSources(sources)
+			return nil, fmt.Errorf("failed to open %s in zip archive: %w", file.Name, err)
+		}
+
+		source := &DecompressedSource{
+			Name:   name + "!/" + file.Name,
+			Reader: s.limit(rc, expanded),
+			closer: rc,
+		}
+
+		if strings.HasSuffix(strings.ToLower(file.Name), ".gz") {
+			gz, err := gzip.NewReader(rc)
+			if err != nil {
+				rc.Close()
+				closeSources(sources)
+				return nil, fmt.Errorf("failed to open gzip stream %s in zip archive: %w", file.Name, err)
+			}
+			source.Name = trimGzipExt(source.Name)
+			source.Reader = s.limit(gz, expanded)
+			source.closer = multiCloser{gz, rc}
+		}
+
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("zip archive %s contains no files", name)
+	}
+
+	return sources, nil
+}
+
+// limit wraps r so that reads fail once the shared counter passes maxSize
+func (s *DecompressionService) limit(r io.Reader, read *int64) io.Reader {
+	if s.maxSize <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, read: read, max: s.maxSize}
+}
+
+type limitedReader struct {
+	r    io.Reader
+	read *int64
+	max  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	*l.read += int64(n)
+	if *l.read > l.max {
+		return n, fmt.Errorf("read more than %d bytes: %w", l.max, ErrDecompressedSizeExceeded)
+	}
+	return n, err
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var errs []error
+	for _, c := range m {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func closeSources(sources []*DecompressedSource) {
+	for _, source := range sources {
+		source.Close()
+	}
+}
+
+// trimGzipExt strips a trailing .gz extension so sitemap.xml.gz is reported as sitemap.xml
+func trimGzipExt(name string) string {
+	ext := path.Ext(name)
+	if strings.EqualFold(ext, ".gz") || strings.EqualFold(ext, ".gzip") {
+		return strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+func looksLikeXML(header []byte) bool {
+	trimmed := bytes.TrimLeft(header, " \t\r\n\xef\xbb\xbf")
+	return len(trimmed) > 0 && trimmed[0] == '<'
+}
+
+func compressionFormatPtr(f models.CompressionFormat) *models.CompressionFormat {
+	return &f
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+const testSitemap = `<?xml version="1.0" encoding="UTF-8"?><urlset><url><loc>https://example.com/</loc></url></urlset>`
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zipped returns a zip archive of files, given as alternating names and contents
+func zipped(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		f, err := w.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectCompression(t *testing.T) {
+	gz := gzipped(t, testSitemap)
+	zp := zipped(t, "sitemap.xml", testSitemap)
+
+	tests := []struct {
+		name            string
+		header          []byte
+		contentEncoding string
+		fileName        string
+		want            models.CompressionFormat // "" for uncompressed
+	}{
+		{"gzip magic", gz, "", "sitemap.xml", models.CompressionFormatGzip},
+		{"zip magic", zp, "", "sitemap.xml", models.CompressionFormatZip},
+		// Magic bytes win over a misleading name or header
+		{"gzip magic named zip", gz, "", "sitemap.zip", models.CompressionFormatGzip},
+		{"zip magic with gzip encoding", zp, "gzip", "sitemap.xml.gz", models.CompressionFormatZip},
+		// Plain XML is never treated as compressed, whatever it's called
+		{"xml named gz", []byte(testSitemap), "gzip", "sitemap.xml.gz", ""},
+		{"xml after a byte order mark", []byte("\xef\xbb\xbf\n  " + testSitemap), "", "sitemap.zip", ""},
+		// Unrecognised bytes fall back to the header, then the name
+		{"content encoding", []byte("??"), "X-GZIP", "sitemap", models.CompressionFormatGzip},
+		{"gz extension", []byte("??"), "", "https://example.com/sitemap.xml.GZ?v=2", models.CompressionFormatGzip},
+		{"zip extension", []byte("??"), "", "sitemaps.zip#x", models.CompressionFormatZip},
+		{"unknown", []byte("??"), "br", "sitemap.txt", ""},
+	}
+
+	service := NewDecompressionService(0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatOf(service.DetectCompression(tt.header, tt.contentEncoding, tt.fileName)); got != tt.want {
+				t.Errorf("DetectCompression = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		source string
+		format models.CompressionFormat
+		names  []string
+	}{
+		{"plain", []byte(testSitemap), "sitemap.xml", "", []string{"sitemap.xml"}},
+		{"gzip", gzipped(t, testSitemap), "sitemap.xml.gz", models.CompressionFormatGzip, []string{"sitemap.xml"}},
+		{
+			name:   "multi-file zip",
+			data:   zipped(t, "a.xml", testSitemap, "dir/", "", "b.xml.gz", string(gzipped(t, testSitemap))),
+			source: "sitemaps.zip",
+			format: models.CompressionFormatZip,
+			// Directories are skipped and nested gzip files expanded
+			names: []string{"sitemaps.zip!/a.xml", "sitemaps.zip!/b.xml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, sources, err := NewDecompressionService(0).Open(bytes.NewReader(tt.data), "", tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeSources(sources)
+
+			if got := formatOf(format); got != tt.format {
+				t.Errorf("format = %q, want %q", got, tt.format)
+			}
+			if len(sources) != len(tt.names) {
+				t.Fatalf("Open returned %d sources, want %d", len(sources), len(tt.names))
+			}
+			for i, source := range sources {
+				if source.Name != tt.names[i] {
+					t.Errorf("source %d is named %q, want %q", i, source.Name, tt.names[i])
+				}
+				data, err := io.ReadAll(source.Reader)
+				if err != nil || string(data) != testSitemap {
+					t.Errorf("source %s = %q, %v; want the sitemap", source.Name, data, err)
+				}
+			}
+		})
+	}
+
+	if _, _, err := NewDecompressionService(0).Open(bytes.NewReader(zipped(t, "dir/", "")), "", "empty.zip"); err == nil {
+		t.Error("Open of a zip without files succeeded")
+	}
+	if _, err := NewDecompressionService(0).OpenOne(bytes.NewReader(zipped(t, "a.xml", testSitemap, "b.xml", testSitemap)), "", "sitemaps.zip"); err == nil {
+		t.Error("OpenOne of a zip with two files succeeded")
+	}
+}
+
+func TestOpenSizeLimit(t *testing.T) {
+	// Highly compressible, so only the decompressed size is over the limit
+	large := "<urlset>" + strings.Repeat("<url><loc>https://example.com/</loc></url>", 1000) + "</urlset>"
+	const limit = 4096
+
+	tests := []struct {
+		name   string
+		data   []byte
+		source string
+	}{
+		{"plain", []byte(large), "sitemap.xml"},
+		{"gzip", gzipped(t, large), "sitemap.xml.gz"},
+		{"zip", zipped(t, "sitemap.xml", large), "sitemaps.zip"},
+		// Files that are each under the limit share one budget
+		{"zip members", zipped(t, "a.xml", large[:limit-100], "b.xml", large[:limit-100]), "sitemaps.zip"},
+		{"gzip in zip", zipped(t, "sitemap.xml.gz", string(gzipped(t, large))), "sitemaps.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sources, err := NewDecompressionService(limit).Open(bytes.NewReader(tt.data), "", tt.source)
+			if err == nil {
+				defer closeSources(sources)
+				for _, source := range sources {
+					if _, err = io.ReadAll(source.Reader); err != nil {
+						break
+					}
+				}
+			}
+			if !errors.Is(err, ErrDecompressedSizeExceeded) {
+				t.Errorf("reading %s = %v, want ErrDecompressedSizeExceeded", tt.name, err)
+			}
+		})
+	}
+
+	// Sources under the limit are read in full
+	_, sources, err := NewDecompressionService(limit).Open(bytes.NewReader(gzipped(t, testSitemap)), "", "sitemap.xml.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSources(sources)
+	if data, err := io.ReadAll(sources[0].Reader); err != nil || string(data) != testSitemap {
+		t.Errorf("reading a small source = %q, %v", data, err)
+	}
+}
+
+func formatOf(format *models.CompressionFormat) models.CompressionFormat {
+	if format == nil {
+		return ""
+	}
+	return *format
+}
@@ -2,19 +2,42 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"jonopens/sitemapper/internal/models"
 	"jonopens/sitemapper/internal/repositories"
 )
 
+// DefaultGroupingDepth is the number of path segments used for automatic groups
+const DefaultGroupingDepth = 1
+
+var (
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// ErrReportSampled is returned when groupings are re-applied to or a diff is
+// computed from a sampled report, whose exact totals can't be recomputed from
+// the stored sample
+var ErrReportSampled = errors.New("report is sampled")
+
 // GroupingService handles grouping management
 type GroupingService struct {
-	db repositories.Database
+	// Depth is the number of directory segments that make up an automatic group
+	Depth int
+	db    repositories.Database
 }
 
 // NewGroupingService creates a new grouping service
 func NewGroupingService(db repositories.Database) *GroupingService {
-	return &GroupingService{db: db}
+	return &GroupingService{Depth: DefaultGroupingDepth, db: db}
 }
 
 // CreateGrouping creates a new grouping
@@ -32,9 +55,506 @@ func (s *GroupingService) ListGroupings(ctx context.Context) ([]*models.Group, e
 	return s.db.Groupings().List(ctx)
 }
 
-// GroupURLs automatically groups URLs based on path segments
+// GroupURLs automatically groups URLs by path pattern (see PathPattern).
+// URLs that cannot be parsed are left out.
 func (s *GroupingService) GroupURLs(ctx context.Context, urls []string) (map[string][]string, error) {
-	// TODO: Implement URL grouping logic
-	return nil, nil
+	groups := make(map[string][]string)
+	for _, u := range urls {
+		pattern := PathPattern(u, s.Depth)
+		if pattern == "" {
+			continue
+		}
+		groups[pattern] = append(groups[pattern], u)
+	}
+	return groups, nil
+}
+
+// PathPattern returns the automatic group of a URL: its first depth directory
+// segments, with numeric and UUID segments collapsed to "*" and anything
+// deeper replaced by a trailing "/*". With depth 1, /blog/post-1 becomes
+// /blog/*; with depth 3, /products/42/reviews/ becomes /products/*/reviews.
+// Top-level pages belong to "/". Returns "" if rawURL cannot be parsed.
+func PathPattern(rawURL string, depth int) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if depth <= 0 {
+		depth = DefaultGroupingDepth
+	}
+
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+
+	// The last segment is a page unless the path ends with a slash
+	dirs := len(segments) - 1
+	if strings.HasSuffix(u.Path, "/") {
+		dirs = len(segments)
+	}
+	if dirs > depth {
+		dirs = depth
+	}
+	if dirs == 0 {
+		return "/"
+	}
+
+	parts := make([]string, 0, dirs+1)
+	for _, segment := range segments[:dirs] {
+		if numericSegment.MatchString(segment) || uuidSegment.MatchString(segment) {
+			segment = "*"
+		}
+		parts = append(parts, segment)
+	}
+	if dirs < len(segments) {
+		parts = append(parts, "*")
+	}
+
+	return "/" + strings.Join(parts, "/")
 }
 
+// Sources of a grouping assignment, in order of precedence
+const (
+	AssignmentOverride  = "override"
+	AssignmentRule      = "rule"
+	AssignmentAutomatic = "automatic"
+)
+
+// GroupingEngine assigns the entries of a report to groups and accumulates a
+// ReportGrouping aggregate for every group. A URL is assigned by its override
+// if one exists, else by the highest-priority matching rule, else by its
+// automatic path pattern. Existing groups of the user with the same pattern
+// are reused.
+type GroupingEngine struct {
+	service    *GroupingService
+	userID     string
+	reportID   string
+	rules      []*compiledRule
+	overrides  map[string]string                 // group ID by URL
+	groups     map[string]*models.Group          // by name
+	names      map[string]string                 // group name by ID
+	aggregates map[string]*models.ReportGrouping // by group ID
+	order      []string
+	ungrouped  int
+}
+
+// NewEngine creates a grouping engine for a report owned by userID
+func (s *GroupingService) NewEngine(ctx context.Context, userID, reportID string) (*GroupingEngine, error) {
+	existing, err := s.db.Groupings().List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groupings: %w", err)
+	}
+
+	engine := &GroupingEngine{
+		service:    s,
+		userID:     userID,
+		reportID:   reportID,
+		groups:     make(map[string]*models.Group),
+		names:      make(map[string]string),
+		aggregates: make(map[string]*models.ReportGrouping),
+	}
+	for _, group := range existing {
+		if group.UserID == userID {
+			engine.groups[group.Name] = group
+			engine.names[group.ID] = group.Name
+		}
+	}
+
+	rules, err := s.ListRules(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid grouping rule %s: %w", rule.ID, err)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+
+	overrides, err := s.db.GroupingOverrides().ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grouping overrides: %w", err)
+	}
+	engine.overrides = make(map[string]string, len(overrides))
+	for _, override := range overrides {
+		engine.overrides[override.URL] = override.GroupingID
+	}
+
+	return engine, nil
+}
+
+// Classify returns the group a URL belongs to and how it was chosen. groupID
+// is empty when the URL falls into an automatic group that does not exist
+// yet, and name is empty when the URL cannot be grouped at all. ruleID is set
+// for rule matches.
+func (e *GroupingEngine) Classify(rawURL string) (groupID, name, source, ruleID string) {
+	if groupID, ok := e.overrides[rawURL]; ok {
+		return groupID, e.GroupName(groupID), AssignmentOverride, ""
+	}
+
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		for _, rule := range e.rules {
+			if rule.match(u, rawURL) {
+				return rule.GroupingID, e.GroupName(rule.GroupingID), AssignmentRule, rule.ID
+			}
+		}
+	}
+
+	pattern := PathPattern(rawURL, e.service.Depth)
+	if pattern == "" {
+		return "", "", "", ""
+	}
+	if group, ok := e.groups[pattern]; ok {
+		return group.ID, pattern, AssignmentAutomatic, ""
+	}
+	return "", pattern, AssignmentAutomatic, ""
+}
+
+// Assign sets the GroupingID of a URL entry, creating its automatic group if
+// needed, and adds the entry to the group totals. Liveness results, if any,
+// must already be set. Entries whose URL cannot be parsed stay ungrouped.
+func (e *GroupingEngine) Assign(ctx context.Context, entry *models.Entry) error {
+	entry.GroupingID = nil
+
+	groupID, name, _, _ := e.Classify(entry.URL)
+	if name == "" && groupID == "" {
+		e.ungrouped++
+		return nil
+	}
+
+	if groupID == "" {
+		description := "Automatic path grouping"
+		group := &models.Group{
+			ID:          uuid.New().String(),
+			UserID:      e.userID,
+			Name:        name,
+			Description: &description,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if err := e.service.db.Groupings().Create(ctx, group); err != nil {
+			return fmt.Errorf("failed to create grouping %s: %w", name, err)
+		}
+		e.groups[name] = group
+		e.names[group.ID] = group.Name
+		groupID = group.ID
+	}
+
+	entry.GroupingID = &groupID
+
+	aggregate, ok := e.aggregates[groupID]
+	if !ok {
+		aggregate = &models.ReportGrouping{
+			ID:         uuid.New().String(),
+			ReportID:   e.reportID,
+			GroupingID: groupID,
+		}
+		e.aggregates[groupID] = aggregate
+		e.order = append(e.order, groupID)
+	}
+
+	aggregate.TotalEntryCount++
+	if entry.IsValid {
+		aggregate.ValidEntryCount++
+	} else {
+		aggregate.InvalidEntryCount++
+	}
+	if entry.IsLive != nil {
+		if *entry.IsLive {
+			aggregate.LiveEntryCount++
+		} else {
+			aggregate.DownEntryCount++
+		}
+	}
+	if aggregate.MinURL == nil || entry.URL < *aggregate.MinURL {
+		minURL := entry.URL
+		aggregate.MinURL = &minURL
+	}
+	if aggregate.MaxURL == nil || entry.URL > *aggregate.MaxURL {
+		maxURL := entry.URL
+		aggregate.MaxURL = &maxURL
+	}
+
+	return nil
+}
+
+// Stored records that an assigned entry was written to the database
+func (e *GroupingEngine) Stored(entry *models.Entry) {
+	if entry.GroupingID == nil {
+		return
+	}
+	if aggregate, ok := e.aggregates[*entry.GroupingID]; ok {
+		aggregate.StoredEntryCount++
+	}
+}
+
+// GroupCount returns the number of groups entries were assigned to
+func (e *GroupingEngine) GroupCount() int {
+	return len(e.aggregates)
+}
+
+// UngroupedCount returns the number of entries that could not be grouped
+func (e *GroupingEngine) UngroupedCount() int {
+	return e.ungrouped
+}
+
+// GroupName returns the name of a group created or reused by the engine
+func (e *GroupingEngine) GroupName(groupID string) string {
+	if name, ok := e.names[groupID]; ok {
+		return name
+	}
+	return groupID
+}
+
+// Save writes a ReportGrouping for every group and returns them ordered by
+// group name
+func (e *GroupingEngine) Save(ctx context.Context) ([]*models.ReportGrouping, error) {
+	aggregates := make([]*models.ReportGrouping, 0, len(e.order))
+	for _, groupID := range e.order {
+		aggregate := e.aggregates[groupID]
+		aggregate.CreatedAt = time.Now()
+		aggregate.UpdatedAt = time.Now()
+		if err := e.service.db.ReportGroupings().Create(ctx, aggregate); err != nil {
+			return nil, fmt.Errorf("failed to create report grouping: %w", err)
+		}
+		aggregates = append(aggregates, aggregate)
+	}
+
+	sort.Slice(aggregates, func(i, j int) bool {
+		return e.GroupName(aggregates[i].GroupingID) < e.GroupName(aggregates[j].GroupingID)
+	})
+	return aggregates, nil
+}
+
+
+// ApplyToReport re-assigns every stored URL entry of a report using the
+// current overrides, rules and automatic grouping, and replaces the report's
+// ReportGrouping aggregates. Sampled reports are refused with
+// ErrReportSampled, since only their stored entries could be re-counted.
+func (s *GroupingService) ApplyToReport(ctx context.Context, report *models.Report) ([]*models.ReportGrouping, error) {
+	if !report.IsFullyStored {
+		return nil, fmt.Errorf("%w: its group totals count every URL, but only %d of %d are stored",
+			ErrReportSampled, report.StoredEntryCount, report.EntryCount)
+	}
+
+	engine, err := s.NewEngine(ctx, report.UserID, report.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.db.Entries().List(ctx, repositories.EntryFilters{ReportID: report.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	otherEntries := 0
+	for _, entry := range entries {
+		if entry.Type != models.EntryTypeURL {
+			otherEntries++
+			continue
+		}
+
+		if err := engine.Assign(ctx, entry); err != nil {
+			return nil, err
+		}
+		engine.Stored(entry)
+		entry.UpdatedAt = time.Now()
+		if err := s.db.Entries().Update(ctx, entry); err != nil {
+			return nil, fmt.Errorf("failed to update entry: %w", err)
+		}
+	}
+
+	// Replace the previous aggregates
+	previous, err := s.db.ReportGroupings().ListByReportID(ctx, report.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report groupings: %w", err)
+	}
+	for _, rg := range previous {
+		if err := s.db.ReportGroupings().Delete(ctx, rg.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete report grouping: %w", err)
+		}
+	}
+
+	aggregates, err := engine.Save(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report.GroupingCount = engine.GroupCount()
+	report.UngroupedCount = engine.UngroupedCount() + otherEntries
+	report.UpdatedAt = time.Now()
+	if err := s.db.Reports().Update(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+
+	return aggregates, nil
+}
+
+// AddRule validates and stores a grouping rule
+func (s *GroupingService) AddRule(ctx context.Context, rule *models.GroupingRule) error {
+	if _, err := compileRule(rule); err != nil {
+		return err
+	}
+	if _, err := s.userGrouping(ctx, rule.UserID, rule.GroupingID); err != nil {
+		return err
+	}
+	return s.db.GroupingRules().Create(ctx, rule)
+}
+
+// ListRules returns the rules of a user in evaluation order: highest priority
+// first, then oldest first
+func (s *GroupingService) ListRules(ctx context.Context, userID string) ([]*models.GroupingRule, error) {
+	rules, err := s.db.GroupingRules().ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grouping rules: %w", err)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+	return rules, nil
+}
+
+// SetOverride pins a URL to a grouping, replacing any existing override
+func (s *GroupingService) SetOverride(ctx context.Context, userID, rawURL, groupingID string) (*models.GroupingOverride, error) {
+	if _, err := s.userGrouping(ctx, userID, groupingID); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.db.GroupingOverrides().GetByURL(ctx, userID, rawURL); err == nil && existing != nil {
+		existing.GroupingID = groupingID
+		existing.UpdatedAt = time.Now()
+		return existing, s.db.GroupingOverrides().Update(ctx, existing)
+	}
+
+	override := &models.GroupingOverride{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		URL:        rawURL,
+		GroupingID: groupingID,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	return override, s.db.GroupingOverrides().Create(ctx, override)
+}
+
+// userGrouping returns a grouping owned by userID; other users' groupings
+// are reported as not found
+func (s *GroupingService) userGrouping(ctx context.Context, userID, groupingID string) (*models.Group, error) {
+	grouping, err := s.db.Groupings().GetByID(ctx, groupingID)
+	if err == nil && grouping.UserID != userID {
+		err = repositories.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("grouping %s not found: %w", groupingID, err)
+	}
+	return grouping, nil
+}
+
+// compiledRule is a grouping rule ready for matching
+type compiledRule struct {
+	*models.GroupingRule
+	match func(u *url.URL, raw string) bool
+}
+
+// compileRule validates a rule and builds its matcher
+func compileRule(rule *models.GroupingRule) (*compiledRule, error) {
+	pattern := strings.TrimSpace(rule.Pattern)
+	if pattern == "" {
+		return nil, fmt.Errorf("rule pattern is required")
+	}
+
+	compiled := &compiledRule{GroupingRule: rule}
+
+	switch rule.Type {
+	case models.GroupingRuleTypeGlob:
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		// Patterns with a scheme match the full URL, all others the path
+		fullURL := strings.Contains(pattern, "://")
+		compiled.match = func(u *url.URL, raw string) bool {
+			if fullURL {
+				return re.MatchString(raw)
+			}
+			path := u.Path
+			if path == "" {
+				path = "/"
+			}
+			return re.MatchString(path)
+		}
+	case models.GroupingRuleTypeRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		compiled.match = func(u *url.URL, raw string) bool {
+			return re.MatchString(raw)
+		}
+	case models.GroupingRuleTypeHost:
+		host := strings.ToLower(pattern)
+		compiled.match = func(u *url.URL, raw string) bool {
+			hostname := strings.ToLower(u.Hostname())
+			if suffix, ok := strings.CutPrefix(host, "*."); ok {
+				return strings.HasSuffix(hostname, "."+suffix)
+			}
+			return hostname == host
+		}
+	case models.GroupingRuleTypeQueryParam:
+		name, value, hasValue := strings.Cut(pattern, "=")
+		compiled.match = func(u *url.URL, raw string) bool {
+			values, ok := u.Query()[name]
+			if !ok {
+				return false
+			}
+			if !hasValue {
+				return true
+			}
+			for _, v := range values {
+				if v == value {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule type %q (want glob, regex, host or query_param)", rule.Type)
+	}
+
+	return compiled, nil
+}
+
+// globToRegexp converts a glob to an anchored regular expression. "*" and "?"
+// do not cross "/" boundaries; "**" matches across them.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
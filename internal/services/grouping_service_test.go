@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/database/memory"
+	"jonopens/sitemapper/internal/models"
+)
+
+func TestPathPattern(t *testing.T) {
+	tests := []struct {
+		url   string
+		depth int
+		want  string
+	}{
+		{"https://example.com/blog/post-1", 1, "/blog/*"},
+		{"https://example.com/blog/2024/post", 1, "/blog/*"},
+		{"https://example.com/blog/", 1, "/blog"},
+		{"https://example.com/about", 1, "/"},
+		{"https://example.com/", 1, "/"},
+		{"https://example.com", 1, "/"},
+		{"/blog/post", 1, "/blog/*"},
+		{"https://example.com/a//b", 1, "/a/*"},
+		// Numeric and UUID directories are collapsed
+		{"https://example.com/2024/post", 1, "/*/*"},
+		{"https://example.com/products/42/reviews/", 3, "/products/*/reviews"},
+		{"https://example.com/users/550e8400-e29b-41d4-a716-446655440000/posts/1", 2, "/users/*/*"},
+		{"https://example.com/v2/docs/", 2, "/v2/docs"},
+		// Depth 0 uses DefaultGroupingDepth
+		{"https://example.com/blog/a/b", 0, "/blog/*"},
+		{"://bad", 1, ""},
+	}
+
+	for _, tt := range tests {
+		if got := PathPattern(tt.url, tt.depth); got != tt.want {
+			t.Errorf("PathPattern(%q, %d) = %q, want %q", tt.url, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRule(t *testing.T) {
+	tests := []struct {
+		ruleType models.GroupingRuleType
+		pattern  string
+		url      string
+		want     bool
+	}{
+		{models.GroupingRuleTypeGlob, "/blog/*", "https://example.com/blog/a", true},
+		{models.GroupingRuleTypeGlob, "/blog/*", "https://example.com/blog/a/b", false},
+		{models.GroupingRuleTypeGlob, "/blog/**", "https://example.com/blog/a/b", true},
+		{models.GroupingRuleTypeGlob, "/p?ge", "https://example.com/page", true},
+		{models.GroupingRuleTypeGlob, "/p?ge", "https://example.com/p/ge", false},
+		{models.GroupingRuleTypeGlob, "/a.b", "https://example.com/aXb", false},
+		{models.GroupingRuleTypeGlob, "/", "https://example.com", true},
+		{models.GroupingRuleTypeGlob, "https://shop.example.com/**", "https://shop.example.com/x", true},
+		{models.GroupingRuleTypeGlob, "https://shop.example.com/**", "https://example.com/x", false},
+		{models.GroupingRuleTypeRegex, `\.pdf$`, "https://example.com/files/a.pdf", true},
+		{models.GroupingRuleTypeRegex, `\.pdf$`, "https://example.com/files/a.pdf?x=1", false},
+		{models.GroupingRuleTypeHost, "Example.com", "https://EXAMPLE.com/a", true},
+		{models.GroupingRuleTypeHost, "example.com", "https://www.example.com/a", false},
+		{models.GroupingRuleTypeHost, "*.example.com", "https://a.b.example.com/", true},
+		{models.GroupingRuleTypeHost, "*.example.com", "https://example.com/", false},
+		{models.GroupingRuleTypeQueryParam, "ref", "https://example.com/?ref=", true},
+		{models.GroupingRuleTypeQueryParam, "ref", "https://example.com/?other=ref", false},
+		{models.GroupingRuleTypeQueryParam, "ref=ads", "https://example.com/?ref=mail&ref=ads", true},
+		{models.GroupingRuleTypeQueryParam, "ref=ads", "https://example.com/?ref=mail", false},
+	}
+
+	for _, tt := range tests {
+		rule, err := compileRule(&models.GroupingRule{Type: tt.ruleType, Pattern: tt.pattern})
+		if err != nil {
+			t.Errorf("compileRule(%s %q): %v", tt.ruleType, tt.pattern, err)
+			continue
+		}
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rule.match(u, tt.url); got != tt.want {
+			t.Errorf("%s %q matching %q = %t, want %t", tt.ruleType, tt.pattern, tt.url, got, tt.want)
+		}
+	}
+
+	for _, rule := range []*models.GroupingRule{
+		{Type: models.GroupingRuleTypeGlob, Pattern: "  "},
+		{Type: models.GroupingRuleTypeRegex, Pattern: "("},
+		{Type: "prefix", Pattern: "/blog"},
+	} {
+		if _, err := compileRule(rule); err == nil {
+			t.Errorf("compileRule(%s %q) succeeded, want an error", rule.Type, rule.Pattern)
+		}
+	}
+}
+
+func TestGroupingEngine(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	service := NewGroupingService(db)
+	service.Depth = 1
+
+	now := time.Now()
+	groups := map[string]*models.Group{}
+	for _, g := range []struct{ id, userID, name string }{
+		{"blog", "user-1", "Blog"},
+		{"campaigns", "user-1", "Campaigns"},
+		{"docs", "user-1", "Docs"},
+		{"news", "user-1", "/news/*"},
+		// Another user's automatic group is never reused
+		{"other-shop", "user-2", "/shop/*"},
+	} {
+		group := &models.Group{ID: g.id, UserID: g.userID, Name: g.name, CreatedAt: now, UpdatedAt: now}
+		if err := db.Groupings().Create(ctx, group); err != nil {
+			t.Fatal(err)
+		}
+		groups[g.id] = group
+	}
+
+	for i, rule := range []*models.GroupingRule{
+		{ID: "blog-rule", UserID: "user-1", GroupingID: "blog", Type: models.GroupingRuleTypeGlob, Pattern: "/blog/**", Priority: 10},
+		{ID: "campaign-rule", UserID: "user-1", GroupingID: "campaigns", Type: models.GroupingRuleTypeQueryParam, Pattern: "utm_campaign", Priority: 20},
+		{ID: "docs-rule", UserID: "user-1", GroupingID: "docs", Type: models.GroupingRuleTypeHost, Pattern: "docs.example.com", Priority: 5},
+		// Equal priority: the older rule wins
+		{ID: "late-blog-rule", UserID: "user-1", GroupingID: "docs", Type: models.GroupingRuleTypeGlob, Pattern: "/blog/**", Priority: 10},
+	} {
+		rule.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		if err := service.AddRule(ctx, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.SetOverride(ctx, "user-1", "https://example.com/blog/launch", "campaigns"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rules and overrides can't point at another user's grouping
+	if err := service.AddRule(ctx, &models.GroupingRule{ID: "x", UserID: "user-1", GroupingID: "other-shop", Type: models.GroupingRuleTypeGlob, Pattern: "/**"}); err == nil {
+		t.Error("AddRule with another user's grouping succeeded")
+	}
+	if _, err := service.SetOverride(ctx, "user-1", "https://example.com/", "other-shop"); err == nil {
+		t.Error("SetOverride with another user's grouping succeeded")
+	}
+
+	if err := db.Reports().Create(ctx, &models.Report{ID: "report-1", UserID: "user-1", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	engine, err := service.NewEngine(ctx, "user-1", "report-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url     string
+		groupID string
+		name    string
+		source  string
+		ruleID  string
+	}{
+		{"https://example.com/blog/launch", "campaigns", "Campaigns", AssignmentOverride, ""},
+		{"https://example.com/blog/2024/post?utm_campaign=spring", "campaigns", "Campaigns", AssignmentRule, "campaign-rule"},
+		{"https://example.com/blog/2024/post", "blog", "Blog", AssignmentRule, "blog-rule"},
+		{"https://docs.example.com/guide", "docs", "Docs", AssignmentRule, "docs-rule"},
+		{"https://example.com/news/today", "news", "/news/*", AssignmentAutomatic, ""},
+		{"https://example.com/shop/item-1", "", "/shop/*", AssignmentAutomatic, ""},
+		{"://bad", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		groupID, name, source, ruleID := engine.Classify(tt.url)
+		if groupID != tt.groupID || name != tt.name || source != tt.source || ruleID != tt.ruleID {
+			t.Errorf("Classify(%q) = %q, %q, %q, %q; want %q, %q, %q, %q",
+				tt.url, groupID, name, source, ruleID, tt.groupID, tt.name, tt.source, tt.ruleID)
+		}
+	}
+
+	live, down := true, false
+	entries := []*models.Entry{
+		{URL: "https://example.com/blog/b", IsValid: true, IsLive: &live},
+		{URL: "https://example.com/blog/a", IsValid: false, IsLive: &down},
+		{URL: "https://example.com/shop/item-1", IsValid: true},
+		{URL: "https://example.com/shop/item-2", IsValid: true},
+		{URL: "://bad", IsValid: false},
+	}
+	for _, entry := range entries {
+		if err := engine.Assign(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := engine.GroupCount(); got != 2 {
+		t.Errorf("GroupCount() = %d, want 2", got)
+	}
+	if got := engine.UngroupedCount(); got != 1 {
+		t.Errorf("UngroupedCount() = %d, want 1", got)
+	}
+	if entries[4].GroupingID != nil {
+		t.Errorf("unparseable URL assigned to %s", *entries[4].GroupingID)
+	}
+
+	// The shop entries share one new automatic group of user-1
+	shopID := entries[2].GroupingID
+	if shopID == nil || *shopID == "other-shop" || entries[3].GroupingID == nil || *entries[3].GroupingID != *shopID {
+		t.Fatalf("shop entries grouped as %v and %v, want one new group", entries[2].GroupingID, entries[3].GroupingID)
+	}
+	shop, err := db.Groupings().GetByID(ctx, *shopID)
+	if err != nil || shop.UserID != "user-1" || shop.Name != "/shop/*" {
+		t.Errorf("automatic group = %+v, %v", shop, err)
+	}
+
+	aggregates, err := engine.Save(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aggregates) != 2 {
+		t.Fatalf("Save() returned %d aggregates, want 2", len(aggregates))
+	}
+	// Ordered by group name: "/shop/*" sorts before "Blog"
+	shopAgg, blog := aggregates[0], aggregates[1]
+	if shopAgg.GroupingID != *shopID || shopAgg.TotalEntryCount != 2 || shopAgg.ValidEntryCount != 2 {
+		t.Errorf("shop aggregate = %+v", shopAgg)
+	}
+	if blog.GroupingID != "blog" || blog.TotalEntryCount != 2 || blog.ValidEntryCount != 1 || blog.InvalidEntryCount != 1 ||
+		blog.LiveEntryCount != 1 || blog.DownEntryCount != 1 {
+		t.Errorf("blog aggregate = %+v", blog)
+	}
+	if blog.MinURL == nil || *blog.MinURL != "https://example.com/blog/a" || blog.MaxURL == nil || *blog.MaxURL != "https://example.com/blog/b" {
+		t.Errorf("blog aggregate URL range = %v - %v", blog.MinURL, blog.MaxURL)
+	}
+}
@@ -0,0 +1,486 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+// CancelPollInterval is how often a running job checks whether it was
+// cancelled from another process
+const CancelPollInterval = 2 * time.Second
+
+// UploadPruneInterval is how often RunPending removes expired uploads
+const UploadPruneInterval = time.Hour
+
+// ErrJobNotClaimable is returned when a job is not pending
+var ErrJobNotClaimable = errors.New("job is not pending")
+
+// ErrJobNotCancellable is returned when a job is not pending or running
+var ErrJobNotCancellable = errors.New("job is not pending or running")
+
+// ErrJobNotRetryable is returned when a job is not failed, cancelled or timed out
+var ErrJobNotRetryable = errors.New("job is not failed, cancelled or timed out")
+
+// JobOptions configures how the job service runs jobs
+type JobOptions struct {
+	Timeout       time.Duration // default per-job timeout (0 = none); ReportJob.TimeoutSeconds overrides it
+	MaxUploadSize int64         // cap on decompressed bytes per source
+	WorkerCount   int           // concurrent liveness requests
+	LivenessRate  float64       // liveness requests per second per host
+	GroupDepth    int           // path segments used for automatic grouping
+	RemoteOnly    bool          // refuse local file sources, for jobs queued over the API
+	UploadDir     string        // where uploaded sitemaps are stored; read locally even when RemoteOnly
+
+	Normalizer *sitemap.Normalizer // records the canonical form of every URL when set
+
+	// UploadRetention is how long the upload of a job that ended without
+	// completing is kept for a retry (0 = until the job completes)
+	UploadRetention time.Duration
+
+	// Progress, if set, receives status messages while jobs run
+	Progress func(message string)
+}
+
+// JobService claims pending report jobs and moves them through their status
+// lifecycle: pending -> running -> completed, failed, cancelled or timed_out.
+// Running a job fetches, decompresses, parses, validates, groups and stores
+// its sitemap as a new report.
+type JobService struct {
+	db      repositories.Database
+	opts    JobOptions
+	uploads *UploadService
+
+	mu        sync.Mutex
+	claim     sync.Mutex // serializes claims and cancellations in this process
+	running   map[string]*runningJob
+	lastPrune time.Time
+}
+
+// runningJob is a job executing in this process
+type runningJob struct {
+	cancel func()
+	done   chan struct{} // closed once the outcome is stored
+}
+
+// NewJobService creates a new job service
+func NewJobService(db repositories.Database, opts JobOptions) *JobService {
+	return &JobService{
+		db:      db,
+		opts:    opts,
+		uploads: NewUploadService(opts.UploadDir, opts.MaxUploadSize),
+		running: make(map[string]*runningJob),
+	}
+}
+
+// GetJob retrieves a job by ID
+func (s *JobService) GetJob(ctx context.Context, id string) (*models.ReportJob, error) {
+	job, err := s.db.ReportJobs().GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("job %s not found: %w", id, err)
+	}
+	if job == nil {
+		return nil, fmt.Errorf("job %s not found", id)
+	}
+	return job, nil
+}
+
+// QueueJob stores a new job as pending, assigning its ID and timestamps
+func (s *JobService) QueueJob(ctx context.Context, job *models.ReportJob) error {
+	now := time.Now()
+	job.ID = uuid.New().String()
+	job.Status = models.ReportJobStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if err := s.db.ReportJobs().Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to queue job: %w", err)
+	}
+	return nil
+}
+
+// ListJobs lists jobs, newest first
+func (s *JobService) ListJobs(ctx context.Context, filters repositories.JobFilters) ([]*models.ReportJob, error) {
+	return s.db.ReportJobs().List(ctx, filters)
+}
+
+// ClaimNext marks the oldest pending job as running and returns it, or nil if
+// no job is pending. The claim is atomic in the database, so runners in other
+// processes never claim the same job; a job another runner claims first is
+// skipped for the next oldest.
+func (s *JobService) ClaimNext(ctx context.Context) (*models.ReportJob, error) {
+	s.claim.Lock()
+	defer s.claim.Unlock()
+
+	for {
+		pending, err := s.db.ReportJobs().List(ctx, repositories.JobFilters{
+			Status:      models.ReportJobStatusPending,
+			OldestFirst: true,
+			Limit:       1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pending jobs: %w", err)
+		}
+		if len(pending) == 0 {
+			return nil, nil
+		}
+
+		job, err := s.db.ReportJobs().Claim(ctx, pending[0].ID, time.Now())
+		if errors.Is(err, repositories.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim job %s: %w", pending[0].ID, err)
+		}
+		return job, nil
+	}
+}
+
+// Claim marks a specific pending job as running
+func (s *JobService) Claim(ctx context.Context, id string) (*models.ReportJob, error) {
+	s.claim.Lock()
+	defer s.claim.Unlock()
+
+	job, err := s.db.ReportJobs().Claim(ctx, id, time.Now())
+	if errors.Is(err, repositories.ErrNotFound) {
+		current, getErr := s.GetJob(ctx, id)
+		if getErr != nil {
+			return nil, getErr
+		}
+		return nil, fmt.Errorf("%w: %s is %s", ErrJobNotClaimable, id, current.Status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// RunPending claims and executes pending jobs one at a time until none are
+// left or ctx is done. It returns the jobs it ran. Every UploadPruneInterval
+// it also removes expired uploads.
+func (s *JobService) RunPending(ctx context.Context) ([]*models.ReportJob, error) {
+	s.mu.Lock()
+	prune := time.Since(s.lastPrune) >= UploadPruneInterval
+	if prune {
+		s.lastPrune = time.Now()
+	}
+	s.mu.Unlock()
+	if prune {
+		if _, err := s.PruneUploads(ctx, time.Now()); err != nil {
+			s.progress(err.Error())
+		}
+	}
+
+	var ran []*models.ReportJob
+	for ctx.Err() == nil {
+		job, err := s.ClaimNext(ctx)
+		if err != nil {
+			return ran, err
+		}
+		if job == nil {
+			break
+		}
+		if err := s.Execute(ctx, job); err != nil {
+			return ran, err
+		}
+		ran = append(ran, job)
+	}
+	return ran, nil
+}
+
+// Execute runs a claimed job and records its outcome. A job that fails,
+// times out or is cancelled is not an error; Execute only returns an error
+// when the outcome cannot be stored. If ctx is done before the job finishes,
+// for example because the runner is shutting down, the job is returned to
+// pending so it runs again later. If the job stopped running meanwhile, for
+// example because another process cancelled it, the stored outcome is kept
+// and loaded into job.
+func (s *JobService) Execute(ctx context.Context, job *models.ReportJob) error {
+	timeout := s.opts.Timeout
+	if job.TimeoutSeconds > 0 {
+		timeout = time.Duration(job.TimeoutSeconds) * time.Second
+	}
+
+	jobCtx, cancelJob := context.WithCancelCause(ctx)
+	defer cancelJob(nil)
+	runCtx := jobCtx
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		runCtx, cancelTimeout = context.WithTimeout(jobCtx, timeout)
+		defer cancelTimeout()
+	}
+
+	run := &runningJob{cancel: func() { cancelJob(errJobCancelled) }, done: make(chan struct{})}
+	s.mu.Lock()
+	s.running[job.ID] = run
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+		close(run.done)
+	}()
+
+	// Notice cancellations made by other processes
+	stopWatch, watchStopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watchStopped)
+		s.watchCancellation(runCtx, job.ID, cancelJob, stopWatch)
+	}()
+
+	reportID, runErr := s.run(runCtx, job)
+	close(stopWatch)
+	<-watchStopped
+
+	now := time.Now()
+	job.UpdatedAt = now
+	switch {
+	case runErr == nil:
+		job.Status = models.ReportJobStatusCompleted
+		job.ReportID = &reportID
+		job.CompletedAt = &now
+	case errors.Is(context.Cause(jobCtx), errJobCancelled):
+		job.Status = models.ReportJobStatusCancelled
+		job.ErrorMessage = stringPtr("cancelled")
+		job.CompletedAt = &now
+	case ctx.Err() != nil:
+		job.Status = models.ReportJobStatusPending
+		job.StartedAt = nil
+	case errors.Is(runErr, context.DeadlineExceeded) || errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		job.Status = models.ReportJobStatusTimedOut
+		job.ErrorMessage = stringPtr(fmt.Sprintf("timed out after %s", timeout))
+		job.CompletedAt = &now
+	default:
+		job.Status = models.ReportJobStatusFailed
+		job.ErrorMessage = stringPtr(runErr.Error())
+		job.CompletedAt = &now
+	}
+
+	// The outcome is stored even when ctx is done
+	storeCtx := context.WithoutCancel(ctx)
+	err := s.db.ReportJobs().Finish(storeCtx, job)
+	if errors.Is(err, repositories.ErrNotFound) {
+		stored, getErr := s.GetJob(storeCtx, job.ID)
+		if getErr != nil {
+			return getErr
+		}
+		*job = *stored
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update job %s: %w", job.ID, err)
+	}
+
+	// An upload is kept until its job completes, so failed jobs can be retried
+	if job.Status == models.ReportJobStatusCompleted && s.isStoredUpload(job) {
+		if err := s.uploads.Remove(job.SourceLocation); err != nil {
+			s.progress(fmt.Sprintf("Failed to remove upload of job %s: %v", job.ID, err))
+		}
+	}
+	return nil
+}
+
+// PruneUploads removes the uploads of jobs that ended more than
+// UploadRetention before now. Uploads are normally removed when their job
+// completes; this catches the ones kept for a retry that never came, and
+// completed jobs whose upload couldn't be removed. It returns the jobs whose
+// uploads it removed.
+func (s *JobService) PruneUploads(ctx context.Context, now time.Time) ([]*models.ReportJob, error) {
+	if s.opts.UploadRetention <= 0 {
+		return nil, nil
+	}
+	cutoff := now.Add(-s.opts.UploadRetention)
+
+	var pruned []*models.ReportJob
+	for _, status := range []string{
+		models.ReportJobStatusCompleted,
+		models.ReportJobStatusFailed,
+		models.ReportJobStatusCancelled,
+		models.ReportJobStatusTimedOut,
+	} {
+		jobs, err := s.db.ReportJobs().List(ctx, repositories.JobFilters{
+			Status:          status,
+			JobType:         models.JobTypeUpload,
+			CompletedBefore: &cutoff,
+		})
+		if err != nil {
+			return pruned, fmt.Errorf("failed to list %s jobs: %w", status, err)
+		}
+		for _, job := range jobs {
+			if !s.isStoredUpload(job) || !s.uploads.Exists(job.SourceLocation) {
+				continue
+			}
+			if err := s.uploads.Remove(job.SourceLocation); err != nil {
+				return pruned, fmt.Errorf("failed to remove upload of job %s: %w", job.ID, err)
+			}
+			pruned = append(pruned, job)
+		}
+	}
+	return pruned, nil
+}
+
+// isStoredUpload reports whether a job processes a sitemap uploaded to the
+// API, which the job service reads from the upload directory
+func (s *JobService) isStoredUpload(job *models.ReportJob) bool {
+	return job.JobType == models.JobTypeUpload && s.uploads.Contains(job.SourceLocation)
+}
+
+var errJobCancelled = errors.New("job cancelled")
+
+// watchCancellation cancels a running job once its stored status is cancelled
+func (s *JobService) watchCancellation(ctx context.Context, id string, cancel context.CancelCauseFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(CancelPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			job, err := s.db.ReportJobs().GetByID(ctx, id)
+			if err == nil && job != nil && job.Status == models.ReportJobStatusCancelled {
+				cancel(errJobCancelled)
+				return
+			}
+		}
+	}
+}
+
+// run executes the pipeline of a job and returns the ID of the new report
+func (s *JobService) run(ctx context.Context, job *models.ReportJob) (string, error) {
+	s.progress(fmt.Sprintf("Running job %s: %s", job.ID, job.SourceLocation))
+
+	sourceService := NewSourceService(s.opts.MaxUploadSize)
+	if s.opts.RemoteOnly {
+		sourceService = sourceService.RemoteOnly()
+	}
+	// Uploads are local files the server stored itself; child sitemaps of an
+	// uploaded index still go through sourceService
+	opener := sourceService
+	if s.isStoredUpload(job) {
+		opener = NewSourceService(s.opts.MaxUploadSize)
+	}
+	format, sources, closeSources, err := opener.Open(ctx, job.SourceLocation)
+	if err != nil {
+		return "", fmt.Errorf("failed to read sitemap: %w", err)
+	}
+	defer closeSources()
+	job.CompressionFormat = format
+
+	snapshotService := NewSnapshotService(s.db, sourceService.Fetcher())
+	snapshot, err := snapshotService.Save(ctx, sources, job.SourceLocation, job.UserID, SnapshotOptions{
+		CheckLiveness:  job.ShouldCheckEntryLiveness,
+		LivenessRate:   s.opts.LivenessRate,
+		WorkerCount:    s.opts.WorkerCount,
+		MaxStored:      job.MaxStoredEntries,
+		GroupDepth:     s.opts.GroupDepth,
+		SkipValidation: !job.ShouldCheckForValidEntries,
+		Normalizer:     s.opts.Normalizer,
+		Progress:       s.opts.Progress,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return snapshot.Report.ID, nil
+}
+
+// CancelJob cancels a pending or running job and returns the updated job. A
+// job running in this process stops right away; one running elsewhere stops at
+// its next status check.
+func (s *JobService) CancelJob(ctx context.Context, id string) (*models.ReportJob, error) {
+	s.claim.Lock()
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		s.claim.Unlock()
+		return nil, err
+	}
+	if job.Status != models.ReportJobStatusPending && job.Status != models.ReportJobStatusRunning {
+		s.claim.Unlock()
+		return nil, fmt.Errorf("%w: %s is already %s", ErrJobNotCancellable, id, job.Status)
+	}
+
+	s.mu.Lock()
+	run, running := s.running[id]
+	s.mu.Unlock()
+	if running {
+		s.claim.Unlock()
+		return s.cancelRunning(ctx, id, run)
+	}
+	defer s.claim.Unlock()
+
+	now := time.Now()
+	job.Status = models.ReportJobStatusCancelled
+	job.ErrorMessage = stringPtr("cancelled")
+	job.CompletedAt = &now
+	job.UpdatedAt = now
+	if err := s.db.ReportJobs().Update(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to cancel job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// cancelRunning cancels a job executing in this process and waits for Execute
+// to store the outcome. The job may have finished before noticing.
+func (s *JobService) cancelRunning(ctx context.Context, id string, run *runningJob) (*models.ReportJob, error) {
+	run.cancel()
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.ReportJobStatusCancelled {
+		return nil, fmt.Errorf("%w: %s is already %s", ErrJobNotCancellable, id, job.Status)
+	}
+	return job, nil
+}
+
+// RetryJob returns a failed, cancelled or timed out job to pending
+func (s *JobService) RetryJob(ctx context.Context, id string) (*models.ReportJob, error) {
+	s.claim.Lock()
+	defer s.claim.Unlock()
+
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch job.Status {
+	case models.ReportJobStatusFailed, models.ReportJobStatusCancelled, models.ReportJobStatusTimedOut:
+	default:
+		return nil, fmt.Errorf("%w: %s is %s", ErrJobNotRetryable, id, job.Status)
+	}
+	if s.isStoredUpload(job) && !s.uploads.Exists(job.SourceLocation) {
+		return nil, fmt.Errorf("%w: the upload of %s has expired", ErrJobNotRetryable, id)
+	}
+
+	job.Status = models.ReportJobStatusPending
+	job.ErrorMessage = nil
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.ReportID = nil
+	job.UpdatedAt = time.Now()
+	if err := s.db.ReportJobs().Update(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to retry job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func (s *JobService) progress(message string) {
+	if s.opts.Progress != nil {
+		s.opts.Progress(message)
+	}
+}
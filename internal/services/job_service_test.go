@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/database/memory"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+func TestPruneUploads(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	dir := t.TempDir()
+	service := NewJobService(db, JobOptions{UploadDir: dir, UploadRetention: 24 * time.Hour})
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	jobs := []struct {
+		id          string
+		status      string
+		completedAt *time.Time
+		pruned      bool
+	}{
+		{"failed-old", models.ReportJobStatusFailed, &old, true},
+		{"cancelled-old", models.ReportJobStatusCancelled, &old, true},
+		{"completed-old", models.ReportJobStatusCompleted, &old, true},
+		{"timed-out-recent", models.ReportJobStatusTimedOut, &recent, false},
+		{"pending", models.ReportJobStatusPending, nil, false},
+	}
+	paths := make(map[string]string)
+	for _, j := range jobs {
+		path, err := service.uploads.Store(strings.NewReader("<urlset/>"), j.id+".xml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[j.id] = path
+		job := &models.ReportJob{
+			ID:             j.id,
+			UserID:         "user-1",
+			SourceLocation: path,
+			JobType:        models.JobTypeUpload,
+			Status:         j.status,
+			CompletedAt:    j.completedAt,
+			CreatedAt:      old,
+			UpdatedAt:      old,
+		}
+		if err := db.ReportJobs().Create(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pruned, err := service.PruneUploads(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 3 {
+		t.Errorf("pruned %d uploads, want 3", len(pruned))
+	}
+	for _, j := range jobs {
+		if exists := service.uploads.Exists(paths[j.id]); exists == j.pruned {
+			t.Errorf("upload of %s exists = %t, want %t", j.id, exists, !j.pruned)
+		}
+	}
+
+	// A second pass has nothing left to remove
+	if pruned, err := service.PruneUploads(ctx, now); err != nil || len(pruned) != 0 {
+		t.Errorf("second PruneUploads = %d, %v; want 0, nil", len(pruned), err)
+	}
+
+	// A job whose upload was pruned can't be retried
+	if _, err := service.RetryJob(ctx, "failed-old"); !errors.Is(err, ErrJobNotRetryable) {
+		t.Errorf("RetryJob of a pruned upload = %v, want ErrJobNotRetryable", err)
+	}
+	if _, err := service.RetryJob(ctx, "timed-out-recent"); err != nil {
+		t.Errorf("RetryJob of a kept upload: %v", err)
+	}
+
+	// No retention keeps every upload
+	keep := NewJobService(db, JobOptions{UploadDir: dir})
+	if pruned, err := keep.PruneUploads(ctx, now.Add(365*24*time.Hour)); err != nil || len(pruned) != 0 {
+		t.Errorf("PruneUploads without retention = %d, %v; want 0, nil", len(pruned), err)
+	}
+}
+
+// queueFileJob queues a job for a sitemap file with one URL
+func queueFileJob(t *testing.T, service *JobService) *models.ReportJob {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sitemap.xml")
+	if err := os.WriteFile(path, []byte(`<urlset><url><loc>http://www.example.com/a/</loc></url></urlset>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	job := &models.ReportJob{UserID: "user-1", SourceLocation: path, JobType: models.JobTypeFile, ShouldCheckForValidEntries: true}
+	if err := service.QueueJob(context.Background(), job); err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestExecuteNormalizes(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	normalizer := sitemap.NewNormalizer(sitemap.AllNormalizeRules, nil)
+	service := NewJobService(db, JobOptions{Normalizer: normalizer})
+	queueFileJob(t, service)
+
+	ran, err := service.RunPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 1 || ran[0].Status != models.ReportJobStatusCompleted {
+		t.Fatalf("RunPending = %+v, want one completed job", ran)
+	}
+	report, err := db.Reports().GetByID(ctx, *ran[0].ReportID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Normalization == nil || *report.Normalization != normalizer.Spec() {
+		t.Errorf("report normalization = %v, want %q", report.Normalization, normalizer.Spec())
+	}
+}
+
+func TestExecuteKeepsStoredOutcome(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	service := NewJobService(db, JobOptions{})
+	queued := queueFileJob(t, service)
+
+	job, err := service.Claim(ctx, queued.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process cancels the job before this one stores its outcome
+	cancelled := *job
+	cancelled.Status = models.ReportJobStatusCancelled
+	if err := db.ReportJobs().Update(ctx, &cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Execute(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := service.GetJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.ReportJobStatusCancelled || job.Status != models.ReportJobStatusCancelled {
+		t.Errorf("job is %s and stored as %s, want both cancelled", job.Status, stored.Status)
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	ctx := context.Background()
+	requested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	db := memory.New()
+	service := NewJobService(db, JobOptions{})
+	job := &models.ReportJob{UserID: "user-1", SourceLocation: server.URL + "/sitemap.xml", JobType: models.JobTypeURL}
+	if err := service.QueueJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	executed := make(chan error, 1)
+	go func() {
+		_, err := service.RunPending(ctx)
+		executed <- err
+	}()
+	<-requested
+
+	cancelled, err := service.CancelJob(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != models.ReportJobStatusCancelled || cancelled.CompletedAt == nil {
+		t.Errorf("CancelJob = %s, want the cancelled job", cancelled.Status)
+	}
+	if err := <-executed; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.CancelJob(ctx, job.ID); !errors.Is(err, ErrJobNotCancellable) {
+		t.Errorf("second CancelJob = %v, want ErrJobNotCancellable", err)
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	sitemaphttp "jonopens/sitemapper/pkg/http"
+)
+
+// LivenessService checks whether entry URLs respond and records the outcome on
+// the entries
+type LivenessService struct {
+	checker *sitemaphttp.LivenessChecker
+}
+
+// NewLivenessService creates a new liveness service. workerCount bounds the
+// number of concurrent requests and requestsPerHost caps the per-second rate
+// to any single host (0 = unlimited).
+func NewLivenessService(workerCount int, requestsPerHost float64) *LivenessService {
+	return &LivenessService{
+		checker: sitemaphttp.NewLivenessChecker(sitemaphttp.LivenessOptions{
+			Workers:         workerCount,
+			RequestsPerHost: requestsPerHost,
+			UserAgent:       "sitemapper",
+		}),
+	}
+}
+
+// CheckURLs checks every URL and returns the results in the same order
+func (s *LivenessService) CheckURLs(ctx context.Context, urls []string) []sitemaphttp.LivenessResult {
+	return s.checker.CheckAll(ctx, urls)
+}
+
+// CheckEntries checks the URL of every entry, writes the results back to the
+// entries and returns the number of live and down entries
+func (s *LivenessService) CheckEntries(ctx context.Context, entries []*models.Entry) (live, down int) {
+	urls := make([]string, len(entries))
+	for i, entry := range entries {
+		urls[i] = entry.URL
+	}
+
+	for i, result := range s.checker.CheckAll(ctx, urls) {
+		ApplyLivenessResult(entries[i], result)
+		if result.IsLive {
+			live++
+		} else {
+			down++
+		}
+	}
+
+	return live, down
+}
+
+// ApplyLivenessResult copies a liveness result onto an entry
+func ApplyLivenessResult(entry *models.Entry, result sitemaphttp.LivenessResult) {
+	isLive := result.IsLive
+	responseTimeMs := int(result.ResponseTime / time.Millisecond)
+	checkedAt := result.CheckedAt
+
+	entry.IsLive = &isLive
+	entry.ResponseTimeMs = &responseTimeMs
+	entry.LivenessCheckedAt = &checkedAt
+	entry.HTTPStatusCode = nil
+	entry.LivenessError = nil
+	entry.FinalURL = nil
+	entry.RedirectChain = result.RedirectChain
+
+	if result.StatusCode != 0 {
+		status := result.StatusCode
+		entry.HTTPStatusCode = &status
+	}
+	if result.Err != nil {
+		errMsg := result.Err.Error()
+		entry.LivenessError = &errMsg
+	}
+	if result.FinalURL != "" && result.FinalURL != result.URL {
+		finalURL := result.FinalURL
+		entry.FinalURL = &finalURL
+	}
+	entry.UpdatedAt = time.Now()
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"jonopens/sitemapper/internal/database/memory"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+)
+
+func TestCheckEntries(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	entries := []*models.Entry{
+		{URL: server.URL + "/ok"},
+		{URL: server.URL + "/moved"},
+		{URL: server.URL + "/gone"},
+		{URL: "http://[::1"},
+	}
+	live, down := NewLivenessService(2, 0).CheckEntries(context.Background(), entries)
+	if live != 2 || down != 2 {
+		t.Errorf("CheckEntries = %d live, %d down; want 2 and 2", live, down)
+	}
+
+	tests := []struct {
+		live     bool
+		status   int // 0 for no status
+		final    string
+		redirect []string
+		failed   bool
+	}{
+		{live: true, status: 200},
+		{live: true, status: 200, final: server.URL + "/ok", redirect: []string{server.URL + "/moved"}},
+		{status: 410},
+		{failed: true},
+	}
+	for i, tt := range tests {
+		entry := entries[i]
+		if entry.IsLive == nil || *entry.IsLive != tt.live || entry.LivenessCheckedAt == nil || entry.ResponseTimeMs == nil {
+			t.Errorf("%s: IsLive = %v, want %t with a check time and response time", entry.URL, entry.IsLive, tt.live)
+		}
+		if got := intValue(entry.HTTPStatusCode); got != tt.status {
+			t.Errorf("%s: HTTPStatusCode = %d, want %d", entry.URL, got, tt.status)
+		}
+		if got := stringValue(entry.FinalURL); got != tt.final {
+			t.Errorf("%s: FinalURL = %q, want %q", entry.URL, got, tt.final)
+		}
+		if !reflect.DeepEqual(entry.RedirectChain, tt.redirect) {
+			t.Errorf("%s: RedirectChain = %v, want %v", entry.URL, entry.RedirectChain, tt.redirect)
+		}
+		if (entry.LivenessError != nil) != tt.failed {
+			t.Errorf("%s: LivenessError = %v", entry.URL, stringValue(entry.LivenessError))
+		}
+	}
+}
+
+func TestSaveChecksLivenessInBatches(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "0") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	count := livenessBatchSize + 10
+	var doc strings.Builder
+	doc.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&doc, "<url><loc>%s/%d</loc></url>", server.URL, i)
+	}
+	doc.WriteString(`</urlset>`)
+
+	var batches []string
+	db := memory.New()
+	snapshot, err := NewSnapshotService(db, nil).Save(ctx,
+		[]*DecompressedSource{{Name: "sitemap.xml", Reader: strings.NewReader(doc.String())}},
+		"sitemap.xml", "user-1", SnapshotOptions{
+			CheckLiveness: true,
+			WorkerCount:   10,
+			Progress: func(message string) {
+				if strings.HasPrefix(message, "Checking liveness") {
+					batches = append(batches, message)
+				}
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		fmt.Sprintf("Checking liveness of URLs 1-%d with 10 workers...", livenessBatchSize),
+		fmt.Sprintf("Checking liveness of URLs %d-%d with 10 workers...", livenessBatchSize+1, count),
+	}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("liveness batches = %q, want %q", batches, want)
+	}
+
+	report := snapshot.Report
+	if report.StoredEntryCount != count || report.LiveEntryCount != count-count/10 || report.DownEntryCount != count/10 {
+		t.Errorf("report = %d stored, %d live, %d down; want %d, %d, %d",
+			report.StoredEntryCount, report.LiveEntryCount, report.DownEntryCount, count, count-count/10, count/10)
+	}
+	entries, err := db.Entries().List(ctx, repositories.EntryFilters{ReportID: report.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsLive == nil {
+			t.Fatalf("%s was stored without a liveness check", entry.URL)
+		}
+	}
+}
+
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func stringValue(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+// StratumKeyFunc assigns an entry to a sampling stratum
+type StratumKeyFunc func(entry *models.Entry) string
+
+// ByGrouping stratifies entries by their grouping; ungrouped entries share a stratum
+func ByGrouping(entry *models.Entry) string {
+	if entry.GroupingID == nil {
+		return ""
+	}
+	return *entry.GroupingID
+}
+
+// SampleResult is the outcome of sampling a stream of entries
+type SampleResult struct {
+	Entries      []*models.Entry // entries to store, with SelectionReason set
+	TotalCount   int             // entries seen
+	Sampled      bool            // false when every entry is stored
+	SamplingRate float64         // share of all entries stored, outliers and boundaries included
+}
+
+// Sampler selects which entries of an oversized sitemap are stored. While the
+// number of entries stays within maxStored every entry is kept. Above it, the
+// sampler keeps every invalid or down entry as an outlier, the alphabetical
+// min and max URL of every stratum as boundaries, and fills the rest of the
+// budget with a proportional random sample of each stratum.
+//
+// Each stratum keeps a reservoir of at most maxStored entries, so memory is
+// bounded by the number of strata rather than the size of the sitemap.
+type Sampler struct {
+	maxStored int
+	keyFunc   StratumKeyFunc
+
+	all      []*models.Entry // every entry, until maxStored is exceeded
+	overflow bool
+	total    int
+	outliers []*models.Entry
+	strata   map[string]*stratum
+}
+
+type stratum struct {
+	min, max  *models.Entry
+	eligible  int // entries that are not outliers
+	reservoir []*models.Entry
+}
+
+// NewSampler creates a new sampler that stores at most maxStored entries
+// (outliers excepted). keyFunc defaults to ByGrouping.
+func NewSampler(maxStored int, keyFunc StratumKeyFunc) *Sampler {
+	if keyFunc == nil {
+		keyFunc = ByGrouping
+	}
+	return &Sampler{
+		maxStored: maxStored,
+		keyFunc:   keyFunc,
+		strata:    make(map[string]*stratum),
+	}
+}
+
+// Add records an entry. Liveness results, if any, must already be set.
+func (s *Sampler) Add(entry *models.Entry) {
+	s.total++
+	if !s.overflow {
+		if s.total > s.maxStored {
+			s.overflow = true
+			s.all = nil
+		} else {
+			s.all = append(s.all, entry)
+		}
+	}
+
+	key := s.keyFunc(entry)
+	st, ok := s.strata[key]
+	if !ok {
+		st = &stratum{}
+		s.strata[key] = st
+	}
+	st.add(entry)
+
+	if isOutlier(entry) {
+		s.outliers = append(s.outliers, entry)
+		return
+	}
+
+	// Reservoir sampling keeps a uniform random subset of the stratum
+	st.eligible++
+	if len(st.reservoir) < s.maxStored {
+		st.reservoir = append(st.reservoir, entry)
+	} else if j := rand.IntN(st.eligible); j < s.maxStored {
+		st.reservoir[j] = entry
+	}
+}
+
+// Select returns the entries to store
+func (s *Sampler) Select() *SampleResult {
+	result := &SampleResult{TotalCount: s.total, SamplingRate: 1}
+
+	if !s.overflow {
+		for _, entry := range s.all {
+			entry.SelectionReason = models.SelectionReasonFullStorage
+		}
+		result.Entries = s.all
+		return result
+	}
+
+	result.Sampled = true
+	selected := make(map[*models.Entry]bool)
+	keep := func(entry *models.Entry, reason models.SelectionReason) {
+		if selected[entry] {
+			return
+		}
+		selected[entry] = true
+		entry.SelectionReason = reason
+		result.Entries = append(result.Entries, entry)
+	}
+
+	for _, entry := range s.outliers {
+		keep(entry, models.SelectionReasonOutlier)
+	}
+
+	keys := s.keys()
+	for _, key := range keys {
+		st := s.strata[key]
+		keep(st.min, models.SelectionReasonBoundary)
+		keep(st.max, models.SelectionReasonBoundary)
+	}
+
+	// Spend what is left of the budget on a proportional sample of every stratum
+	budget := s.maxStored - len(result.Entries)
+	eligible := 0
+	for _, st := range s.strata {
+		eligible += st.eligible
+	}
+
+	if budget > 0 && eligible > 0 {
+		quotas := s.allocate(keys, budget, eligible)
+		for _, key := range keys {
+			st := s.strata[key]
+			rand.Shuffle(len(st.reservoir), func(i, j int) {
+				st.reservoir[i], st.reservoir[j] = st.reservoir[j], st.reservoir[i]
+			})
+
+			quota := quotas[key]
+			for _, entry := range st.reservoir {
+				if quota == 0 {
+					break
+				}
+				if selected[entry] {
+					continue
+				}
+				keep(entry, models.SelectionReasonSampled)
+				quota--
+			}
+		}
+	}
+	result.SamplingRate = float64(len(result.Entries)) / float64(s.total)
+
+	return result
+}
+
+// allocate splits budget across strata in proportion to their eligible
+// entries, using the largest remainder method so the quotas sum to budget
+func (s *Sampler) allocate(keys []string, budget, eligible int) map[string]int {
+	type remainder struct {
+		key   string
+		value int
+	}
+
+	quotas := make(map[string]int, len(keys))
+	remainders := make([]remainder, 0, len(keys))
+	allocated := 0
+	for _, key := range keys {
+		share := budget * s.strata[key].eligible
+		quotas[key] = share / eligible
+		allocated += quotas[key]
+		remainders = append(remainders, remainder{key, share % eligible})
+	}
+
+	sort.SliceStable(remainders, func(i, j int) bool { return remainders[i].value > remainders[j].value })
+	for i := 0; allocated < budget && i < len(remainders); i++ {
+		quotas[remainders[i].key]++
+		allocated++
+	}
+
+	return quotas
+}
+
+func (s *Sampler) keys() []string {
+	keys := make([]string, 0, len(s.strata))
+	for key := range s.strata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (st *stratum) add(entry *models.Entry) {
+	if st.min == nil || entry.URL < st.min.URL {
+		st.min = entry
+	}
+	if st.max == nil || entry.URL > st.max.URL {
+		st.max = entry
+	}
+}
+
+// isOutlier reports whether an entry is invalid or was found to be down
+func isOutlier(entry *models.Entry) bool {
+	return !entry.IsValid || (entry.IsLive != nil && !*entry.IsLive)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"jonopens/sitemapper/internal/models"
+)
+
+// sampleEntries returns n valid entries in grouping group
+func sampleEntries(group string, n int) []*models.Entry {
+	entries := make([]*models.Entry, n)
+	for i := range entries {
+		groupingID := group
+		entries[i] = &models.Entry{
+			URL:        fmt.Sprintf("https://example.com/%s/%04d", group, i),
+			GroupingID: &groupingID,
+			IsValid:    true,
+		}
+	}
+	return entries
+}
+
+func TestSampler(t *testing.T) {
+	down := false
+	invalid := sampleEntries("c", 3)
+	invalid[0].IsValid = false
+	invalid[1].IsLive = &down
+
+	tests := []struct {
+		name      string
+		maxStored int
+		entries   []*models.Entry
+		sampled   bool
+		stored    int
+		perGroup  map[string]int // stored entries per grouping
+		outliers  int
+	}{
+		{
+			name:      "under the threshold",
+			maxStored: 10,
+			entries:   sampleEntries("a", 10),
+			stored:    10,
+			perGroup:  map[string]int{"a": 10},
+		},
+		{
+			name:      "proportional quotas",
+			maxStored: 12,
+			entries:   append(sampleEntries("a", 80), sampleEntries("b", 20)...),
+			sampled:   true,
+			stored:    12,
+			// 2 boundaries each, then 8 sampled split 6.4 / 1.6 by largest remainder
+			perGroup: map[string]int{"a": 8, "b": 4},
+		},
+		{
+			name:      "outliers are kept beyond the budget",
+			maxStored: 4,
+			entries:   append(sampleEntries("a", 20), invalid...),
+			sampled:   true,
+			// 2 outliers, then the boundaries of a and c, of which c's min is
+			// already an outlier; no budget is left to sample
+			stored:   5,
+			perGroup: map[string]int{"a": 2, "c": 3},
+			outliers: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := NewSampler(tt.maxStored, nil)
+			for _, entry := range tt.entries {
+				sampler.Add(entry)
+			}
+			result := sampler.Select()
+
+			if result.Sampled != tt.sampled || result.TotalCount != len(tt.entries) || len(result.Entries) != tt.stored {
+				t.Fatalf("Select() = sampled %v, total %d, stored %d; want %v, %d, %d",
+					result.Sampled, result.TotalCount, len(result.Entries), tt.sampled, len(tt.entries), tt.stored)
+			}
+			if want := float64(tt.stored) / float64(len(tt.entries)); result.SamplingRate != want {
+				t.Errorf("SamplingRate = %v, want stored/total = %v", result.SamplingRate, want)
+			}
+
+			perGroup := make(map[string]int)
+			reasons := make(map[models.SelectionReason]int)
+			seen := make(map[*models.Entry]bool)
+			for _, entry := range result.Entries {
+				if seen[entry] {
+					t.Errorf("%s stored twice", entry.URL)
+				}
+				seen[entry] = true
+				perGroup[*entry.GroupingID]++
+				reasons[entry.SelectionReason]++
+			}
+			if fmt.Sprint(perGroup) != fmt.Sprint(tt.perGroup) {
+				t.Errorf("stored per grouping = %v, want %v", perGroup, tt.perGroup)
+			}
+			if reasons[models.SelectionReasonOutlier] != tt.outliers {
+				t.Errorf("outliers = %d, want %d", reasons[models.SelectionReasonOutlier], tt.outliers)
+			}
+			if !tt.sampled && reasons[models.SelectionReasonFullStorage] != tt.stored {
+				t.Errorf("reasons = %v, want every entry stored in full", reasons)
+			}
+		})
+	}
+}
+
+func TestSamplerKeepsBoundaries(t *testing.T) {
+	entries := sampleEntries("a", 50)
+	sampler := NewSampler(5, nil)
+	// Add out of order, so the boundaries aren't simply the first and last
+	for i := range entries {
+		sampler.Add(entries[(i*7)%len(entries)])
+	}
+	result := sampler.Select()
+
+	boundaries := make(map[string]bool)
+	for _, entry := range result.Entries {
+		if entry.SelectionReason == models.SelectionReasonBoundary {
+			boundaries[entry.URL] = true
+		}
+	}
+	if len(boundaries) != 2 || !boundaries[entries[0].URL] || !boundaries[entries[49].URL] {
+		t.Errorf("boundaries = %v, want the first and last URL", boundaries)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/pkg/scheduler"
+)
+
+// MissedRunGrace is how late a scheduled run may be picked up before it
+// counts as missed, e.g. because the scheduler was not running
+const MissedRunGrace = 5 * time.Minute
+
+// ScheduledRun is the outcome of a due schedule being processed
+type ScheduledRun struct {
+	Schedule    *models.ReportSchedule
+	Job         *models.ReportJob // nil when a missed run was skipped
+	ScheduledAt time.Time
+	Missed      bool
+}
+
+// ScheduleService manages report schedules and queues their jobs
+type ScheduleService struct {
+	db repositories.Database
+}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService(db repositories.Database) *ScheduleService {
+	return &ScheduleService{db: db}
+}
+
+// CreateSchedule validates a schedule and stores it with its first run time
+func (s *ScheduleService) CreateSchedule(ctx context.Context, schedule *models.ReportSchedule) error {
+	if schedule.SourceLocation == "" {
+		return fmt.Errorf("schedule source is required")
+	}
+	if schedule.JitterSeconds < 0 {
+		return fmt.Errorf("jitter must not be negative")
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+
+	next, err := NextRun(schedule, time.Now())
+	if err != nil {
+		return err
+	}
+	if !schedule.IsPaused {
+		schedule.NextRunAt = &next
+	}
+
+	return s.db.ReportSchedules().Create(ctx, schedule)
+}
+
+// GetSchedule retrieves a schedule by ID
+func (s *ScheduleService) GetSchedule(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	return s.db.ReportSchedules().GetByID(ctx, id)
+}
+
+// ListSchedules lists schedules, oldest first
+func (s *ScheduleService) ListSchedules(ctx context.Context, filters repositories.ScheduleFilters) ([]*models.ReportSchedule, error) {
+	return s.db.ReportSchedules().List(ctx, filters)
+}
+
+// PauseSchedule stops a schedule from queueing jobs
+func (s *ScheduleService) PauseSchedule(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	schedule, err := s.getSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.IsPaused = true
+	schedule.NextRunAt = nil
+	schedule.UpdatedAt = time.Now()
+	return schedule, s.db.ReportSchedules().Update(ctx, schedule)
+}
+
+// ResumeSchedule re-enables a paused schedule from its next run after now.
+// Runs that fell due while it was paused are not caught up.
+func (s *ScheduleService) ResumeSchedule(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	schedule, err := s.getSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := NextRun(schedule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	schedule.IsPaused = false
+	schedule.NextRunAt = &next
+	schedule.UpdatedAt = time.Now()
+	return schedule, s.db.ReportSchedules().Update(ctx, schedule)
+}
+
+// DeleteSchedule deletes a schedule. Jobs it already queued are kept.
+func (s *ScheduleService) DeleteSchedule(ctx context.Context, id string) error {
+	if _, err := s.getSchedule(ctx, id); err != nil {
+		return err
+	}
+	return s.db.ReportSchedules().Delete(ctx, id)
+}
+
+// RunDue queues a pending ReportJob for every schedule due at now and moves
+// each schedule on to its next run. A run picked up more than MissedRunGrace
+// late is missed: it is queued once if the schedule catches up, and skipped
+// otherwise. Several missed runs never queue more than one job.
+func (s *ScheduleService) RunDue(ctx context.Context, now time.Time) ([]*ScheduledRun, error) {
+	due, err := s.db.ReportSchedules().ListDue(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due schedules: %w", err)
+	}
+
+	var runs []*ScheduledRun
+	for _, schedule := range due {
+		run, err := s.runSchedule(ctx, schedule, now)
+		if err != nil {
+			return runs, fmt.Errorf("schedule %s: %w", schedule.ID, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *ScheduleService) runSchedule(ctx context.Context, schedule *models.ReportSchedule, now time.Time) (*ScheduledRun, error) {
+	run := &ScheduledRun{
+		Schedule:    schedule,
+		ScheduledAt: *schedule.NextRunAt,
+		Missed:      now.Sub(*schedule.NextRunAt) > MissedRunGrace,
+	}
+
+	next, err := NextRun(schedule, now)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !run.Missed || schedule.CatchUp {
+		run.Job = newScheduledJob(schedule, now)
+		if err := tx.ReportJobs().Create(ctx, run.Job); err != nil {
+			return nil, fmt.Errorf("failed to queue job: %w", err)
+		}
+		schedule.LastRunAt = &now
+	}
+
+	schedule.NextRunAt = &next
+	schedule.UpdatedAt = now
+	if err := tx.ReportSchedules().Update(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit scheduled run: %w", err)
+	}
+	return run, nil
+}
+
+func (s *ScheduleService) getSchedule(ctx context.Context, id string) (*models.ReportSchedule, error) {
+	schedule, err := s.db.ReportSchedules().GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %s not found: %w", id, err)
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("schedule %s not found", id)
+	}
+	return schedule, nil
+}
+
+// NextRun returns the first run of a schedule after the given time, with the
+// schedule's jitter applied. Jitter never pushes a run past the following
+// one, so no run is skipped.
+func NextRun(schedule *models.ReportSchedule, after time.Time) (time.Time, error) {
+	cron, err := scheduler.Parse(schedule.CronExpression)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tz := schedule.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	next := cron.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never matches", schedule.CronExpression)
+	}
+
+	maxJitter := time.Duration(schedule.JitterSeconds) * time.Second
+	if following := cron.Next(next); !following.IsZero() && following.Sub(next) < maxJitter {
+		maxJitter = following.Sub(next)
+	}
+	return next.Add(scheduler.Jitter(maxJitter)).UTC(), nil
+}
+
+// newScheduledJob creates the pending job for a run of schedule
+func newScheduledJob(schedule *models.ReportSchedule, now time.Time) *models.ReportJob {
+	jobType := models.JobTypeFile
+	if strings.HasPrefix(schedule.SourceLocation, "http://") || strings.HasPrefix(schedule.SourceLocation, "https://") {
+		jobType = models.JobTypeURL
+	}
+
+	scheduleID := schedule.ID
+	return &models.ReportJob{
+		ID:                         uuid.New().String(),
+		UserID:                     schedule.UserID,
+		ReportScheduleID:           &scheduleID,
+		SourceLocation:             schedule.SourceLocation,
+		JobType:                    jobType,
+		ShouldCheckEntryLiveness:   schedule.ShouldCheckEntryLiveness,
+		ShouldCheckForValidEntries: true,
+		MaxStoredEntries:           schedule.MaxStoredEntries,
+		Status:                     models.ReportJobStatusPending,
+		CreatedAt:                  now,
+		UpdatedAt:                  now,
+	}
+}
@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"jonopens/sitemapper/internal/models"
 	"jonopens/sitemapper/internal/repositories"
 	"jonopens/sitemapper/pkg/sitemap"
 )
@@ -30,9 +34,179 @@ func (s *SitemapService) ProcessSitemap(ctx context.Context, userID string, data
 	return nil
 }
 
-// CompareSitemaps compares two sitemaps and returns differences
-func (s *SitemapService) CompareSitemaps(ctx context.Context, oldID, newID string) error {
-	// TODO: Implement comparison logic
-	return nil
+// CompareSitemaps compares two tracked reports and stores the result as a
+// ReportDiff with one ReportDiffEntry per added, removed or changed URL.
+// Entries are matched by their URL normalized by normalizer, if it is not nil.
+// Sampled reports are refused with ErrReportSampled, since URLs left out of
+// the sample would show up as added or removed.
+func (s *SitemapService) CompareSitemaps(ctx context.Context, oldID, newID string, normalizer *sitemap.Normalizer) (*models.ReportDiff, *sitemap.Diff, error) {
+	base, err := s.comparableReport(ctx, oldID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get base report: %w", err)
+	}
+	if _, err := s.comparableReport(ctx, newID); err != nil {
+		return nil, nil, fmt.Errorf("failed to get compare report: %w", err)
+	}
+
+	differ := sitemap.NewDiffer()
+	if err := s.StreamReportURLs(ctx, oldID, normalizer, differ.AddBaseKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to load base report: %w", err)
+	}
+	if err := s.StreamReportURLs(ctx, newID, normalizer, differ.AddCompareKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to load compare report: %w", err)
+	}
+	diff := differ.Result()
+
+	now := time.Now()
+	reportDiff := &models.ReportDiff{
+		ID:              uuid.New().String(),
+		UserID:          base.UserID,
+		BaseReportID:    oldID,
+		CompareReportID: newID,
+		EntriesAdded:    len(diff.Added),
+		EntriesRemoved:  len(diff.Removed),
+		EntriesChanged:  len(diff.Changed),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	diffRepo := tx.ReportDiffs()
+	if err := diffRepo.Create(ctx, reportDiff); err != nil {
+		return nil, nil, fmt.Errorf("failed to create report diff: %w", err)
+	}
+
+	newEntry := func(loc string, changeType models.DiffChangeType) *models.ReportDiffEntry {
+		return &models.ReportDiffEntry{
+			ID:           uuid.New().String(),
+			ReportDiffID: reportDiff.ID,
+			URL:          loc,
+			ChangeType:   changeType,
+			CreatedAt:    now,
+		}
+	}
+	for _, loc := range diff.Added {
+		if err := diffRepo.CreateEntry(ctx, newEntry(loc, models.DiffChangeTypeAdded)); err != nil {
+			return nil, nil, fmt.Errorf("failed to create diff entry: %w", err)
+		}
+	}
+	for _, loc := range diff.Removed {
+		if err := diffRepo.CreateEntry(ctx, newEntry(loc, models.DiffChangeTypeRemoved)); err != nil {
+			return nil, nil, fmt.Errorf("failed to create diff entry: %w", err)
+		}
+	}
+	for _, change := range diff.Changed {
+		entry := newEntry(change.Loc, models.DiffChangeTypeChanged)
+		for _, field := range change.Fields {
+			entry.Fields = append(entry.Fields, models.DiffFieldChange{Field: field.Field, Old: field.Old, New: field.New})
+		}
+		if err := diffRepo.CreateEntry(ctx, entry); err != nil {
+			return nil, nil, fmt.Errorf("failed to create diff entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit report diff: %w", err)
+	}
+
+	return reportDiff, diff, nil
 }
 
+// GetDiff retrieves a stored report diff by ID
+func (s *SitemapService) GetDiff(ctx context.Context, id string) (*models.ReportDiff, error) {
+	return s.db.ReportDiffs().GetByID(ctx, id)
+}
+
+// ListDiffs lists stored report diffs, newest first
+func (s *SitemapService) ListDiffs(ctx context.Context, filters repositories.ReportDiffFilters) ([]*models.ReportDiff, error) {
+	return s.db.ReportDiffs().List(ctx, filters)
+}
+
+// ListDiffEntries lists the change rows of a stored report diff
+func (s *SitemapService) ListDiffEntries(ctx context.Context, diffID string) ([]*models.ReportDiffEntry, error) {
+	return s.db.ReportDiffs().ListEntries(ctx, diffID)
+}
+
+// reportURLPageSize is the number of entries StreamReportURLs loads at a time
+const reportURLPageSize = 1000
+
+// comparableReport returns a report that holds every URL of its sitemap
+func (s *SitemapService) comparableReport(ctx context.Context, id string) (*models.Report, error) {
+	report, err := s.db.Reports().GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if report == nil {
+		return nil, fmt.Errorf("report not found: %s", id)
+	}
+	if !report.IsFullyStored {
+		return nil, fmt.Errorf("%w: only %d of %d URLs of report %s are stored",
+			ErrReportSampled, report.StoredEntryCount, report.EntryCount, id)
+	}
+	return report, nil
+}
+
+// StreamReportURLs calls fn for every URL entry of a tracked report with the
+// key the entry is matched by: its URL normalized by normalizer, if it is not
+// nil. Entries are loaded a page at a time, so a large report is never held in
+// memory. Child sitemap entries of an index are left out.
+//
+// When the report was normalized with the same rules as normalizer, the stored
+// NormalizedURL is the key. Otherwise the stored URL is normalized again, so
+// a report tracked with other rules or none keys the same way as a file.
+func (s *SitemapService) StreamReportURLs(ctx context.Context, reportID string, normalizer *sitemap.Normalizer, fn func(key string, u sitemap.URL) error) error {
+	report, err := s.db.Reports().GetByID(ctx, reportID)
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return fmt.Errorf("report not found: %s", reportID)
+	}
+	stored := report.Normalization != nil && *report.Normalization == normalizer.Spec()
+
+	urlType := models.EntryTypeURL
+	for offset := 0; ; offset += reportURLPageSize {
+		entries, err := s.db.Entries().List(ctx, repositories.EntryFilters{
+			ReportID: reportID,
+			Type:     &urlType,
+			Limit:    reportURLPageSize,
+			Offset:   offset,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			u := sitemap.URL{Loc: entry.URL}
+			if entry.LastModified != nil {
+				u.LastMod = entry.LastModified.Format(time.RFC3339)
+			}
+			if entry.ChangeFreq != nil {
+				u.ChangeFreq = *entry.ChangeFreq
+			}
+			if entry.Priority != nil {
+				u.Priority = *entry.Priority
+			}
+
+			key := entry.URL
+			switch {
+			case stored && entry.NormalizedURL != nil:
+				key = *entry.NormalizedURL
+			case !stored:
+				key = normalizer.Normalize(entry.URL)
+			}
+			if err := fn(key, u); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < reportURLPageSize {
+			return nil
+		}
+	}
+}
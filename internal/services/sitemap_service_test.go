@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"jonopens/sitemapper/internal/database/memory"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+func TestCompareNormalizedReports(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	all := sitemap.NewNormalizer(sitemap.AllNormalizeRules, nil)
+
+	// The same sitemap, tracked with and without --normalize
+	locs := []string{"http://www.example.com/blog/", "https://example.com/about?utm_source=x"}
+	for _, report := range []struct {
+		id         string
+		normalizer *sitemap.Normalizer
+	}{
+		{"normalized", all},
+		{"raw", nil},
+	} {
+		if err := db.Reports().Create(ctx, &models.Report{
+			ID:            report.id,
+			UserID:        "user-1",
+			IsFullyStored: true,
+			Normalization: stringPtr(report.normalizer.Spec()),
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		for _, loc := range locs {
+			entry := &models.Entry{
+				ID:            report.id + loc,
+				ReportID:      report.id,
+				Type:          models.EntryTypeURL,
+				URL:           loc,
+				NormalizedURL: normalizedURL(report.normalizer, loc),
+				IsValid:       true,
+			}
+			if err := db.Entries().Create(ctx, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		normalizer *sitemap.Normalizer
+	}{
+		{"without normalization", nil},
+		{"with every rule", all},
+		{"with other rules", sitemap.NewNormalizer([]sitemap.NormalizeRule{sitemap.NormalizeTrailingSlash}, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewSitemapService(db)
+			for _, pair := range [][2]string{{"normalized", "raw"}, {"raw", "normalized"}} {
+				_, diff, err := service.CompareSitemaps(ctx, pair[0], pair[1], tt.normalizer)
+				if err != nil {
+					t.Fatalf("CompareSitemaps(%s, %s): %v", pair[0], pair[1], err)
+				}
+				if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.Changed) != 0 {
+					t.Errorf("CompareSitemaps(%s, %s) = added %v, removed %v, changed %d; want no changes",
+						pair[0], pair[1], diff.Added, diff.Removed, len(diff.Changed))
+				}
+			}
+
+			// A file of the same sitemap matches the normalized report too
+			differ := sitemap.NewDiffer()
+			if err := service.StreamReportURLs(ctx, "normalized", tt.normalizer, differ.AddBaseKey); err != nil {
+				t.Fatal(err)
+			}
+			for _, loc := range locs {
+				if err := differ.AddCompareKey(tt.normalizer.Normalize(loc), sitemap.URL{Loc: loc}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if diff := differ.Result(); len(diff.Added) != 0 || len(diff.Removed) != 0 {
+				t.Errorf("report against file = added %v, removed %v; want no changes", diff.Added, diff.Removed)
+			}
+		})
+	}
+}
+
+func TestStreamReportURLsPages(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+
+	// Other reports' entries and child sitemaps are left out of every page
+	if err := db.Reports().Create(ctx, &models.Report{ID: "report-1", UserID: "user-1", IsFullyStored: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 2*reportURLPageSize + 1
+	entries := []*models.Entry{
+		{ID: "other", ReportID: "other", Type: models.EntryTypeURL, URL: "https://example.com/other"},
+		{ID: "child", ReportID: "report-1", Type: models.EntryTypeSitemap, URL: "https://example.com/child.xml"},
+	}
+	for i := 0; i < count; i++ {
+		entries = append(entries, &models.Entry{
+			ID:       fmt.Sprintf("entry-%d", i),
+			ReportID: "report-1",
+			Type:     models.EntryTypeURL,
+			URL:      fmt.Sprintf("https://example.com/%05d", i),
+		})
+	}
+	if err := db.Entries().CreateBatch(ctx, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	err := NewSitemapService(db).StreamReportURLs(ctx, "report-1", nil, func(key string, u sitemap.URL) error {
+		if seen[key] {
+			t.Errorf("%s streamed twice", key)
+		}
+		seen[key] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != count {
+		t.Errorf("streamed %d URLs, want %d", len(seen), count)
+	}
+}
+
+func TestStreamReportURLsKeys(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	www := sitemap.NewNormalizer([]sitemap.NormalizeRule{sitemap.NormalizeWWW}, nil)
+	if err := db.Reports().Create(ctx, &models.Report{ID: "report-1", UserID: "user-1", IsFullyStored: true, Normalization: stringPtr(www.Spec())}); err != nil {
+		t.Fatal(err)
+	}
+	// The stored form is deliberately not what www would produce, to tell
+	// which one is used. Entries are streamed in URL order.
+	entries := []*models.Entry{
+		{ID: "a", ReportID: "report-1", Type: models.EntryTypeURL, URL: "https://www.example.com/a", NormalizedURL: stringPtr("stored")},
+		{ID: "b", ReportID: "report-1", Type: models.EntryTypeURL, URL: "https://example.com/b"},
+	}
+	if err := db.Entries().CreateBatch(ctx, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		normalizer *sitemap.Normalizer
+		want       []string
+	}{
+		{"same rules", sitemap.NewNormalizer([]sitemap.NormalizeRule{sitemap.NormalizeWWW}, nil), []string{"https://example.com/b", "stored"}},
+		{"other rules", sitemap.NewNormalizer([]sitemap.NormalizeRule{sitemap.NormalizeWWW, sitemap.NormalizeScheme}, nil), []string{"https://example.com/b", "https://example.com/a"}},
+		{"no rules", nil, []string{"https://example.com/b", "https://www.example.com/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			err := NewSitemapService(db).StreamReportURLs(ctx, "report-1", tt.normalizer, func(key string, u sitemap.URL) error {
+				keys = append(keys, key)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("keys = %v, want %v", keys, tt.want)
+			}
+		})
+	}
+
+	if err := NewSitemapService(db).StreamReportURLs(ctx, "missing", nil, func(string, sitemap.URL) error { return nil }); err == nil {
+		t.Error("StreamReportURLs of an unknown report succeeded")
+	}
+}
+
+func TestCompareSitemapsRefusesIncompleteReports(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	for _, report := range []*models.Report{
+		{ID: "full", UserID: "user-1", IsFullyStored: true, EntryCount: 1, StoredEntryCount: 1},
+		{ID: "sampled", UserID: "user-1", EntryCount: 10, StoredEntryCount: 5},
+	} {
+		if err := db.Reports().Create(ctx, report); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		oldID, newID string
+		sampled      bool
+	}{
+		{"unknown base", "missing", "full", false},
+		{"unknown compare", "full", "missing", false},
+		{"sampled base", "sampled", "full", true},
+		{"sampled compare", "full", "sampled", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := NewSitemapService(db).CompareSitemaps(ctx, tt.oldID, tt.newID, nil)
+			if err == nil || errors.Is(err, ErrReportSampled) != tt.sampled {
+				t.Errorf("CompareSitemaps(%s, %s) = %v, want an error (sampled %t)", tt.oldID, tt.newID, err, tt.sampled)
+			}
+		})
+	}
+
+	diffs, err := db.ReportDiffs().List(ctx, repositories.ReportDiffFilters{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("%d diffs were stored, want none", len(diffs))
+	}
+}
@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/internal/repositories"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+// SnapshotOptions controls how a sitemap snapshot is stored
+type SnapshotOptions struct {
+	CheckLiveness bool    // request every URL before storing
+	LivenessRate  float64 // liveness requests per second per host (0 = unlimited)
+	WorkerCount   int     // concurrent liveness requests
+	MaxStored     int     // sample above this many URL entries (0 = store all)
+	GroupDepth    int     // path segments used for automatic grouping
+	MaxDepth      int     // nesting limit when resolving sitemap indexes
+
+	// SkipValidation stores every URL entry as valid without checking it
+	SkipValidation bool
+
+	Normalizer *sitemap.Normalizer // records the canonical form of every URL when set
+
+	// Progress, if set, receives status messages for long-running steps
+	Progress func(message string)
+}
+
+// Snapshot is the outcome of saving a sitemap snapshot
+type Snapshot struct {
+	Report   *models.Report
+	Resolved *sitemap.ResolvedSitemap
+	Sample   *SampleResult // nil when sampling is disabled
+
+	Groupings  []*models.ReportGrouping
+	GroupNames map[string]string // group name by grouping ID
+}
+
+// snapshotBatchSize is the number of entries written per CreateBatch call
+const snapshotBatchSize = 5000
+
+// livenessBatchSize is the number of URL entries held back for one round of
+// liveness checks
+const livenessBatchSize = 1000
+
+// SnapshotService stores sitemap snapshots as reports
+type SnapshotService struct {
+	db      repositories.Database
+	fetcher sitemap.Fetcher
+}
+
+// NewSnapshotService creates a new snapshot service. fetcher loads the child
+// sitemaps of sitemap indexes.
+func NewSnapshotService(db repositories.Database, fetcher sitemap.Fetcher) *SnapshotService {
+	return &SnapshotService{db: db, fetcher: fetcher}
+}
+
+// Save streams the sitemap sources into a new report. URL
+// entries are written in batches as they are decoded, so the full sitemap is
+// never held in memory; report totals are filled in once the stream is
+// exhausted. When an archive expands to several sources, each file is recorded
+// as a child sitemap.
+// URL entries are assigned to automatic path groups and a ReportGrouping with
+// accurate totals is written per group.
+// With CheckLiveness, URL entries are held back and checked in batches of
+// livenessBatchSize before they are stored. With MaxStored, entries pass through a sampler and only the selected
+// entries are written, while report totals still cover every URL.
+// The whole snapshot is written in one transaction, so a cancelled ctx leaves
+// nothing behind.
+func (s *SnapshotService) Save(ctx context.Context, sources []*DecompressedSource, source, userID string, opts SnapshotOptions) (*Snapshot, error) {
+	// Create report; counts are updated after streaming
+	report := &models.Report{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		IsFullyStored:    true,
+		SamplingStrategy: models.SamplingStrategyNone,
+		SamplingRate:     nil,
+		Normalization:    stringPtr(opts.Normalizer.Spec()),
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Save report
+	if err := tx.Reports().Create(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to create report: %w", err)
+	}
+
+	grouping := NewGroupingService(tx)
+	grouping.Depth = opts.GroupDepth
+	engine, err := grouping.NewEngine(ctx, userID, report.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var sampler *Sampler
+	if opts.MaxStored > 0 {
+		sampler = NewSampler(opts.MaxStored, ByGrouping)
+	}
+
+	validator := sitemap.NewValidator()
+	entryCount, validCount, storedCount := 0, 0, 0
+	var pending []*models.Entry
+
+	// Entries are written in batches of snapshotBatchSize
+	var batch []*models.Entry
+	flushEntries := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Entries().CreateBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to create entries: %w", err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	saveEntry := func(entry *models.Entry) error {
+		batch = append(batch, entry)
+		storedCount++
+		engine.Stored(entry)
+		if len(batch) < snapshotBatchSize {
+			return nil
+		}
+		if err := flushEntries(); err != nil {
+			return err
+		}
+		if opts.Progress != nil {
+			opts.Progress(fmt.Sprintf("Stored %d entries...", storedCount))
+		}
+		return nil
+	}
+
+	// storeURLEntry groups a finished URL entry, then hands it to the sampler
+	// or stores it directly
+	storeURLEntry := func(entry *models.Entry) error {
+		if err := engine.Assign(ctx, entry); err != nil {
+			return err
+		}
+		if sampler != nil {
+			sampler.Add(entry)
+			return nil
+		}
+		return saveEntry(entry)
+	}
+
+	// checkPending checks the liveness of the held back URL entries, then
+	// stores them
+	var liveness *LivenessService
+	if opts.CheckLiveness {
+		liveness = NewLivenessService(opts.WorkerCount, opts.LivenessRate)
+	}
+	liveCount, downCount, checkedCount := 0, 0, 0
+	checkPending := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if opts.Progress != nil {
+			opts.Progress(fmt.Sprintf("Checking liveness of URLs %d-%d with %d workers...",
+				checkedCount+1, checkedCount+len(pending), opts.WorkerCount))
+		}
+
+		live, down := liveness.CheckEntries(ctx, pending)
+		liveCount += live
+		downCount += down
+		checkedCount += len(pending)
+
+		for _, entry := range pending {
+			if err := storeURLEntry(entry); err != nil {
+				return err
+			}
+		}
+		pending = pending[:0]
+		return ctx.Err()
+	}
+
+	newURLEntry := func(u sitemap.SourcedURL) error {
+		entry := &models.Entry{
+			ID:              uuid.New().String(),
+			ReportID:        report.ID,
+			GroupingID:      nil,
+			Type:            models.EntryTypeURL,
+			URL:             u.Loc,
+			LastModified:    parseLastMod(u.LastMod),
+			ChangeFreq:      stringPtr(u.ChangeFreq),
+			Priority:        float64Ptr(u.Priority),
+			NormalizedURL:   normalizedURL(opts.Normalizer, u.Loc),
+			Extensions:      entryExtensions(&u.URL),
+			IsValid:         true,
+			ValidationError: nil,
+			SelectionReason: models.SelectionReasonFullStorage,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+
+		// Validate entry
+		if !opts.SkipValidation {
+			if err := validator.ValidateURL(&u.URL); err != nil {
+				entry.IsValid = false
+				errMsg := err.Error()
+				entry.ValidationError = &errMsg
+			}
+		}
+
+		entryCount++
+		if entry.IsValid {
+			validCount++
+		}
+
+		// Liveness is checked a batch at a time, so only one batch is held back
+		if opts.CheckLiveness {
+			pending = append(pending, entry)
+			if len(pending) < livenessBatchSize {
+				return nil
+			}
+			return checkPending()
+		}
+
+		return storeURLEntry(entry)
+	}
+
+	// Stream URL entries, resolving sitemap indexes into their child sitemaps
+	resolver := sitemap.NewResolver(s.fetcher)
+	if opts.MaxDepth > 0 {
+		resolver.MaxDepth = opts.MaxDepth
+	}
+	resolved := &sitemap.ResolvedSitemap{Root: source, IsIndex: len(sources) > 1}
+	for _, src := range sources {
+		before := entryCount
+		part, err := resolver.Stream(ctx, src.Name, src.Reader, newURLEntry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sitemap %s: %w", src.Name, err)
+		}
+
+		if len(sources) > 1 {
+			resolved.Children = append(resolved.Children, sitemap.ChildSitemap{
+				Loc:      src.Name,
+				Parent:   source,
+				Depth:    1,
+				IsIndex:  part.IsIndex,
+				URLCount: entryCount - before,
+			})
+		}
+		resolved.IsIndex = resolved.IsIndex || part.IsIndex
+		resolved.Children = append(resolved.Children, part.Children...)
+	}
+
+	// Check and store the last partial batch of URL entries
+	if err := checkPending(); err != nil {
+		return nil, err
+	}
+
+	// Store the sampled URL entries
+	var sample *SampleResult
+	if sampler != nil {
+		sample = sampler.Select()
+		for _, entry := range sample.Entries {
+			if err := saveEntry(entry); err != nil {
+				return nil, err
+			}
+		}
+
+		if sample.Sampled {
+			rate := sample.SamplingRate
+			report.IsFullyStored = false
+			report.SamplingStrategy = models.SamplingStrategyStratified
+			report.SamplingRate = &rate
+		}
+	}
+
+	// Save child sitemap entries
+	for _, child := range resolved.Children {
+		entry := &models.Entry{
+			ID:              uuid.New().String(),
+			ReportID:        report.ID,
+			GroupingID:      nil,
+			Type:            models.EntryTypeSitemap,
+			URL:             child.Loc,
+			LastModified:    parseLastMod(child.LastMod),
+			IsValid:         child.Error == "",
+			ValidationError: stringPtr(child.Error),
+			SelectionReason: models.SelectionReasonFullStorage,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+		entryCount++
+		if entry.IsValid {
+			validCount++
+		}
+		if err := saveEntry(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := flushEntries(); err != nil {
+		return nil, err
+	}
+
+	reportGroupings, err := engine.Save(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupNames := make(map[string]string, len(reportGroupings))
+	for _, rg := range reportGroupings {
+		groupNames[rg.GroupingID] = engine.GroupName(rg.GroupingID)
+	}
+
+	// Update report totals
+	report.EntryCount = entryCount
+	report.StoredEntryCount = storedCount
+	report.ValidEntryCount = validCount
+	report.InvalidEntryCount = entryCount - validCount
+	report.LiveEntryCount = liveCount
+	report.DownEntryCount = downCount
+	report.GroupingCount = engine.GroupCount()
+	report.UngroupedCount = engine.UngroupedCount() + len(resolved.Children)
+	report.ChildSitemapCount = len(resolved.Children)
+	report.UpdatedAt = time.Now()
+
+	if err := tx.Reports().Update(ctx, report); err != nil {
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &Snapshot{
+		Report:     report,
+		Resolved:   resolved,
+		Sample:     sample,
+		Groupings:  reportGroupings,
+		GroupNames: groupNames,
+	}, nil
+}
+
+func parseLastMod(lastMod string) *time.Time {
+	if lastMod == "" {
+		return nil
+	}
+
+	// Accept every W3C Datetime profile allowed by the sitemap protocol
+	t, err := sitemap.ParseW3CDate(strings.TrimSpace(lastMod))
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
+
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func float64Ptr(f float64) *float64 {
+	if f == 0 {
+		return nil
+	}
+	return &f
+}
+
+// entryExtensions converts parsed sitemap extension elements into their
+// persisted form. Returns nil when the URL carries no extension data.
+func entryExtensions(u *sitemap.URL) *models.EntryExtensions {
+	if !u.HasExtensions() {
+		return nil
+	}
+
+	ext := &models.EntryExtensions{}
+
+	for _, img := range u.Images {
+		ext.Images = append(ext.Images, models.EntryImage{
+			Loc:         img.Loc,
+			Caption:     stringPtr(img.Caption),
+			Title:       stringPtr(img.Title),
+			GeoLocation: stringPtr(img.GeoLocation),
+			License:     stringPtr(img.License),
+		})
+	}
+
+	for _, video := range u.Videos {
+		ext.Videos = append(ext.Videos, models.EntryVideo{
+			ThumbnailLoc:    video.ThumbnailLoc,
+			Title:           video.Title,
+			Description:     video.Description,
+			ContentLoc:      stringPtr(video.ContentLoc),
+			PlayerLoc:       stringPtr(video.PlayerLoc),
+			DurationSeconds: intPtrFromString(video.Duration),
+			Rating:          float64PtrFromString(video.Rating),
+			ViewCount:       intPtrFromString(video.ViewCount),
+			PublicationDate: parseLastMod(video.PublicationDate),
+			ExpirationDate:  parseLastMod(video.ExpirationDate),
+			FamilyFriendly:  yesNoPtr(video.FamilyFriendly),
+			Live:            yesNoPtr(video.Live),
+			Uploader:        stringPtr(video.Uploader),
+			Tags:            video.Tags,
+		})
+	}
+
+	if u.News != nil {
+		ext.News = &models.EntryNews{
+			PublicationName:     u.News.Publication.Name,
+			PublicationLanguage: u.News.Publication.Language,
+			PublicationDate:     parseLastMod(u.News.PublicationDate),
+			Title:               u.News.Title,
+		}
+	}
+
+	for _, alt := range u.Alternates {
+		ext.Alternates = append(ext.Alternates, models.EntryAlternate{
+			Hreflang: alt.Hreflang,
+			Href:     alt.Href,
+		})
+	}
+
+	return ext
+}
+
+func intPtrFromString(s string) *int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func float64PtrFromString(s string) *float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func yesNoPtr(s string) *bool {
+	var b bool
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true":
+		b = true
+	case "no", "false":
+		b = false
+	default:
+		return nil
+	}
+	return &b
+}
+
+// normalizedURL returns the canonical form of loc, or nil when it is already
+// canonical or normalization is disabled
+func normalizedURL(normalizer *sitemap.Normalizer, loc string) *string {
+	normalized := normalizer.Normalize(loc)
+	if normalized == loc {
+		return nil
+	}
+	return &normalized
+}
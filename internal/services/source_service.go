@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"jonopens/sitemapper/internal/models"
+	"jonopens/sitemapper/pkg/http"
+	"jonopens/sitemapper/pkg/sitemap"
+)
+
+// SourceService opens sitemap files and URLs, expanding compressed sources
+type SourceService struct {
+	decompressor *DecompressionService
+	client       *http.RetryClient
+	remoteOnly   bool
+}
+
+// NewSourceService creates a new source service. maxSize caps the total
+// decompressed bytes read from a single source (0 = unlimited).
+func NewSourceService(maxSize int64) *SourceService {
+	return &SourceService{
+		decompressor: NewDecompressionService(maxSize),
+		client:       http.NewRetryClient(3, 30*time.Second),
+	}
+}
+
+// RemoteOnly returns a copy of the service that refuses local file paths, for
+// sources supplied by API clients. Child sitemaps are refused as well, so a
+// sitemap index can't point the service at local files either.
+func (s *SourceService) RemoteOnly() *SourceService {
+	remote := *s
+	remote.remoteOnly = true
+	return &remote
+}
+
+// Open opens a sitemap file or URL for streaming and expands gzip and zip
+// compression into one source per sitemap document. The caller must call the
+// returned close function once done with the sources.
+func (s *SourceService) Open(ctx context.Context, source string) (*models.CompressionFormat, []*DecompressedSource, func(), error) {
+	rc, contentEncoding, err := s.fetch(ctx, source)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	format, sources, err := s.decompressor.Open(rc, contentEncoding, source)
+	if err != nil {
+		rc.Close()
+		return nil, nil, nil, err
+	}
+
+	closeAll := func() {
+		for _, src := range sources {
+			src.Close()
+		}
+		rc.Close()
+	}
+
+	return format, sources, closeAll, nil
+}
+
+// OpenOne opens a single, decompressed sitemap document for streaming. The
+// caller must close the returned reader.
+func (s *SourceService) OpenOne(ctx context.Context, source string) (io.ReadCloser, error) {
+	rc, contentEncoding, err := s.fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := s.decompressor.OpenOne(rc, contentEncoding, source)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{src.Reader, multiCloser{src, rc}}, nil
+}
+
+// Read reads a single, decompressed sitemap document into memory
+func (s *SourceService) Read(ctx context.Context, source string) ([]byte, error) {
+	rc, err := s.OpenOne(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// Fetcher returns a fetcher that opens child sitemaps the same way top-level
+// sources are opened
+func (s *SourceService) Fetcher() sitemap.Fetcher {
+	return sitemap.FetcherFunc(s.OpenOne)
+}
+
+// fetch opens a raw sitemap file or URL and returns the response
+// Content-Encoding, if any. The caller must close the returned reader.
+func (s *SourceService) fetch(ctx context.Context, source string) (io.ReadCloser, string, error) {
+	// Check if source is a URL
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		resp, err := s.client.GetContext(ctx, source)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to fetch URL: %w", err)
+		}
+
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+
+		return resp.Body, resp.Header.Get("Content-Encoding"), nil
+	}
+
+	if s.remoteOnly {
+		return nil, "", fmt.Errorf("not an http or https URL: %s", source)
+	}
+
+	// Read from file
+	f, err := os.Open(source)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, "", nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrUploadTooLarge is returned when an upload is bigger than the size limit
+var ErrUploadTooLarge = errors.New("upload is too large")
+
+// ErrUploadEmpty is returned when an upload has no content
+var ErrUploadEmpty = errors.New("upload is empty")
+
+// unsafeNameChars are replaced in the file names uploads are stored under
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// UploadService stores uploaded sitemaps as files in one directory until the
+// jobs that process them run. Blobs are stored as uploaded, compressed or not.
+type UploadService struct {
+	dir     string
+	maxSize int64
+}
+
+// NewUploadService creates a new upload service storing blobs in dir.
+// maxSize caps the bytes stored per upload (0 = unlimited).
+func NewUploadService(dir string, maxSize int64) *UploadService {
+	return &UploadService{dir: dir, maxSize: maxSize}
+}
+
+// Store writes an upload to a new file and returns its path. The original
+// file name is kept at the end of the stored name, so reports show it.
+func (s *UploadService) Store(r io.Reader, name string) (string, error) {
+	if s.dir == "" {
+		return "", fmt.Errorf("no upload directory is configured")
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	name = strings.Trim(unsafeNameChars.ReplaceAllString(filepath.Base(name), "_"), "._")
+	if name == "" {
+		name = "sitemap.xml"
+	}
+	path := filepath.Join(dir, uuid.New().String()+"-"+name)
+
+	// Write to a temporary name, so a partial upload is never picked up
+	f, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	src := r
+	if s.maxSize > 0 {
+		src = io.LimitReader(r, s.maxSize+1)
+	}
+	n, err := io.Copy(f, src)
+	if err == nil && s.maxSize > 0 && n > s.maxSize {
+		err = fmt.Errorf("%w (limit %d bytes)", ErrUploadTooLarge, s.maxSize)
+	}
+	if err == nil && n == 0 {
+		err = ErrUploadEmpty
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	return path, nil
+}
+
+// Contains reports whether path is a blob in the upload directory
+func (s *UploadService) Contains(path string) bool {
+	if s.dir == "" {
+		return false
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return false
+	}
+	return filepath.IsAbs(path) && filepath.Dir(filepath.Clean(path)) == dir
+}
+
+// Exists reports whether path is a blob in the upload directory that hasn't
+// been removed
+func (s *UploadService) Exists(path string) bool {
+	if !s.Contains(path) {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Remove deletes a stored blob; blobs outside the upload directory are left alone
+func (s *UploadService) Remove(path string) error {
+	if !s.Contains(path) {
+		return fmt.Errorf("%s is not a stored upload", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
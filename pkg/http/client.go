@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -26,10 +27,20 @@ func NewRetryClient(maxRetries int, timeout time.Duration) *RetryClient {
 
 // Get performs a GET request with retry logic
 func (c *RetryClient) Get(url string) (*http.Response, error) {
+	return c.GetContext(context.Background(), url)
+}
+
+// GetContext performs a GET request with retry logic, giving up when ctx is done
+func (c *RetryClient) GetContext(ctx context.Context, url string) (*http.Response, error) {
 	var lastErr error
 	
 	for i := 0; i < c.MaxRetries; i++ {
-		resp, err := c.client.Get(url)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		
+		resp, err := c.client.Do(req)
 		if err == nil && resp.StatusCode < 500 {
 			return resp, nil
 		}
@@ -44,7 +55,11 @@ func (c *RetryClient) Get(url string) (*http.Response, error) {
 		// Exponential backoff
 		if i < c.MaxRetries-1 {
 			backoff := time.Duration(i+1) * time.Second
-			time.Sleep(backoff)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
 		}
 	}
 	
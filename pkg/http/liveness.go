@@ -0,0 +1,235 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Liveness checker defaults
+const (
+	DefaultLivenessWorkers      = 5
+	DefaultLivenessTimeout      = 10 * time.Second
+	DefaultLivenessMaxRedirects = 10
+)
+
+// ErrTooManyRedirects is returned when a URL redirects more than MaxRedirects times
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// LivenessOptions configures a LivenessChecker
+type LivenessOptions struct {
+	Workers         int           // concurrent requests (default 5)
+	RequestsPerHost float64       // max requests per second to a single host (0 = unlimited)
+	Timeout         time.Duration // per-request timeout (default 10s)
+	MaxRedirects    int           // redirects followed before giving up (default 10)
+	UserAgent       string
+}
+
+// LivenessResult is the outcome of checking a single URL
+type LivenessResult struct {
+	URL           string        `json:"url"`
+	FinalURL      string        `json:"final_url"`
+	StatusCode    int           `json:"status_code"`
+	IsLive        bool          `json:"is_live"`
+	Method        string        `json:"method"`
+	RedirectChain []string      `json:"redirect_chain,omitempty"` // URLs that answered with a redirect, in order
+	ResponseTime  time.Duration `json:"response_time"`
+	CheckedAt     time.Time     `json:"checked_at"`
+	Err           error         `json:"-"`
+}
+
+// LivenessChecker checks whether URLs respond successfully. Requests use HEAD
+// and fall back to GET when HEAD fails or is rejected, since many servers do
+// not implement HEAD correctly.
+type LivenessChecker struct {
+	opts    LivenessOptions
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+// NewLivenessChecker creates a new liveness checker
+func NewLivenessChecker(opts LivenessOptions) *LivenessChecker {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultLivenessWorkers
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultLivenessTimeout
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = DefaultLivenessMaxRedirects
+	}
+
+	return &LivenessChecker{
+		opts: opts,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			// Redirects are followed manually so every hop is recorded and rate limited
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		limiter: newHostLimiter(opts.RequestsPerHost),
+	}
+}
+
+// Check checks a single URL
+func (c *LivenessChecker) Check(ctx context.Context, rawURL string) LivenessResult {
+	start := time.Now()
+
+	result := c.follow(ctx, http.MethodHead, rawURL)
+	if result.Err != nil || result.StatusCode >= 400 {
+		if ctx.Err() == nil {
+			result = c.follow(ctx, http.MethodGet, rawURL)
+		}
+	}
+
+	result.ResponseTime = time.Since(start)
+	result.CheckedAt = time.Now()
+	result.IsLive = result.Err == nil && result.StatusCode >= 200 && result.StatusCode < 300
+	return result
+}
+
+// CheckAll checks every URL using a bounded pool of workers and returns the
+// results in the same order as urls
+func (c *LivenessChecker) CheckAll(ctx context.Context, urls []string) []LivenessResult {
+	results := make([]LivenessResult, len(urls))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < c.opts.Workers && w < len(urls); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = c.Check(ctx, urls[i])
+			}
+		}()
+	}
+
+	for i := range urls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// follow issues method against rawURL and follows redirects until a
+// non-redirect response, recording each hop
+func (c *LivenessChecker) follow(ctx context.Context, method, rawURL string) LivenessResult {
+	result := LivenessResult{URL: rawURL, FinalURL: rawURL, Method: method}
+
+	current := rawURL
+	for hops := 0; ; hops++ {
+		status, location, err := c.do(ctx, method, current)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.StatusCode = status
+		result.FinalURL = current
+
+		if location == "" {
+			return result
+		}
+		if hops >= c.opts.MaxRedirects {
+			result.Err = fmt.Errorf("stopped after %d redirects: %w", hops, ErrTooManyRedirects)
+			return result
+		}
+
+		result.RedirectChain = append(result.RedirectChain, current)
+		current = location
+	}
+}
+
+// do performs a single request and returns its status and, for redirects, the
+// absolute URL of the next hop
+func (c *LivenessChecker) do(ctx context.Context, method, rawURL string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid request: %w", err)
+	}
+	if c.opts.UserAgent != "" {
+		req.Header.Set("User-Agent", c.opts.UserAgent)
+	}
+
+	if err := c.limiter.Wait(ctx, req.URL.Host); err != nil {
+		return 0, "", err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	// The body is never needed; closing without reading keeps GET checks cheap
+	resp.Body.Close()
+
+	if !isRedirect(resp.StatusCode) {
+		return resp.StatusCode, "", nil
+	}
+
+	location, err := resp.Location()
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("redirect without valid Location header: %w", err)
+	}
+	return resp.StatusCode, location.String(), nil
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// hostLimiter spaces requests to the same host by a fixed interval
+type hostLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time
+}
+
+func newHostLimiter(perSecond float64) *hostLimiter {
+	limiter := &hostLimiter{next: make(map[string]time.Time)}
+	if perSecond > 0 {
+		limiter.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return limiter
+}
+
+// Wait blocks until a request to host is allowed or ctx is cancelled
+func (l *hostLimiter) Wait(ctx context.Context, host string) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	// Reserve the next free slot for this host, then sleep until it arrives
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
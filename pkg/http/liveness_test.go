@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	// Servers that reject HEAD are checked again with GET
+	for path, status := range map[string]int{"/no-head": http.StatusMethodNotAllowed, "/head-unimplemented": http.StatusNotImplemented} {
+		status := status
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(status)
+			}
+		})
+	}
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusTemporaryRedirect)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		live     bool
+		status   int
+		method   string
+		final    string
+		redirect []string
+		err      error
+	}{
+		{path: "/ok", live: true, status: 200, method: http.MethodHead, final: "/ok"},
+		{path: "/missing", status: 404, method: http.MethodGet, final: "/missing"},
+		{path: "/no-head", live: true, status: 200, method: http.MethodGet, final: "/no-head"},
+		{path: "/head-unimplemented", live: true, status: 200, method: http.MethodGet, final: "/head-unimplemented"},
+		{path: "/a", live: true, status: 200, method: http.MethodHead, final: "/ok", redirect: []string{"/a", "/b"}},
+		{path: "/loop", status: 307, method: http.MethodGet, final: "/loop", redirect: []string{"/loop", "/loop", "/loop"}, err: ErrTooManyRedirects},
+	}
+
+	checker := NewLivenessChecker(LivenessOptions{MaxRedirects: 3})
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result := checker.Check(context.Background(), server.URL+tt.path)
+
+			if result.IsLive != tt.live || result.StatusCode != tt.status || result.Method != tt.method {
+				t.Errorf("Check = live %t, status %d, method %s; want %t, %d, %s",
+					result.IsLive, result.StatusCode, result.Method, tt.live, tt.status, tt.method)
+			}
+			if result.FinalURL != server.URL+tt.final {
+				t.Errorf("FinalURL = %s, want %s", result.FinalURL, server.URL+tt.final)
+			}
+			var redirect []string
+			for _, path := range tt.redirect {
+				redirect = append(redirect, server.URL+path)
+			}
+			if !reflect.DeepEqual(result.RedirectChain, redirect) {
+				t.Errorf("RedirectChain = %v, want %v", result.RedirectChain, redirect)
+			}
+			if !errors.Is(result.Err, tt.err) {
+				t.Errorf("Err = %v, want %v", result.Err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/a", server.URL + "/down", server.URL + "/b", "not a url"}
+	results := NewLivenessChecker(LivenessOptions{Workers: 2}).CheckAll(context.Background(), urls)
+
+	// Results keep the order of urls
+	for i, want := range []bool{true, false, true, false} {
+		if results[i].URL != urls[i] || results[i].IsLive != want {
+			t.Errorf("result %d = %s live %t, want %s live %t", i, results[i].URL, results[i].IsLive, urls[i], want)
+		}
+	}
+	if results[3].Err == nil {
+		t.Error("checking an invalid URL did not fail")
+	}
+}
+
+func TestCheckAllRateLimit(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	// 20 requests per second spaces requests to the host 50ms apart, however
+	// many workers there are
+	checker := NewLivenessChecker(LivenessOptions{Workers: 5, RequestsPerHost: 20})
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/c", server.URL + "/d", server.URL + "/e"}
+
+	start := time.Now()
+	checker.CheckAll(context.Background(), urls)
+	if elapsed := time.Since(start); elapsed < 4*50*time.Millisecond {
+		t.Errorf("checking %d URLs took %s, want at least 200ms", len(urls), elapsed)
+	}
+	if got := atomic.LoadInt32(&requests); got != int32(len(urls)) {
+		t.Errorf("server saw %d requests, want %d", got, len(urls))
+	}
+}
+
+func TestCheckCancelled(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	result := NewLivenessChecker(LivenessOptions{}).Check(ctx, server.URL)
+	if result.IsLive || !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Check = live %t, err %v; want a cancelled check", result.IsLive, result.Err)
+	}
+	// A cancelled HEAD is not retried with GET
+	if result.Method != http.MethodHead || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Check made %d requests ending with %s, want one HEAD", atomic.LoadInt32(&requests), result.Method)
+	}
+}
+
+func TestCheckCancelledWhileRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The first request takes the host's only slot for the next ten seconds
+	checker := NewLivenessChecker(LivenessOptions{RequestsPerHost: 0.1})
+	if result := checker.Check(context.Background(), server.URL); !result.IsLive {
+		t.Fatalf("first Check = %+v, want live", result)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result := checker.Check(ctx, server.URL)
+	if !errors.Is(result.Err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("Check waiting for the limiter = %v after %s, want the deadline", result.Err, time.Since(start))
+	}
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Thursday
+	from := time.Date(2026, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 1, 1, 10, 25, 0, 0, time.UTC)},
+		{"30 8-10/2 * * *", time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"0,7 10 * * *", time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{"0 0 * * *", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 JAN *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 7 is Sunday too
+		{"0 9 * * 7", time.Date(2026, 1, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * sat,sun", time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)},
+		// Both days restricted: either may match, so Friday the 2nd
+		{"0 9 15 * fri", time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)},
+		// A day of month starting with * only narrows the day of week: the
+		// first odd-numbered Monday, not Saturday the 3rd
+		{"0 9 */2 * mon", time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 9 1-31/2 * mon", time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * */2", time.Date(2026, 1, 3, 9, 0, 0, 0, time.UTC)},
+		// Never matches
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			s, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNextDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	s, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2:30 doesn't exist on 8 March 2026, when clocks skip from 2:00 to 3:00
+	got := s.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	if !got.After(time.Date(2026, 3, 7, 12, 0, 0, 0, loc)) || got.Minute() != 30 {
+		t.Errorf("Next across the spring transition = %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@every",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for _, max := range []time.Duration{-time.Second, 0} {
+		if got := Jitter(max); got != 0 {
+			t.Errorf("Jitter(%v) = %v, want 0", max, got)
+		}
+	}
+	max := 3 * time.Second
+	for range 1000 {
+		if got := Jitter(max); got < 0 || got >= max {
+			t.Fatalf("Jitter(%v) = %v, want [0, %v)", max, got, max)
+		}
+	}
+}
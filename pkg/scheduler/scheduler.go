@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often a Scheduler checks for due work
+const DefaultPollInterval = 30 * time.Second
+
+// TickFunc is called on every poll with the current time
+type TickFunc func(ctx context.Context, now time.Time) error
+
+// Scheduler calls a TickFunc at a fixed poll interval until stopped. The
+// TickFunc decides what is due, so schedules can live in a database and
+// change while the scheduler runs.
+type Scheduler struct {
+	Interval time.Duration
+
+	// OnError is called with errors returned by the TickFunc; they do not stop the scheduler
+	OnError func(err error)
+
+	tick   TickFunc
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a new scheduler polling every DefaultPollInterval
+func New(tick TickFunc) *Scheduler {
+	return &Scheduler{Interval: DefaultPollInterval, tick: tick}
+}
+
+// Start runs the first tick immediately and then one per interval in a
+// background goroutine. It does nothing if the scheduler is already running.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+}
+
+// Stop stops the scheduler and waits for a running tick to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Done returns a channel that is closed once the scheduler has stopped,
+// either through Stop or because the context passed to Start was cancelled
+func (s *Scheduler) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	return s.done
+}
+
+func (s *Scheduler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.tick(ctx, time.Now()); err != nil && s.OnError != nil && ctx.Err() == nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Jitter returns a random duration in [0, max), or 0 if max is not positive
+func Jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
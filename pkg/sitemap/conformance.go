@@ -0,0 +1,405 @@
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Protocol limits from https://www.sitemaps.org/protocol.html
+const (
+	MaxURLsPerSitemap = 50000
+	MaxSitemapBytes   = 50 * 1024 * 1024
+	MaxURLLength      = 2048
+)
+
+// Severity indicates how serious a conformance finding is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Conformance rule identifiers
+const (
+	RuleWellFormed    = "xml-well-formed"
+	RuleNamespace     = "xml-namespace"
+	RuleURLLimit      = "url-limit"
+	RuleSizeLimit     = "size-limit"
+	RuleLocMissing    = "loc-missing"
+	RuleLocFormat     = "loc-format"
+	RuleLocAbsolute   = "loc-absolute"
+	RuleLocHost       = "loc-host"
+	RuleLocPath       = "loc-path"
+	RuleLocLength     = "loc-length"
+	RuleLocEscaping   = "loc-escaping"
+	RuleLocEncoding   = "loc-encoding"
+	RuleLocDuplicate  = "loc-duplicate"
+	RuleLastModFormat = "lastmod-format"
+	RulePriorityRange = "priority-range"
+	RuleChangeFreq    = "changefreq-value"
+	RuleExtension     = "extension-fields"
+)
+
+// Finding is a single conformance issue found in a sitemap document
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Index    int      `json:"index"`  // entry index, -1 for document-level findings
+	Offset   int64    `json:"offset"` // byte offset of the entry in the uncompressed document
+	Line     int      `json:"line"`   // 1-based line of the entry, 0 if unknown
+	Column   int      `json:"column"` // 1-based column of the entry, 0 if unknown
+}
+
+// position locates an entry in the document being checked
+type position struct {
+	index  int
+	offset int64
+	line   int
+	column int
+}
+
+// ConformanceOptions configures a strict conformance check
+type ConformanceOptions struct {
+	// Strict enables the full protocol rule set. Without it only the checks
+	// performed by ValidateURL are applied, but every issue is still reported.
+	Strict bool
+
+	// SitemapLoc is where the sitemap was fetched from. When it is an http(s)
+	// URL, every <loc> must share its host and fall under its directory.
+	SitemapLoc string
+
+	// Limits default to the protocol maximums when zero
+	MaxURLs      int
+	MaxBytes     int64
+	MaxURLLength int
+}
+
+// conformanceEntry decodes <url> and <sitemap> elements loosely so malformed
+// values are reported as findings instead of aborting the decode
+type conformanceEntry struct {
+	Loc        string      `xml:"loc"`
+	LastMod    string      `xml:"lastmod"`
+	ChangeFreq string      `xml:"changefreq"`
+	Priority   string      `xml:"priority"`
+	Images     []Image     `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+	Videos     []Video     `xml:"http://www.google.com/schemas/sitemap-video/1.1 video"`
+	News       *News       `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Alternates []Alternate `xml:"http://www.w3.org/1999/xhtml link"`
+}
+
+var rawLocPattern = regexp.MustCompile(`<loc(?:\s[^>]*)?>([\s\S]*?)</loc\s*>`)
+
+var validChangeFreqs = map[string]bool{
+	"always":  true,
+	"hourly":  true,
+	"daily":   true,
+	"weekly":  true,
+	"monthly": true,
+	"yearly":  true,
+	"never":   true,
+}
+
+// CheckConformance streams a sitemap or sitemap index document from r and
+// checks it against the sitemaps.org protocol: the full rule set with
+// opts.Strict, otherwise only the ValidateURL checks. Unlike Validate it does
+// not stop at the first problem: every finding is returned in document order.
+// An error is only returned if reading r fails or ctx is cancelled.
+func (v *Validator) CheckConformance(ctx context.Context, r io.Reader, opts ConformanceOptions) ([]Finding, error) {
+	findings, _, err := v.check(ctx, r, opts)
+	return findings, err
+}
+
+// check runs the document checks and returns the findings and number of entries
+func (v *Validator) check(ctx context.Context, r io.Reader, opts ConformanceOptions) ([]Finding, int, error) {
+	if opts.MaxURLs == 0 {
+		opts.MaxURLs = MaxURLsPerSitemap
+	}
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = MaxSitemapBytes
+	}
+	if opts.MaxURLLength == 0 {
+		opts.MaxURLLength = MaxURLLength
+	}
+
+	check := &conformanceCheck{
+		opts:      opts,
+		validator: NewValidator(),
+		seen:      make(map[string]int),
+		src:       &windowReader{r: r},
+	}
+	check.base = baseLocation(opts.SitemapLoc)
+
+	if err := check.run(ctx); err != nil {
+		return check.findings, check.count, err
+	}
+	return check.findings, check.count, nil
+}
+
+// documentPosition is used for findings about the document as a whole
+var documentPosition = position{index: -1}
+
+type conformanceCheck struct {
+	opts      ConformanceOptions
+	validator *Validator
+	base      *url.URL
+	src       *windowReader
+	seen      map[string]int
+	count     int
+	findings  []Finding
+}
+
+func (c *conformanceCheck) add(rule string, severity Severity, pos position, format string, args ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Index:    pos.index,
+		Offset:   pos.offset,
+		Line:     pos.line,
+		Column:   pos.column,
+	})
+}
+
+// positionOf returns the position of the next token the decoder will return
+func positionOf(decoder *xml.Decoder, index int) position {
+	line, column := decoder.InputPos()
+	return position{index: index, offset: decoder.InputOffset(), line: line, column: column}
+}
+
+func (c *conformanceCheck) run(ctx context.Context) error {
+	decoder := xml.NewDecoder(c.src)
+
+	// Locate the root element and check its namespace
+	var element string
+	for element == "" {
+		pos := positionOf(decoder, -1)
+		token, err := decoder.Token()
+		if err != nil {
+			return c.readError(err, pos)
+		}
+		se, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "urlset":
+			element = "url"
+		case "sitemapindex":
+			element = "sitemap"
+		default:
+			c.add(RuleWellFormed, SeverityError, pos, "unknown root element <%s>", se.Name.Local)
+			return nil
+		}
+		if c.opts.Strict && se.Name.Space != NamespaceSitemap {
+			c.add(RuleNamespace, SeverityError, pos, "root element must declare xmlns=%q, got %q", NamespaceSitemap, se.Name.Space)
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		pos := positionOf(decoder, c.count)
+		c.src.discard(pos.offset)
+
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return c.readError(err, pos)
+		}
+
+		se, ok := token.(xml.StartElement)
+		if !ok {
+			if _, isEnd := token.(xml.EndElement); isEnd {
+				break
+			}
+			continue
+		}
+		if se.Name.Local != element {
+			if err := decoder.Skip(); err != nil {
+				return c.readError(err, pos)
+			}
+			continue
+		}
+
+		var entry conformanceEntry
+		if err := decoder.DecodeElement(&entry, &se); err != nil {
+			return c.readError(err, pos)
+		}
+		raw := c.src.window(pos.offset, decoder.InputOffset())
+
+		c.checkEntry(&entry, raw, pos)
+		c.count++
+	}
+
+	// Drain anything after the root element so the size check sees the whole document
+	if _, err := io.Copy(io.Discard, c.src); err != nil {
+		return err
+	}
+
+	if !c.opts.Strict {
+		return nil
+	}
+	if c.count > c.opts.MaxURLs {
+		c.add(RuleURLLimit, SeverityError, documentPosition, "document contains %d <%s> entries, the limit is %d", c.count, element, c.opts.MaxURLs)
+	}
+	if c.src.total > c.opts.MaxBytes {
+		c.add(RuleSizeLimit, SeverityError, documentPosition, "document is %d bytes uncompressed, the limit is %d", c.src.total, c.opts.MaxBytes)
+	}
+
+	return nil
+}
+
+// readError records a syntax error as a finding; other errors abort the check
+func (c *conformanceCheck) readError(err error, pos position) error {
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		if syntaxErr != nil {
+			// Point at the line the decoder actually failed on
+			pos.line, pos.column = syntaxErr.Line, 0
+		}
+		c.add(RuleWellFormed, SeverityError, pos, "document is not well-formed XML: %v", err)
+		return nil
+	}
+	return err
+}
+
+func (c *conformanceCheck) checkEntry(entry *conformanceEntry, raw []byte, pos position) {
+	loc := strings.TrimSpace(entry.Loc)
+	if loc == "" {
+		c.add(RuleLocMissing, SeverityError, pos, "missing <loc> element")
+	} else if c.opts.Strict {
+		c.checkLoc(loc, raw, pos)
+	} else if _, err := url.Parse(loc); err != nil {
+		c.add(RuleLocFormat, SeverityError, pos, "invalid URL format: %v", err)
+	}
+
+	if c.opts.Strict && entry.LastMod != "" {
+		if _, err := ParseW3CDate(strings.TrimSpace(entry.LastMod)); err != nil {
+			c.add(RuleLastModFormat, SeverityError, pos, "lastmod %q is not a W3C datetime", entry.LastMod)
+		}
+	}
+
+	if entry.Priority != "" {
+		p, err := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64)
+		if err != nil || p < 0.0 || p > 1.0 {
+			c.add(RulePriorityRange, SeverityError, pos, "priority must be between 0.0 and 1.0, got %q", entry.Priority)
+		}
+	}
+
+	if entry.ChangeFreq != "" && !validChangeFreqs[strings.TrimSpace(entry.ChangeFreq)] {
+		c.add(RuleChangeFreq, SeverityError, pos, "invalid changefreq value: %s", entry.ChangeFreq)
+	}
+
+	u := URL{Images: entry.Images, Videos: entry.Videos, News: entry.News, Alternates: entry.Alternates}
+	if err := c.validator.ValidateExtensions(&u); err != nil {
+		c.add(RuleExtension, SeverityError, pos, "%v", err)
+	}
+}
+
+func (c *conformanceCheck) checkLoc(loc string, raw []byte, pos position) {
+	if len(loc) > c.opts.MaxURLLength {
+		c.add(RuleLocLength, SeverityError, pos, "loc is %d characters long, the limit is %d", len(loc), c.opts.MaxURLLength)
+	}
+
+	if first, exists := c.seen[loc]; exists {
+		c.add(RuleLocDuplicate, SeverityWarning, pos, "duplicate loc %s (first seen at entry %d)", loc, first)
+	} else {
+		c.seen[loc] = pos.index
+	}
+
+	if match := rawLocPattern.FindSubmatch(raw); match != nil {
+		text := string(match[1])
+		if strings.ContainsAny(text, `'">`) {
+			c.add(RuleLocEscaping, SeverityWarning, pos, "loc contains unescaped characters; use &apos; &quot; and &gt; entities")
+		}
+	}
+
+	for _, r := range loc {
+		if r <= ' ' || r > '~' {
+			c.add(RuleLocEncoding, SeverityWarning, pos, "loc contains whitespace or non-ASCII characters that should be percent-encoded")
+			break
+		}
+	}
+
+	parsed, err := url.Parse(loc)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		c.add(RuleLocAbsolute, SeverityError, pos, "loc %q is not an absolute URL", loc)
+		return
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		c.add(RuleLocAbsolute, SeverityError, pos, "loc %q must use http or https", loc)
+		return
+	}
+
+	if c.base == nil {
+		return
+	}
+	if !strings.EqualFold(parsed.Hostname(), c.base.Hostname()) {
+		c.add(RuleLocHost, SeverityError, pos, "loc host %s does not match sitemap host %s", parsed.Hostname(), c.base.Hostname())
+		return
+	}
+	if dir := path.Dir(c.base.Path); dir != "/" && dir != "." && !strings.HasPrefix(parsed.Path, dir+"/") {
+		c.add(RuleLocPath, SeverityWarning, pos, "loc %s is outside the sitemap directory %s/", loc, dir)
+	}
+}
+
+// baseLocation returns the parsed sitemap location when it is an http(s) URL
+func baseLocation(loc string) *url.URL {
+	parsed, err := url.Parse(loc)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil
+	}
+	return parsed
+}
+
+// windowReader retains the bytes read since the last discard so the raw text
+// of the element being decoded can be inspected without buffering the document
+type windowReader struct {
+	r     io.Reader
+	buf   []byte
+	base  int64 // document offset of buf[0]
+	total int64
+}
+
+func (w *windowReader) Read(p []byte) (int, error) {
+	n, err := w.r.Read(p)
+	w.buf = append(w.buf, p[:n]...)
+	w.total += int64(n)
+	return n, err
+}
+
+// window returns the raw bytes between two document offsets
+func (w *windowReader) window(start, end int64) []byte {
+	if start < w.base || end > w.base+int64(len(w.buf)) || start > end {
+		return nil
+	}
+	return w.buf[start-w.base : end-w.base]
+}
+
+// discard drops retained bytes before offset
+func (w *windowReader) discard(offset int64) {
+	if offset <= w.base {
+		return
+	}
+	drop := offset - w.base
+	if drop > int64(len(w.buf)) {
+		drop = int64(len(w.buf))
+	}
+	w.buf = append(w.buf[:0], w.buf[drop:]...)
+	w.base += drop
+}
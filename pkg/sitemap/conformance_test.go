@@ -0,0 +1,206 @@
+package sitemap
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// urlsetDoc wraps url elements in a urlset with the sitemap and image namespaces
+func urlsetDoc(urls ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+` + strings.Join(urls, "\n") + `
+</urlset>`
+}
+
+func TestCheckConformance(t *testing.T) {
+	type finding struct {
+		rule     string
+		severity Severity
+		index    int
+	}
+	strict := ConformanceOptions{Strict: true}
+
+	tests := []struct {
+		name string
+		doc  string
+		opts ConformanceOptions
+		want []finding
+	}{
+		{
+			name: "conforming",
+			doc: urlsetDoc(
+				`<url><loc>https://example.com/</loc><lastmod>2024-01-01</lastmod><changefreq>daily</changefreq><priority>1.0</priority></url>`,
+				`<url><loc>https://example.com/a?x=1&amp;y=2</loc><image:image><image:loc>https://example.com/a.jpg</image:loc></image:image></url>`,
+			),
+			opts: ConformanceOptions{Strict: true, SitemapLoc: "https://example.com/sitemap.xml"},
+		},
+		{
+			name: "unknown root",
+			doc:  `<rss></rss>`,
+			opts: strict,
+			want: []finding{{RuleWellFormed, SeverityError, -1}},
+		},
+		{
+			name: "malformed entry",
+			doc:  urlsetDoc(`<url><loc>https://example.com/</loc></url>`, `<url><loc>https://example.com/a</url>`),
+			opts: strict,
+			want: []finding{{RuleWellFormed, SeverityError, 1}},
+		},
+		{
+			name: "missing namespace",
+			doc:  `<urlset><url><loc>https://example.com/</loc></url></urlset>`,
+			opts: strict,
+			want: []finding{{RuleNamespace, SeverityError, -1}},
+		},
+		{
+			name: "url limit",
+			doc:  urlsetDoc(`<url><loc>https://example.com/a</loc></url>`, `<url><loc>https://example.com/b</loc></url>`),
+			opts: ConformanceOptions{Strict: true, MaxURLs: 1},
+			want: []finding{{RuleURLLimit, SeverityError, -1}},
+		},
+		{
+			name: "size limit",
+			doc:  urlsetDoc(`<url><loc>https://example.com/a</loc></url>`),
+			opts: ConformanceOptions{Strict: true, MaxBytes: 100},
+			want: []finding{{RuleSizeLimit, SeverityError, -1}},
+		},
+		{
+			name: "missing loc",
+			doc:  urlsetDoc(`<url><loc>https://example.com/</loc></url>`, `<url><lastmod>2024-01-01</lastmod></url>`),
+			opts: strict,
+			want: []finding{{RuleLocMissing, SeverityError, 1}},
+		},
+		{
+			name: "relative loc",
+			doc:  urlsetDoc(`<url><loc>/about</loc></url>`),
+			opts: strict,
+			want: []finding{{RuleLocAbsolute, SeverityError, 0}},
+		},
+		{
+			name: "loc scheme",
+			doc:  urlsetDoc(`<url><loc>ftp://example.com/a</loc></url>`),
+			opts: strict,
+			want: []finding{{RuleLocAbsolute, SeverityError, 0}},
+		},
+		{
+			name: "loc host",
+			doc:  urlsetDoc(`<url><loc>https://EXAMPLE.com/a</loc></url>`, `<url><loc>https://cdn.example.com/a</loc></url>`),
+			opts: ConformanceOptions{Strict: true, SitemapLoc: "https://example.com/sitemap.xml"},
+			want: []finding{{RuleLocHost, SeverityError, 1}},
+		},
+		{
+			name: "loc path",
+			doc:  urlsetDoc(`<url><loc>https://example.com/blog/a</loc></url>`, `<url><loc>https://example.com/about</loc></url>`),
+			opts: ConformanceOptions{Strict: true, SitemapLoc: "https://example.com/blog/sitemap.xml"},
+			want: []finding{{RuleLocPath, SeverityWarning, 1}},
+		},
+		{
+			name: "loc length",
+			doc: urlsetDoc(
+				`<url><loc>https://example.com/`+strings.Repeat("a", MaxURLLength-len("https://example.com/"))+`</loc></url>`,
+				`<url><loc>https://example.com/`+strings.Repeat("a", MaxURLLength-len("https://example.com/")+1)+`</loc></url>`,
+			),
+			opts: strict,
+			want: []finding{{RuleLocLength, SeverityError, 1}},
+		},
+		{
+			name: "loc escaping",
+			doc:  urlsetDoc(`<url><loc>https://example.com/it's</loc></url>`, `<url><loc>https://example.com/we&apos;re</loc></url>`),
+			opts: strict,
+			want: []finding{{RuleLocEscaping, SeverityWarning, 0}},
+		},
+		{
+			name: "loc encoding",
+			doc:  urlsetDoc(`<url><loc>https://example.com/straße</loc></url>`, `<url><loc>https://example.com/stra%C3%9Fe</loc></url>`),
+			opts: strict,
+			want: []finding{{RuleLocEncoding, SeverityWarning, 0}},
+		},
+		{
+			name: "duplicate loc",
+			doc:  urlsetDoc(`<url><loc>https://example.com/a</loc></url>`, `<url><loc>https://example.com/b</loc></url>`, `<url><loc> https://example.com/a </loc></url>`),
+			opts: strict,
+			want: []finding{{RuleLocDuplicate, SeverityWarning, 2}},
+		},
+		{
+			name: "lastmod",
+			doc:  urlsetDoc(`<url><loc>https://example.com/</loc><lastmod>yesterday</lastmod></url>`),
+			opts: strict,
+			want: []finding{{RuleLastModFormat, SeverityError, 0}},
+		},
+		{
+			name: "priority",
+			doc:  urlsetDoc(`<url><loc>https://example.com/a</loc><priority>1.5</priority></url>`, `<url><loc>https://example.com/b</loc><priority>high</priority></url>`),
+			opts: strict,
+			want: []finding{{RulePriorityRange, SeverityError, 0}, {RulePriorityRange, SeverityError, 1}},
+		},
+		{
+			name: "changefreq",
+			doc:  urlsetDoc(`<url><loc>https://example.com/</loc><changefreq>sometimes</changefreq></url>`),
+			opts: strict,
+			want: []finding{{RuleChangeFreq, SeverityError, 0}},
+		},
+		{
+			name: "extension",
+			doc:  urlsetDoc(`<url><loc>https://example.com/</loc><image:image><image:title>x</image:title></image:image></url>`),
+			opts: strict,
+			want: []finding{{RuleExtension, SeverityError, 0}},
+		},
+		{
+			name: "index entries",
+			doc: `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+<sitemap><loc>https://example.com/a.xml</loc></sitemap>
+<sitemap><lastmod>2024-01-01</lastmod></sitemap>
+</sitemapindex>`,
+			opts: strict,
+			want: []finding{{RuleLocMissing, SeverityError, 1}},
+		},
+		{
+			name: "several findings per entry",
+			doc:  urlsetDoc(`<url><loc>/a</loc><lastmod>soon</lastmod><priority>2</priority></url>`),
+			opts: strict,
+			want: []finding{{RuleLocAbsolute, SeverityError, 0}, {RuleLastModFormat, SeverityError, 0}, {RulePriorityRange, SeverityError, 0}},
+		},
+		{
+			// Without Strict only the ValidateURL checks apply
+			name: "lenient",
+			doc: `<urlset>
+<url><loc>/relative</loc><lastmod>yesterday</lastmod></url>
+<url><loc>http://[::1</loc><changefreq>sometimes</changefreq></url>
+<url><loc>https://example.com/a</loc></url>
+<url><loc>https://example.com/a</loc><priority>-1</priority></url>
+</urlset>`,
+			want: []finding{
+				{RuleLocFormat, SeverityError, 1},
+				{RuleChangeFreq, SeverityError, 1},
+				{RulePriorityRange, SeverityError, 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := NewValidator().CheckConformance(context.Background(), strings.NewReader(tt.doc), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []finding
+			for _, f := range findings {
+				got = append(got, finding{f.Rule, f.Severity, f.Index})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckConformance = %+v\nwant %+v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConformanceCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewValidator().CheckConformance(ctx, strings.NewReader(urlsetDoc(`<url><loc>https://example.com/</loc></url>`)), ConformanceOptions{}); err == nil {
+		t.Error("CheckConformance with a cancelled context succeeded")
+	}
+}
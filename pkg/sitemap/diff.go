@@ -0,0 +1,165 @@
+package sitemap
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Fields compared between two versions of a URL
+const (
+	FieldLastMod    = "lastmod"
+	FieldChangeFreq = "changefreq"
+	FieldPriority   = "priority"
+)
+
+// FieldChange is a single field whose value differs between two sitemaps.
+// An empty value means the field was absent.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// URLChange lists the fields of a URL that changed between two sitemaps
+type URLChange struct {
+	Loc    string        `json:"loc"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// Diff is the result of comparing two sitemaps by location
+type Diff struct {
+	BaseCount    int         `json:"base_count"`
+	CompareCount int         `json:"compare_count"`
+	Added        []string    `json:"added"`
+	Removed      []string    `json:"removed"`
+	Changed      []URLChange `json:"changed"`
+	Unchanged    []string    `json:"unchanged"`
+}
+
+// Differ compares two sitemaps by location and reports field-level changes.
+// Only the base sitemap is indexed; the compared sitemap is consumed as a
+// stream, so either side can be fed from Parser.Stream or Resolver.Stream.
+//
+// Locations are matched after normalization by Normalizer, if set, but the
+// diff lists them as they appear in the sitemaps.
+type Differ struct {
+	Normalizer *Normalizer
+
+	base  map[string]*baseURL
+	order []string
+	added map[string]bool // keys of added URLs, so duplicates are listed once
+	diff  Diff
+}
+
+// baseURL holds the compared fields of a base URL
+type baseURL struct {
+	loc        string
+	lastMod    string
+	changeFreq string
+	priority   string
+	seen       bool
+}
+
+// NewDiffer creates a new differ
+func NewDiffer() *Differ {
+	return &Differ{base: make(map[string]*baseURL), added: make(map[string]bool)}
+}
+
+// AddBase records a URL of the base (older) sitemap. The first occurrence of
+// a duplicated location wins.
+func (d *Differ) AddBase(u URL) error {
+	return d.AddBaseKey(d.Normalizer.Normalize(u.Loc), u)
+}
+
+// AddBaseKey is AddBase for a URL whose location was already normalized to key
+func (d *Differ) AddBaseKey(key string, u URL) error {
+	d.diff.BaseCount++
+	if _, exists := d.base[key]; exists {
+		return nil
+	}
+	d.base[key] = &baseURL{
+		loc:        u.Loc,
+		lastMod:    strings.TrimSpace(u.LastMod),
+		changeFreq: strings.TrimSpace(u.ChangeFreq),
+		priority:   formatPriority(u.Priority),
+	}
+	d.order = append(d.order, key)
+	return nil
+}
+
+// AddCompare records a URL of the compared (newer) sitemap
+func (d *Differ) AddCompare(u URL) error {
+	return d.AddCompareKey(d.Normalizer.Normalize(u.Loc), u)
+}
+
+// AddCompareKey is AddCompare for a URL whose location was already normalized
+// to key. Like the base side, only the first occurrence of a duplicated
+// location is compared.
+func (d *Differ) AddCompareKey(key string, u URL) error {
+	d.diff.CompareCount++
+
+	base, exists := d.base[key]
+	if !exists {
+		if !d.added[key] {
+			d.added[key] = true
+			d.diff.Added = append(d.diff.Added, u.Loc)
+		}
+		return nil
+	}
+	if base.seen {
+		return nil
+	}
+	base.seen = true
+
+	var fields []FieldChange
+	if lastMod := strings.TrimSpace(u.LastMod); !sameLastMod(base.lastMod, lastMod) {
+		fields = append(fields, FieldChange{Field: FieldLastMod, Old: base.lastMod, New: lastMod})
+	}
+	if changeFreq := strings.TrimSpace(u.ChangeFreq); !strings.EqualFold(base.changeFreq, changeFreq) {
+		fields = append(fields, FieldChange{Field: FieldChangeFreq, Old: base.changeFreq, New: changeFreq})
+	}
+	if priority := formatPriority(u.Priority); base.priority != priority {
+		fields = append(fields, FieldChange{Field: FieldPriority, Old: base.priority, New: priority})
+	}
+
+	if len(fields) > 0 {
+		d.diff.Changed = append(d.diff.Changed, URLChange{Loc: u.Loc, Fields: fields})
+	} else {
+		d.diff.Unchanged = append(d.diff.Unchanged, u.Loc)
+	}
+	return nil
+}
+
+// Result returns the diff. Removed locations are listed in base order.
+func (d *Differ) Result() *Diff {
+	result := d.diff
+	result.Removed = nil
+	for _, key := range d.order {
+		if base := d.base[key]; !base.seen {
+			result.Removed = append(result.Removed, base.loc)
+		}
+	}
+	return &result
+}
+
+// sameLastMod compares two lastmod values as instants when both are valid W3C
+// datetimes, so 2024-01-01 and 2024-01-01T00:00:00Z are the same
+func sameLastMod(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ta, errA := ParseW3CDate(a)
+	tb, errB := ParseW3CDate(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return ta.Equal(tb)
+}
+
+// formatPriority renders a priority for comparison; 0 means absent
+func formatPriority(priority float64) string {
+	if priority == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(priority, 'f', -1, 64)
+}
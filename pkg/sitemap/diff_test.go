@@ -0,0 +1,192 @@
+package sitemap
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer *Normalizer
+		base       []URL
+		compare    []URL
+		want       Diff
+	}{
+		{
+			name: "empty",
+			want: Diff{},
+		},
+		{
+			name:    "added and removed",
+			base:    []URL{{Loc: "https://example.com/b"}, {Loc: "https://example.com/a"}, {Loc: "https://example.com/c"}},
+			compare: []URL{{Loc: "https://example.com/a"}, {Loc: "https://example.com/d"}},
+			want: Diff{
+				BaseCount:    3,
+				CompareCount: 2,
+				Added:        []string{"https://example.com/d"},
+				// In base order
+				Removed:   []string{"https://example.com/b", "https://example.com/c"},
+				Unchanged: []string{"https://example.com/a"},
+			},
+		},
+		{
+			name: "changed fields",
+			base: []URL{
+				{Loc: "https://example.com/a", LastMod: "2024-01-01", ChangeFreq: "daily", Priority: 0.5},
+				{Loc: "https://example.com/b", Priority: 0.8},
+			},
+			compare: []URL{
+				{Loc: "https://example.com/a", LastMod: "2024-02-01", ChangeFreq: "weekly", Priority: 0.5},
+				{Loc: "https://example.com/b"},
+			},
+			want: Diff{
+				BaseCount:    2,
+				CompareCount: 2,
+				Changed: []URLChange{
+					{Loc: "https://example.com/a", Fields: []FieldChange{
+						{Field: FieldLastMod, Old: "2024-01-01", New: "2024-02-01"},
+						{Field: FieldChangeFreq, Old: "daily", New: "weekly"},
+					}},
+					// A missing priority is an empty value, not 0
+					{Loc: "https://example.com/b", Fields: []FieldChange{
+						{Field: FieldPriority, Old: "0.8", New: ""},
+					}},
+				},
+			},
+		},
+		{
+			name: "equivalent values",
+			base: []URL{
+				{Loc: "https://example.com/a", LastMod: "2024-01-01", ChangeFreq: "Daily"},
+				{Loc: "https://example.com/b", LastMod: "2024-01-01T01:00:00+01:00"},
+				{Loc: "https://example.com/c", LastMod: " 2024-01-01 "},
+			},
+			compare: []URL{
+				{Loc: "https://example.com/a", LastMod: "2024-01-01T00:00:00Z", ChangeFreq: "daily"},
+				{Loc: "https://example.com/b", LastMod: "2024-01-01T00:00:00Z"},
+				{Loc: "https://example.com/c", LastMod: "2024-01-01"},
+			},
+			want: Diff{
+				BaseCount:    3,
+				CompareCount: 3,
+				Unchanged:    []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"},
+			},
+		},
+		{
+			name:    "invalid lastmod",
+			base:    []URL{{Loc: "https://example.com/a", LastMod: "yesterday"}},
+			compare: []URL{{Loc: "https://example.com/a", LastMod: "today"}},
+			want: Diff{
+				BaseCount:    1,
+				CompareCount: 1,
+				Changed: []URLChange{{Loc: "https://example.com/a", Fields: []FieldChange{
+					{Field: FieldLastMod, Old: "yesterday", New: "today"},
+				}}},
+			},
+		},
+		{
+			name: "duplicates",
+			base: []URL{
+				{Loc: "https://example.com/a", Priority: 0.5},
+				{Loc: "https://example.com/a", Priority: 0.9},
+			},
+			compare: []URL{
+				{Loc: "https://example.com/a", Priority: 0.5},
+				{Loc: "https://example.com/a", Priority: 0.1},
+			},
+			// The first occurrence on each side is compared; counts include duplicates
+			want: Diff{
+				BaseCount:    2,
+				CompareCount: 2,
+				Unchanged:    []string{"https://example.com/a"},
+			},
+		},
+		{
+			name: "duplicate added",
+			base: []URL{{Loc: "https://example.com/a"}},
+			compare: []URL{
+				{Loc: "https://example.com/b"},
+				{Loc: "https://example.com/a"},
+				{Loc: "https://example.com/b", Priority: 0.5},
+			},
+			// A new location is added once however often it repeats
+			want: Diff{
+				BaseCount:    1,
+				CompareCount: 3,
+				Added:        []string{"https://example.com/b"},
+				Unchanged:    []string{"https://example.com/a"},
+			},
+		},
+		{
+			name:       "normalized duplicate added",
+			normalizer: NewNormalizer(AllNormalizeRules, nil),
+			compare:    []URL{{Loc: "https://example.com/b/"}, {Loc: "http://www.example.com/b"}},
+			want: Diff{
+				CompareCount: 2,
+				Added:        []string{"https://example.com/b/"},
+			},
+		},
+		{
+			name:       "normalized locations",
+			normalizer: NewNormalizer(AllNormalizeRules, nil),
+			base:       []URL{{Loc: "http://www.example.com/a/"}, {Loc: "https://example.com/b?utm_source=x"}},
+			compare:    []URL{{Loc: "https://example.com/a"}, {Loc: "https://example.com/b"}},
+			// Listed as they appear in the compared sitemap
+			want: Diff{
+				BaseCount:    2,
+				CompareCount: 2,
+				Unchanged:    []string{"https://example.com/a", "https://example.com/b"},
+			},
+		},
+		{
+			name:    "without normalization",
+			base:    []URL{{Loc: "http://www.example.com/a/"}},
+			compare: []URL{{Loc: "https://example.com/a"}},
+			want: Diff{
+				BaseCount:    1,
+				CompareCount: 1,
+				Added:        []string{"https://example.com/a"},
+				Removed:      []string{"http://www.example.com/a/"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDiffer()
+			d.Normalizer = tt.normalizer
+			for _, u := range tt.base {
+				if err := d.AddBase(u); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, u := range tt.compare {
+				if err := d.AddCompare(u); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := d.Result(); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Result() = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDifferResultIsRepeatable(t *testing.T) {
+	d := NewDiffer()
+	d.AddBase(URL{Loc: "https://example.com/a"})
+	d.AddBase(URL{Loc: "https://example.com/b"})
+	first := d.Result()
+
+	// Result doesn't consume the differ, and later URLs still count
+	d.AddCompare(URL{Loc: "https://example.com/a"})
+	second := d.Result()
+
+	if len(first.Removed) != 2 {
+		t.Errorf("first Result() removed %v, want both base URLs", first.Removed)
+	}
+	if want := []string{"https://example.com/b"}; !reflect.DeepEqual(second.Removed, want) {
+		t.Errorf("second Result() removed %v, want %v", second.Removed, want)
+	}
+}
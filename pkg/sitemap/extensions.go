@@ -0,0 +1,64 @@
+package sitemap
+
+// XML namespaces of the sitemap protocol extensions understood by the parser
+const (
+	NamespaceSitemap = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	NamespaceImage   = "http://www.google.com/schemas/sitemap-image/1.1"
+	NamespaceVideo   = "http://www.google.com/schemas/sitemap-video/1.1"
+	NamespaceNews    = "http://www.google.com/schemas/sitemap-news/0.9"
+	NamespaceXHTML   = "http://www.w3.org/1999/xhtml"
+)
+
+// Image represents an <image:image> element
+type Image struct {
+	Loc         string `xml:"loc"`
+	Caption     string `xml:"caption"`
+	Title       string `xml:"title"`
+	GeoLocation string `xml:"geo_location"`
+	License     string `xml:"license"`
+}
+
+// Video represents a <video:video> element. Numeric fields are kept as text so
+// a malformed value is reported by the validator instead of failing the parse.
+type Video struct {
+	ThumbnailLoc         string   `xml:"thumbnail_loc"`
+	Title                string   `xml:"title"`
+	Description          string   `xml:"description"`
+	ContentLoc           string   `xml:"content_loc"`
+	PlayerLoc            string   `xml:"player_loc"`
+	Duration             string   `xml:"duration"`
+	ExpirationDate       string   `xml:"expiration_date"`
+	Rating               string   `xml:"rating"`
+	ViewCount            string   `xml:"view_count"`
+	PublicationDate      string   `xml:"publication_date"`
+	FamilyFriendly       string   `xml:"family_friendly"`
+	RequiresSubscription string   `xml:"requires_subscription"`
+	Uploader             string   `xml:"uploader"`
+	Live                 string   `xml:"live"`
+	Tags                 []string `xml:"tag"`
+}
+
+// News represents a <news:news> element
+type News struct {
+	Publication     NewsPublication `xml:"publication"`
+	PublicationDate string          `xml:"publication_date"`
+	Title           string          `xml:"title"`
+}
+
+// NewsPublication identifies the publication a news article belongs to
+type NewsPublication struct {
+	Name     string `xml:"name"`
+	Language string `xml:"language"`
+}
+
+// Alternate represents an <xhtml:link rel="alternate" hreflang="..."> annotation
+type Alternate struct {
+	Rel      string `xml:"rel,attr"`
+	Hreflang string `xml:"hreflang,attr"`
+	Href     string `xml:"href,attr"`
+}
+
+// HasExtensions reports whether the URL carries any protocol extension data
+func (u *URL) HasExtensions() bool {
+	return len(u.Images) > 0 || len(u.Videos) > 0 || u.News != nil || len(u.Alternates) > 0
+}
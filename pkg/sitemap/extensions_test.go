@@ -0,0 +1,181 @@
+package sitemap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseExtensions(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string // children of <url> besides <loc>
+		want URL
+	}{
+		{
+			name: "image",
+			url: `<image:image>
+				<image:loc>https://example.com/a.jpg</image:loc>
+				<image:caption>A</image:caption>
+				<image:title>Title</image:title>
+			</image:image>
+			<image:image><image:loc>https://example.com/b.jpg</image:loc></image:image>`,
+			want: URL{Images: []Image{
+				{Loc: "https://example.com/a.jpg", Caption: "A", Title: "Title"},
+				{Loc: "https://example.com/b.jpg"},
+			}},
+		},
+		{
+			name: "video",
+			url: `<video:video>
+				<video:thumbnail_loc>https://example.com/t.jpg</video:thumbnail_loc>
+				<video:title>Title</video:title>
+				<video:description>Description</video:description>
+				<video:content_loc>https://example.com/v.mp4</video:content_loc>
+				<video:duration>600</video:duration>
+				<video:rating>4.2</video:rating>
+				<video:tag>a</video:tag>
+				<video:tag>b</video:tag>
+			</video:video>`,
+			want: URL{Videos: []Video{{
+				ThumbnailLoc: "https://example.com/t.jpg",
+				Title:        "Title",
+				Description:  "Description",
+				ContentLoc:   "https://example.com/v.mp4",
+				Duration:     "600",
+				Rating:       "4.2",
+				Tags:         []string{"a", "b"},
+			}}},
+		},
+		{
+			name: "news",
+			url: `<news:news>
+				<news:publication><news:name>Example Times</news:name><news:language>en</news:language></news:publication>
+				<news:publication_date>2024-01-01</news:publication_date>
+				<news:title>Headline</news:title>
+			</news:news>`,
+			want: URL{News: &News{
+				Publication:     NewsPublication{Name: "Example Times", Language: "en"},
+				PublicationDate: "2024-01-01",
+				Title:           "Headline",
+			}},
+		},
+		{
+			name: "hreflang",
+			url: `<xhtml:link rel="alternate" hreflang="de" href="https://example.com/de/"/>
+			<xhtml:link rel="alternate" hreflang="x-default" href="https://example.com/"/>`,
+			want: URL{Alternates: []Alternate{
+				{Rel: "alternate", Hreflang: "de", Href: "https://example.com/de/"},
+				{Rel: "alternate", Hreflang: "x-default", Href: "https://example.com/"},
+			}},
+		},
+		{
+			// Elements are matched by namespace, not by prefix
+			name: "other prefix",
+			url:  `<img:image xmlns:img="http://www.google.com/schemas/sitemap-image/1.1"><img:loc>https://example.com/a.jpg</img:loc></img:image>`,
+			want: URL{Images: []Image{{Loc: "https://example.com/a.jpg"}}},
+		},
+		{
+			name: "unknown namespace",
+			url:  `<other:image xmlns:other="https://example.com/ns"><other:loc>https://example.com/a.jpg</other:loc></other:image>`,
+			want: URL{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := `<urlset xmlns="` + NamespaceSitemap + `"
+				xmlns:image="` + NamespaceImage + `"
+				xmlns:video="` + NamespaceVideo + `"
+				xmlns:news="` + NamespaceNews + `"
+				xmlns:xhtml="` + NamespaceXHTML + `">
+				<url><loc>https://example.com/</loc>` + tt.url + `</url></urlset>`
+			sm, err := NewParser().Parse([]byte(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sm.URLs) != 1 {
+				t.Fatalf("Parse found %d URLs, want 1", len(sm.URLs))
+			}
+			want := tt.want
+			want.Loc = "https://example.com/"
+			if got := sm.URLs[0]; !reflect.DeepEqual(got, want) {
+				t.Errorf("Parse = %+v\nwant %+v", got, want)
+			}
+			if got := sm.URLs[0].HasExtensions(); got != !reflect.DeepEqual(tt.want, URL{}) {
+				t.Errorf("HasExtensions() = %t", got)
+			}
+		})
+	}
+}
+
+func TestValidateExtensions(t *testing.T) {
+	video := Video{
+		ThumbnailLoc: "https://example.com/t.jpg",
+		Title:        "Title",
+		Description:  "Description",
+		ContentLoc:   "https://example.com/v.mp4",
+	}
+	withVideo := func(edit func(*Video)) URL {
+		v := video
+		edit(&v)
+		return URL{Videos: []Video{v}}
+	}
+	news := News{
+		Publication:     NewsPublication{Name: "Example Times", Language: "en"},
+		PublicationDate: "2024-01-01T10:00:00Z",
+		Title:           "Headline",
+	}
+	withNews := func(edit func(*News)) URL {
+		n := news
+		edit(&n)
+		return URL{News: &n}
+	}
+
+	tests := []struct {
+		name string
+		url  URL
+		want string // substring of the error, "" for a valid URL
+	}{
+		{"no extensions", URL{}, ""},
+		{"image", URL{Images: []Image{{Loc: "https://example.com/a.jpg"}}}, ""},
+		{"image without loc", URL{Images: []Image{{Loc: "https://example.com/a.jpg"}, {Title: "x"}}}, "invalid <image:image> 1: missing <image:loc> element"},
+		{"relative image loc", URL{Images: []Image{{Loc: "/a.jpg"}}}, "image:loc must be an absolute URL"},
+		{"video", withVideo(func(*Video) {}), ""},
+		{"video with player only", withVideo(func(v *Video) { v.ContentLoc, v.PlayerLoc = "", "https://example.com/player" }), ""},
+		{"video without thumbnail", withVideo(func(v *Video) { v.ThumbnailLoc = "" }), "missing <video:thumbnail_loc> element"},
+		{"video without title", withVideo(func(v *Video) { v.Title = "" }), "missing <video:title> element"},
+		{"video without description", withVideo(func(v *Video) { v.Description = "" }), "missing <video:description> element"},
+		{"video without content", withVideo(func(v *Video) { v.ContentLoc = "" }), "one of <video:content_loc> or <video:player_loc> is required"},
+		{"video duration", withVideo(func(v *Video) { v.Duration = "28800" }), ""},
+		{"video too long", withVideo(func(v *Video) { v.Duration = "28801" }), "video:duration must be between 1 and 28800 seconds"},
+		{"video duration not a number", withVideo(func(v *Video) { v.Duration = "10m" }), "video:duration"},
+		{"video rating", withVideo(func(v *Video) { v.Rating = "5.1" }), "video:rating must be between 0.0 and 5.0"},
+		{"video view count", withVideo(func(v *Video) { v.ViewCount = "-1" }), "video:view_count must be a non-negative integer"},
+		{"video publication date", withVideo(func(v *Video) { v.PublicationDate = "yesterday" }), "video:publication_date"},
+		{"video expiration date", withVideo(func(v *Video) { v.ExpirationDate = "2024-13-01" }), "video:expiration_date"},
+		{"news", withNews(func(*News) {}), ""},
+		{"news without name", withNews(func(n *News) { n.Publication.Name = "" }), "invalid <news:news>: missing <news:name> element"},
+		{"news without language", withNews(func(n *News) { n.Publication.Language = "" }), "missing <news:language> element"},
+		{"news without date", withNews(func(n *News) { n.PublicationDate = "" }), "missing <news:publication_date> element"},
+		{"news date", withNews(func(n *News) { n.PublicationDate = "01/01/2024" }), "news:publication_date"},
+		{"news without title", withNews(func(n *News) { n.Title = "" }), "missing <news:title> element"},
+		{"hreflang", URL{Alternates: []Alternate{{Rel: "alternate", Hreflang: "de", Href: "https://example.com/de/"}}}, ""},
+		{"hreflang rel", URL{Alternates: []Alternate{{Rel: "canonical", Hreflang: "de", Href: "https://example.com/de/"}}}, `rel must be "alternate"`},
+		{"hreflang without language", URL{Alternates: []Alternate{{Rel: "alternate", Href: "https://example.com/de/"}}}, "missing hreflang attribute"},
+		{"hreflang without href", URL{Alternates: []Alternate{{Rel: "alternate", Hreflang: "de"}}}, "missing href attribute"},
+		{"relative hreflang href", URL{Alternates: []Alternate{{Rel: "alternate", Hreflang: "de", Href: "/de/"}}}, "invalid <xhtml:link> 0: href must be an absolute URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewValidator().ValidateExtensions(&tt.url)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("ValidateExtensions = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("ValidateExtensions = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package sitemap
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// NormalizeRule names a URL normalization applied before comparing locations
+type NormalizeRule string
+
+const (
+	NormalizeTrailingSlash  NormalizeRule = "trailing_slash"  // /blog/ and /blog are the same; an empty path is /
+	NormalizeScheme         NormalizeRule = "scheme"          // http is treated as https
+	NormalizeWWW            NormalizeRule = "www"             // www.example.com is treated as example.com
+	NormalizeHostCase       NormalizeRule = "host_case"       // host names are compared lowercase
+	NormalizeDefaultPort    NormalizeRule = "default_port"    // :80 for http and :443 for https are dropped
+	NormalizeTrackingParams NormalizeRule = "tracking_params" // tracking query parameters are dropped
+)
+
+// AllNormalizeRules lists every normalization rule in the order they are applied
+var AllNormalizeRules = []NormalizeRule{
+	NormalizeHostCase,
+	NormalizeDefaultPort,
+	NormalizeScheme,
+	NormalizeWWW,
+	NormalizeTrailingSlash,
+	NormalizeTrackingParams,
+}
+
+// DefaultTrackingParams are the query parameters dropped by NormalizeTrackingParams.
+// A trailing "*" matches any parameter with that prefix.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid",
+	"dclid",
+	"fbclid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"_ga",
+	"_gl",
+}
+
+// ParseNormalizeRules parses rule names. "all" selects every rule and "none"
+// (or no names) selects none.
+func ParseNormalizeRules(names []string) ([]NormalizeRule, error) {
+	var rules []NormalizeRule
+	seen := make(map[NormalizeRule]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "", "none":
+			continue
+		case "all":
+			return append([]NormalizeRule(nil), AllNormalizeRules...), nil
+		}
+
+		rule := NormalizeRule(strings.ReplaceAll(name, "-", "_"))
+		if !isNormalizeRule(rule) {
+			return nil, fmt.Errorf("unknown normalization rule %q", name)
+		}
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func isNormalizeRule(rule NormalizeRule) bool {
+	for _, r := range AllNormalizeRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalizer rewrites URLs into a canonical form so that cosmetic differences
+// do not show up as added and removed URLs. A nil Normalizer leaves URLs as-is.
+type Normalizer struct {
+	rules          map[NormalizeRule]bool
+	trackingParams []string
+}
+
+// NewNormalizer creates a normalizer applying rules. trackingParams replaces
+// DefaultTrackingParams when not empty.
+func NewNormalizer(rules []NormalizeRule, trackingParams []string) *Normalizer {
+	if len(trackingParams) == 0 {
+		trackingParams = DefaultTrackingParams
+	}
+	n := &Normalizer{
+		rules:          make(map[NormalizeRule]bool, len(rules)),
+		trackingParams: trackingParams,
+	}
+	for _, rule := range rules {
+		n.rules[rule] = true
+	}
+	return n
+}
+
+// Rules returns the enabled rules in the order they are applied
+func (n *Normalizer) Rules() []NormalizeRule {
+	if n == nil {
+		return nil
+	}
+	var rules []NormalizeRule
+	for _, rule := range AllNormalizeRules {
+		if n.rules[rule] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Spec describes the normalizer in a form that can be stored: the enabled
+// rules and, with NormalizeTrackingParams, the parameters it drops.
+// Normalizers with the same Spec normalize every URL the same way. It is empty
+// when no rule is enabled.
+func (n *Normalizer) Spec() string {
+	rules := n.Rules()
+	if len(rules) == 0 {
+		return ""
+	}
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = string(rule)
+	}
+	spec := strings.Join(names, ",")
+	if n.rules[NormalizeTrackingParams] {
+		spec += ";" + strings.Join(n.trackingParams, ",")
+	}
+	return spec
+}
+
+// Enabled reports whether any rule is enabled
+func (n *Normalizer) Enabled() bool {
+	return n != nil && len(n.rules) > 0
+}
+
+// Normalize returns the canonical form of loc. Locations that are not
+// absolute URLs are returned unchanged.
+func (n *Normalizer) Normalize(loc string) string {
+	if !n.Enabled() {
+		return loc
+	}
+
+	u, err := url.Parse(strings.TrimSpace(loc))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return loc
+	}
+
+	host, port := u.Hostname(), u.Port()
+	if n.rules[NormalizeHostCase] {
+		host = strings.ToLower(host)
+	}
+	if n.rules[NormalizeDefaultPort] {
+		if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+			port = ""
+		}
+	}
+	if n.rules[NormalizeScheme] && u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	if n.rules[NormalizeWWW] && len(host) > 4 && strings.EqualFold(host[:4], "www.") {
+		host = host[4:]
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if n.rules[NormalizeTrailingSlash] {
+		if u.Path == "" {
+			u.Path, u.RawPath = "/", ""
+		} else if len(u.Path) > 1 && strings.HasSuffix(u.Path, "/") {
+			u.Path = strings.TrimRight(u.Path, "/")
+			u.RawPath = strings.TrimRight(u.RawPath, "/")
+			if u.Path == "" {
+				u.Path, u.RawPath = "/", ""
+			}
+		}
+	}
+
+	if n.rules[NormalizeTrackingParams] && u.RawQuery != "" {
+		u.RawQuery = n.stripTrackingParams(u.RawQuery)
+		u.ForceQuery = false
+	}
+
+	return u.String()
+}
+
+// stripTrackingParams removes tracking parameters from a raw query, keeping
+// the order and encoding of the remaining parameters
+func (n *Normalizer) stripTrackingParams(rawQuery string) string {
+	parts := strings.Split(rawQuery, "&")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(part, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if !n.isTrackingParam(key) {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+func (n *Normalizer) isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range n.trackingParams {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
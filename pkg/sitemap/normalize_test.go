@@ -0,0 +1,107 @@
+package sitemap
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	all := NewNormalizer(AllNormalizeRules, nil)
+
+	tests := []struct {
+		name       string
+		normalizer *Normalizer
+		loc        string
+		want       string
+	}{
+		{"every rule", all, "http://WWW.Example.COM:80/Blog/", "https://example.com/Blog"},
+		{"empty path", all, "https://example.com", "https://example.com/"},
+		{"only slashes", all, "https://example.com///", "https://example.com/"},
+		{"surrounding space", all, "  https://example.com/a/  ", "https://example.com/a"},
+		{"escaped path", all, "https://example.com/a%2Fb/", "https://example.com/a%2Fb"},
+		{"default https port", all, "https://example.com:443/a", "https://example.com/a"},
+		// 443 isn't the default port of http, so it stays after the scheme changes
+		{"other scheme's port", all, "http://example.com:443/a", "https://example.com:443/a"},
+		{"other port", all, "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"ipv6 host", all, "https://[::1]:443/x/", "https://[::1]/x"},
+		{"short www host", all, "https://www./a", "https://www./a"},
+		{"tracking params", all, "https://example.com/a?utm_source=x&id=1&gclid=2", "https://example.com/a?id=1"},
+		{"only tracking params", all, "https://example.com/a?utm_source=x", "https://example.com/a"},
+		{"tracking param case", all, "https://example.com/a?UTM_Medium=x&b=%20", "https://example.com/a?b=%20"},
+		{"escaped tracking param", all, "https://example.com/a?utm%5Fsource=x&b=1", "https://example.com/a?b=1"},
+		{"relative", all, "/relative/path/", "/relative/path/"},
+		{"not a url", all, "not a url", "not a url"},
+		{"nil normalizer", nil, "http://WWW.Example.COM:80/Blog/", "http://WWW.Example.COM:80/Blog/"},
+		{"no rules", NewNormalizer(nil, nil), "http://www.example.com/a/", "http://www.example.com/a/"},
+		{"scheme only", NewNormalizer([]NormalizeRule{NormalizeScheme}, nil), "http://WWW.example.com/a/", "https://WWW.example.com/a/"},
+		{"www only", NewNormalizer([]NormalizeRule{NormalizeWWW}, nil), "https://WWW.example.com/", "https://example.com/"},
+		{"custom tracking params", NewNormalizer([]NormalizeRule{NormalizeTrackingParams}, []string{"ref"}), "https://example.com/?ref=1&utm_source=2", "https://example.com/?utm_source=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.loc); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.loc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNormalizeRules(t *testing.T) {
+	tests := []struct {
+		names   []string
+		want    []NormalizeRule
+		wantErr bool
+	}{
+		{nil, nil, false},
+		{[]string{"none"}, nil, false},
+		{[]string{"all"}, AllNormalizeRules, false},
+		{[]string{"www", "all"}, AllNormalizeRules, false},
+		{[]string{"Trailing-Slash", " www ", "www"}, []NormalizeRule{NormalizeTrailingSlash, NormalizeWWW}, false},
+		{[]string{"www", "bogus"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNormalizeRules(tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseNormalizeRules(%q) error = %v, want error %t", tt.names, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseNormalizeRules(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizerRules(t *testing.T) {
+	n := NewNormalizer([]NormalizeRule{NormalizeTrackingParams, NormalizeHostCase}, nil)
+	want := []NormalizeRule{NormalizeHostCase, NormalizeTrackingParams}
+	if got := n.Rules(); !slices.Equal(got, want) {
+		t.Errorf("Rules() = %v, want %v in the order they are applied", got, want)
+	}
+	var none *Normalizer
+	if none.Enabled() || none.Rules() != nil {
+		t.Error("a nil Normalizer has rules")
+	}
+}
+
+func TestNormalizerSpec(t *testing.T) {
+	tests := []struct {
+		normalizer *Normalizer
+		want       string
+	}{
+		{nil, ""},
+		{NewNormalizer(nil, nil), ""},
+		{NewNormalizer([]NormalizeRule{NormalizeWWW, NormalizeTrailingSlash}, nil), "www,trailing_slash"},
+		{NewNormalizer([]NormalizeRule{NormalizeTrackingParams}, []string{"ref", "utm_*"}), "tracking_params;ref,utm_*"},
+	}
+	for _, tt := range tests {
+		if got := tt.normalizer.Spec(); got != tt.want {
+			t.Errorf("Spec() = %q, want %q", got, tt.want)
+		}
+	}
+	// The tracking parameters only matter with the rule that uses them
+	if a, b := NewNormalizer([]NormalizeRule{NormalizeWWW}, []string{"ref"}), NewNormalizer([]NormalizeRule{NormalizeWWW}, nil); a.Spec() != b.Spec() {
+		t.Errorf("Spec() = %q and %q, want the same", a.Spec(), b.Spec())
+	}
+}
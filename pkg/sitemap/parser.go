@@ -1,8 +1,12 @@
 package sitemap
 
 import (
+	"bytes"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 )
 
 // Parser handles XML sitemap parsing
@@ -21,8 +25,14 @@ type Sitemap struct {
 
 // SitemapIndex represents a sitemap index
 type SitemapIndex struct {
-	XMLName  xml.Name  `xml:"sitemapindex"`
-	Sitemaps []Sitemap `xml:"sitemap"`
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Sitemaps []IndexEntry `xml:"sitemap"`
+}
+
+// IndexEntry represents a single child sitemap reference in a sitemap index
+type IndexEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
 }
 
 // URL represents a single URL entry in a sitemap
@@ -31,6 +41,12 @@ type URL struct {
 	LastMod    string  `xml:"lastmod"`
 	ChangeFreq string  `xml:"changefreq"`
 	Priority   float64 `xml:"priority"`
+
+	// Protocol extensions
+	Images     []Image     `xml:"http://www.google.com/schemas/sitemap-image/1.1 image" json:",omitempty"`
+	Videos     []Video     `xml:"http://www.google.com/schemas/sitemap-video/1.1 video" json:",omitempty"`
+	News       *News       `xml:"http://www.google.com/schemas/sitemap-news/0.9 news" json:",omitempty"`
+	Alternates []Alternate `xml:"http://www.w3.org/1999/xhtml link" json:",omitempty"`
 }
 
 // Parse parses XML data into a Sitemap structure
@@ -51,6 +67,117 @@ func (p *Parser) ParseIndex(data []byte) (*SitemapIndex, error) {
 	return &index, nil
 }
 
+// Stream decodes a <urlset> document from r token by token, calling fn for
+// every <url> element as soon as it is decoded. Only one URL is held in memory
+// at a time. Decoding stops at the first error returned by fn or when ctx is done.
+func (p *Parser) Stream(ctx context.Context, r io.Reader, fn func(URL) error) error {
+	decoder := xml.NewDecoder(r)
+	if err := expectRoot(decoder, "urlset"); err != nil {
+		return fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	return streamElements(ctx, decoder, "url", func(se *xml.StartElement) error {
+		var u URL
+		if err := decoder.DecodeElement(&u, se); err != nil {
+			return fmt.Errorf("failed to parse sitemap: %w", err)
+		}
+		return fn(u)
+	})
+}
+
+// StreamIndex decodes a <sitemapindex> document from r token by token, calling
+// fn for every <sitemap> element as soon as it is decoded
+func (p *Parser) StreamIndex(ctx context.Context, r io.Reader, fn func(IndexEntry) error) error {
+	decoder := xml.NewDecoder(r)
+	if err := expectRoot(decoder, "sitemapindex"); err != nil {
+		return fmt.Errorf("failed to parse sitemap index: %w", err)
+	}
+
+	return streamElements(ctx, decoder, "sitemap", func(se *xml.StartElement) error {
+		var entry IndexEntry
+		if err := decoder.DecodeElement(&entry, se); err != nil {
+			return fmt.Errorf("failed to parse sitemap index: %w", err)
+		}
+		return fn(entry)
+	})
+}
+
+// DetectTypeReader determines the sitemap type from the root element of r
+// without consuming it. The returned reader replays everything that was read
+// during detection and must be used in place of r.
+func (p *Parser) DetectTypeReader(r io.Reader) (string, io.Reader, error) {
+	var buf bytes.Buffer
+	decoder := xml.NewDecoder(io.TeeReader(r, &buf))
+	replay := io.MultiReader(&buf, r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", replay, fmt.Errorf("empty data")
+		}
+		if err != nil {
+			return "", replay, err
+		}
+		if se, ok := token.(xml.StartElement); ok {
+			sitemapType, err := rootType(se.Name.Local)
+			return sitemapType, replay, err
+		}
+	}
+}
+
+// expectRoot advances the decoder to the root element and checks its name
+func expectRoot(decoder *xml.Decoder, local string) error {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return fmt.Errorf("empty data")
+		}
+		if err != nil {
+			return err
+		}
+		if se, ok := token.(xml.StartElement); ok {
+			if se.Name.Local != local {
+				return fmt.Errorf("expected <%s> root element, got <%s>", local, se.Name.Local)
+			}
+			return nil
+		}
+	}
+}
+
+// streamElements calls fn for every direct child of the current element whose
+// local name matches local, skipping everything else
+func streamElements(ctx context.Context, decoder *xml.Decoder, local string, fn func(*xml.StartElement) error) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != local {
+				if err := decoder.Skip(); err != nil {
+					return fmt.Errorf("failed to read token: %w", err)
+				}
+				continue
+			}
+			if err := fn(&t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			// End of the root element
+			return nil
+		}
+	}
+}
+
 // DetectType determines if the XML is a sitemap or sitemap index
 func (p *Parser) DetectType(data []byte) (string, error) {
 	// Try to detect from root element
@@ -66,13 +193,17 @@ func (p *Parser) DetectType(data []byte) (string, error) {
 		return "", err
 	}
 	
-	switch temp.XMLName.Local {
+	return rootType(temp.XMLName.Local)
+}
+
+func rootType(local string) (string, error) {
+	switch local {
 	case "urlset":
 		return "sitemap", nil
 	case "sitemapindex":
 		return "index", nil
 	default:
-		return "", fmt.Errorf("unknown sitemap type: %s", temp.XMLName.Local)
+		return "", fmt.Errorf("unknown sitemap type: %s", local)
 	}
 }
 
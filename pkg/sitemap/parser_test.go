@@ -0,0 +1,186 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated -->
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"
+        xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2024-01-01</lastmod>
+    <changefreq>daily</changefreq>
+    <priority>1.0</priority>
+  </url>
+  <unknown><url><loc>https://example.com/nested</loc></url></unknown>
+  <url>
+    <loc>https://example.com/gallery</loc>
+    <image:image><image:loc>https://example.com/a.jpg</image:loc></image:image>
+  </url>
+</urlset>`
+
+func TestStream(t *testing.T) {
+	want, err := NewParser().Parse([]byte(testURLSet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Elements other than <url> are skipped, including their children
+	if len(want.URLs) != 2 {
+		t.Fatalf("Parse found %d URLs, want 2", len(want.URLs))
+	}
+
+	var got []URL
+	err = NewParser().Stream(context.Background(), strings.NewReader(testURLSet), func(u URL) error {
+		got = append(got, u)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want.URLs) {
+		t.Errorf("Stream = %+v, want %+v as parsed by Parse", got, want.URLs)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		urls int // URLs delivered before the error
+	}{
+		{"empty", "", 0},
+		{"only a prolog", `<?xml version="1.0"?>`, 0},
+		{"index root", `<sitemapindex><sitemap><loc>https://example.com/a.xml</loc></sitemap></sitemapindex>`, 0},
+		{"malformed url", `<urlset><url><loc>https://example.com/</loc></url><url><loc>x</url></urlset>`, 1},
+		{"truncated", `<urlset><url><loc>https://example.com/</loc></url><url><loc>https://exa`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls := 0
+			err := NewParser().Stream(context.Background(), strings.NewReader(tt.doc), func(URL) error {
+				urls++
+				return nil
+			})
+			if err == nil {
+				t.Error("Stream succeeded, want an error")
+			}
+			if urls != tt.urls {
+				t.Errorf("Stream delivered %d URLs before failing, want %d", urls, tt.urls)
+			}
+		})
+	}
+}
+
+func TestStreamStops(t *testing.T) {
+	stop := errors.New("stop")
+	urls := 0
+	err := NewParser().Stream(context.Background(), strings.NewReader(testURLSet), func(URL) error {
+		urls++
+		return stop
+	})
+	if !errors.Is(err, stop) || urls != 1 {
+		t.Errorf("Stream with a failing callback = %v after %d URLs, want stop after 1", err, urls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = NewParser().Stream(ctx, strings.NewReader(testURLSet), func(URL) error {
+		t.Error("callback called after the context was cancelled")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream with a cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+// TestStreamIsIncremental checks that URLs are delivered before the rest of
+// the document has been read
+func TestStreamIsIncremental(t *testing.T) {
+	readErr := errors.New("connection reset")
+	r := io.MultiReader(
+		strings.NewReader(`<urlset><url><loc>https://example.com/a</loc></url>`),
+		&failingReader{err: readErr},
+	)
+
+	var got []string
+	err := NewParser().Stream(context.Background(), r, func(u URL) error {
+		got = append(got, u.Loc)
+		return nil
+	})
+	if !errors.Is(err, readErr) {
+		t.Errorf("Stream = %v, want the read error", err)
+	}
+	if want := []string{"https://example.com/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Stream delivered %v before the read error, want %v", got, want)
+	}
+}
+
+func TestStreamIndex(t *testing.T) {
+	doc := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/a.xml</loc><lastmod>2024-01-01</lastmod></sitemap>
+  <sitemap><loc>https://example.com/b.xml.gz</loc></sitemap>
+</sitemapindex>`
+
+	var got []IndexEntry
+	err := NewParser().StreamIndex(context.Background(), strings.NewReader(doc), func(entry IndexEntry) error {
+		got = append(got, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewParser().ParseIndex([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !reflect.DeepEqual(got, want.Sitemaps) {
+		t.Errorf("StreamIndex = %+v, want %+v", got, want.Sitemaps)
+	}
+
+	if err := NewParser().StreamIndex(context.Background(), strings.NewReader(testURLSet), func(IndexEntry) error { return nil }); err == nil {
+		t.Error("StreamIndex of a urlset succeeded, want an error")
+	}
+}
+
+func TestDetectTypeReader(t *testing.T) {
+	tests := []struct {
+		doc     string
+		want    string
+		wantErr bool
+	}{
+		{testURLSet, "sitemap", false},
+		{`<sitemapindex></sitemapindex>`, "index", false},
+		{`<rss></rss>`, "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		sitemapType, r, err := NewParser().DetectTypeReader(strings.NewReader(tt.doc))
+		if (err != nil) != tt.wantErr || sitemapType != tt.want {
+			t.Errorf("DetectTypeReader = %q, %v; want %q, error %t", sitemapType, err, tt.want, tt.wantErr)
+			continue
+		}
+		// The returned reader replays the whole document
+		data, err := io.ReadAll(r)
+		if err != nil || string(data) != tt.doc {
+			t.Errorf("replayed %q, %v; want the original document", data, err)
+		}
+	}
+}
+
+// failingReader fails every read with err
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,126 @@
+package sitemap
+
+import (
+	"context"
+	"io"
+	"net/url"
+)
+
+// ruleDescriptions gives a short, human-readable description of every rule
+var ruleDescriptions = map[string]string{
+	RuleWellFormed:    "Document must be well-formed XML with a <urlset> or <sitemapindex> root",
+	RuleNamespace:     "Root element must declare the sitemaps.org 0.9 namespace",
+	RuleURLLimit:      "A sitemap may contain at most 50,000 entries",
+	RuleSizeLimit:     "A sitemap may be at most 50MB uncompressed",
+	RuleLocMissing:    "Every entry requires a <loc> element",
+	RuleLocFormat:     "<loc> must be a parseable URL",
+	RuleLocAbsolute:   "<loc> must be an absolute http or https URL",
+	RuleLocHost:       "<loc> must be on the same host as the sitemap",
+	RuleLocPath:       "<loc> should be within the directory of the sitemap",
+	RuleLocLength:     "<loc> must be at most 2,048 characters long",
+	RuleLocEscaping:   "<loc> must entity-escape &, ', \", < and >",
+	RuleLocEncoding:   "<loc> should percent-encode whitespace and non-ASCII characters",
+	RuleLocDuplicate:  "<loc> values should be unique within a sitemap",
+	RuleLastModFormat: "<lastmod> must be a W3C datetime",
+	RulePriorityRange: "<priority> must be between 0.0 and 1.0",
+	RuleChangeFreq:    "<changefreq> must be one of always, hourly, daily, weekly, monthly, yearly, never",
+	RuleExtension:     "Image, video, news and hreflang extensions must include their required fields",
+}
+
+// RuleDescription returns the description of a rule ID
+func RuleDescription(rule string) string {
+	return ruleDescriptions[rule]
+}
+
+// ValidationReport lists every issue found while validating a sitemap document
+type ValidationReport struct {
+	Source     string    `json:"source"`
+	Strict     bool      `json:"strict"`
+	EntryCount int       `json:"entry_count"`
+	Findings   []Finding `json:"findings"`
+}
+
+// Count returns the number of findings with the given severity
+func (r *ValidationReport) Count(severity Severity) int {
+	count := 0
+	for _, f := range r.Findings {
+		if f.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// HasErrors reports whether any finding has error severity
+func (r *ValidationReport) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// ValidateDocument streams a sitemap document from r and returns a report of
+// every issue found, each with its rule, severity, entry index and XML
+// line/column. Without opts.Strict only the ValidateURL checks are applied.
+func (v *Validator) ValidateDocument(ctx context.Context, r io.Reader, source string, opts ConformanceOptions) (*ValidationReport, error) {
+	if opts.SitemapLoc == "" {
+		opts.SitemapLoc = source
+	}
+
+	findings, count, err := v.check(ctx, r, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ValidationReport{
+		Source:     source,
+		Strict:     opts.Strict,
+		EntryCount: count,
+		Findings:   findings,
+	}
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	return report, nil
+}
+
+// ValidateURLs validates already-parsed URLs and returns a report with every
+// issue. Findings carry entry indexes but no XML positions.
+func (v *Validator) ValidateURLs(source string, urls []URL) *ValidationReport {
+	report := &ValidationReport{
+		Source:     source,
+		EntryCount: len(urls),
+		Findings:   []Finding{},
+	}
+
+	for i := range urls {
+		if err := v.ValidateURL(&urls[i]); err != nil {
+			report.Findings = append(report.Findings, Finding{
+				Rule:     urlRule(&urls[i]),
+				Severity: SeverityError,
+				Message:  err.Error(),
+				Index:    i,
+			})
+		}
+	}
+
+	return report
+}
+
+// urlRule maps the first ValidateURL failure of u back to its rule ID
+func urlRule(u *URL) string {
+	switch {
+	case u.Loc == "":
+		return RuleLocMissing
+	case !isParseableURL(u.Loc):
+		return RuleLocFormat
+	case u.Priority < 0.0 || u.Priority > 1.0:
+		return RulePriorityRange
+	case u.ChangeFreq != "" && !validChangeFreqs[u.ChangeFreq]:
+		return RuleChangeFreq
+	default:
+		return RuleExtension
+	}
+}
+
+func isParseableURL(raw string) bool {
+	_, err := url.Parse(raw)
+	return err == nil
+}
@@ -0,0 +1,225 @@
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// reportDoc has findings on entries spread over several lines and one about
+// the document as a whole when checked with MaxURLs 2
+const reportDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+  </url>
+  <url>
+    <loc>/about</loc>
+    <priority>1.5</priority>
+  </url>
+  <url><loc>https://example.com/</loc></url>
+</urlset>
+`
+
+func TestValidateDocumentPositions(t *testing.T) {
+	report, err := NewValidator().ValidateDocument(context.Background(), strings.NewReader(reportDoc), "sitemap.xml", ConformanceOptions{Strict: true, MaxURLs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type position struct {
+		rule         string
+		index        int
+		line, column int
+	}
+	want := []position{
+		{RuleLocAbsolute, 1, 6, 3},
+		{RulePriorityRange, 1, 6, 3},
+		{RuleLocDuplicate, 2, 10, 3},
+		{RuleURLLimit, -1, 0, 0},
+	}
+	var got []position
+	for _, f := range report.Findings {
+		got = append(got, position{f.Rule, f.Index, f.Line, f.Column})
+		// The offset points at the start of the entry
+		if f.Index >= 0 && !strings.HasPrefix(reportDoc[f.Offset:], "<url>") {
+			t.Errorf("%s finding at offset %d, which isn't the start of an entry", f.Rule, f.Offset)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings at %+v\nwant %+v", got, want)
+	}
+	if report.EntryCount != 3 || report.Count(SeverityError) != 3 || report.Count(SeverityWarning) != 1 || !report.HasErrors() {
+		t.Errorf("report = %d entries, %d errors, %d warnings", report.EntryCount, report.Count(SeverityError), report.Count(SeverityWarning))
+	}
+}
+
+func TestValidateDocumentSyntaxErrorLine(t *testing.T) {
+	doc := "<urlset>\n<url><loc>https://example.com/</loc></url>\n<url>\n<loc>https://example.com/a</url>\n</urlset>"
+	report, err := NewValidator().ValidateDocument(context.Background(), strings.NewReader(doc), "sitemap.xml", ConformanceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Findings) != 1 {
+		t.Fatalf("findings = %+v, want one", report.Findings)
+	}
+	// A syntax error points at the line the decoder failed on
+	if f := report.Findings[0]; f.Rule != RuleWellFormed || f.Index != 1 || f.Line != 4 || f.Column != 0 {
+		t.Errorf("finding = %+v, want %s of entry 1 on line 4", f, RuleWellFormed)
+	}
+}
+
+// reportSARIF is the SARIF log of reportDoc, encoded as parse --report-format
+// sarif prints it
+const reportSARIF = `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "sitemapper",
+          "version": "1.0.0",
+          "informationUri": "https://www.sitemaps.org/protocol.html",
+          "rules": [
+            {
+              "id": "loc-absolute",
+              "shortDescription": {
+                "text": "\u003cloc\u003e must be an absolute http or https URL"
+              }
+            },
+            {
+              "id": "loc-duplicate",
+              "shortDescription": {
+                "text": "\u003cloc\u003e values should be unique within a sitemap"
+              }
+            },
+            {
+              "id": "priority-range",
+              "shortDescription": {
+                "text": "\u003cpriority\u003e must be between 0.0 and 1.0"
+              }
+            },
+            {
+              "id": "url-limit",
+              "shortDescription": {
+                "text": "A sitemap may contain at most 50,000 entries"
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "loc-absolute",
+          "level": "error",
+          "message": {
+            "text": "loc \"/about\" is not an absolute URL"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "sitemap.xml"
+                },
+                "region": {
+                  "startLine": 6,
+                  "startColumn": 3
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "priority-range",
+          "level": "error",
+          "message": {
+            "text": "priority must be between 0.0 and 1.0, got \"1.5\""
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "sitemap.xml"
+                },
+                "region": {
+                  "startLine": 6,
+                  "startColumn": 3
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "loc-duplicate",
+          "level": "warning",
+          "message": {
+            "text": "duplicate loc https://example.com/ (first seen at entry 0)"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "sitemap.xml"
+                },
+                "region": {
+                  "startLine": 10,
+                  "startColumn": 3
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "url-limit",
+          "level": "error",
+          "message": {
+            "text": "document contains 3 \u003curl\u003e entries, the limit is 2"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "sitemap.xml"
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestSARIF(t *testing.T) {
+	report, err := NewValidator().ValidateDocument(context.Background(), strings.NewReader(reportDoc), "sitemap.xml", ConformanceOptions{Strict: true, MaxURLs: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report.SARIF("sitemapper", "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != reportSARIF {
+		t.Errorf("SARIF =\n%s\nwant\n%s", got, reportSARIF)
+	}
+}
+
+func TestRuleDescriptions(t *testing.T) {
+	for _, rule := range []string{
+		RuleWellFormed, RuleNamespace, RuleURLLimit, RuleSizeLimit, RuleLocMissing, RuleLocFormat,
+		RuleLocAbsolute, RuleLocHost, RuleLocPath, RuleLocLength, RuleLocEscaping, RuleLocEncoding,
+		RuleLocDuplicate, RuleLastModFormat, RulePriorityRange, RuleChangeFreq, RuleExtension,
+	} {
+		if RuleDescription(rule) == "" {
+			t.Errorf("rule %s has no description", rule)
+		}
+	}
+}
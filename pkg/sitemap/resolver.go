@@ -0,0 +1,228 @@
+package sitemap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxDepth is the default nesting limit when resolving sitemap indexes
+const DefaultMaxDepth = 5
+
+// Fetcher opens a sitemap by location for streaming. The caller must close
+// the returned reader.
+type Fetcher interface {
+	Fetch(ctx context.Context, loc string) (io.ReadCloser, error)
+}
+
+// FetcherFunc adapts an ordinary function to the Fetcher interface
+type FetcherFunc func(ctx context.Context, loc string) (io.ReadCloser, error)
+
+// Fetch calls f(ctx, loc)
+func (f FetcherFunc) Fetch(ctx context.Context, loc string) (io.ReadCloser, error) {
+	return f(ctx, loc)
+}
+
+// ChildSitemap describes a sitemap reached while resolving a sitemap index
+type ChildSitemap struct {
+	Loc      string `json:"loc"`
+	LastMod  string `json:"lastmod,omitempty"`
+	Parent   string `json:"parent"`
+	Depth    int    `json:"depth"`
+	IsIndex  bool   `json:"is_index"`
+	URLCount int    `json:"url_count"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SourcedURL is a URL entry tagged with the sitemap it was read from
+type SourcedURL struct {
+	URL
+	Source string `json:"source"`
+}
+
+// ResolvedSitemap is the merged result of resolving a sitemap or sitemap index
+type ResolvedSitemap struct {
+	Root     string         `json:"root"`
+	IsIndex  bool           `json:"is_index"`
+	URLs     []SourcedURL   `json:"urls"`
+	Children []ChildSitemap `json:"children,omitempty"`
+}
+
+// Sitemap returns the merged URLs as a plain Sitemap
+func (r *ResolvedSitemap) Sitemap() *Sitemap {
+	sm := &Sitemap{URLs: make([]URL, 0, len(r.URLs))}
+	for _, u := range r.URLs {
+		sm.URLs = append(sm.URLs, u.URL)
+	}
+	return sm
+}
+
+// Resolver expands sitemap indexes by fetching every child sitemap
+type Resolver struct {
+	MaxDepth int
+	parser   *Parser
+	fetcher  Fetcher
+}
+
+// NewResolver creates a new resolver that fetches child sitemaps with fetcher
+func NewResolver(fetcher Fetcher) *Resolver {
+	return &Resolver{
+		MaxDepth: DefaultMaxDepth,
+		parser:   NewParser(),
+		fetcher:  fetcher,
+	}
+}
+
+// Resolve parses the sitemap at loc and, if it is an index, recursively fetches
+// its children. Child fetch and parse failures are recorded on the returned
+// ChildSitemap rather than aborting the whole resolution.
+func (r *Resolver) Resolve(ctx context.Context, loc string, data []byte) (*ResolvedSitemap, error) {
+	var urls []SourcedURL
+	result, err := r.Stream(ctx, loc, bytes.NewReader(data), func(u SourcedURL) error {
+		urls = append(urls, u)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.URLs = urls
+	return result, nil
+}
+
+// Stream behaves like Resolve but hands every URL to fn as it is decoded
+// instead of collecting them. The returned ResolvedSitemap describes the
+// structure of the sitemap and its children but carries no URLs.
+func (r *Resolver) Stream(ctx context.Context, loc string, src io.Reader, fn func(SourcedURL) error) (*ResolvedSitemap, error) {
+	sitemapType, src, err := r.parser.DetectTypeReader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResolvedSitemap{Root: loc, IsIndex: sitemapType == "index"}
+	visited := map[string]bool{loc: true}
+
+	if sitemapType == "sitemap" {
+		err := r.parser.Stream(ctx, src, func(u URL) error {
+			return fn(SourcedURL{URL: u, Source: loc})
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	var refs []IndexEntry
+	err = r.parser.StreamIndex(ctx, src, func(ref IndexEntry) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.resolveIndex(ctx, result, refs, loc, 1, visited, fn); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Resolver) resolveIndex(ctx context.Context, result *ResolvedSitemap, refs []IndexEntry, parent string, depth int, visited map[string]bool, fn func(SourcedURL) error) error {
+	for _, ref := range refs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		child := ChildSitemap{
+			Loc:     ref.Loc,
+			LastMod: ref.LastMod,
+			Parent:  parent,
+			Depth:   depth,
+		}
+
+		if ref.Loc == "" {
+			child.Error = "missing <loc> element"
+			result.Children = append(result.Children, child)
+			continue
+		}
+		if visited[ref.Loc] {
+			child.Error = "cycle detected: sitemap already visited"
+			result.Children = append(result.Children, child)
+			continue
+		}
+		visited[ref.Loc] = true
+
+		rc, err := r.fetcher.Fetch(ctx, ref.Loc)
+		if err != nil {
+			child.Error = fmt.Sprintf("failed to fetch: %v", err)
+			result.Children = append(result.Children, child)
+			continue
+		}
+
+		nested, err := r.streamChild(ctx, &child, rc, fn)
+		rc.Close()
+		result.Children = append(result.Children, child)
+		if err != nil {
+			return err
+		}
+		if nested != nil {
+			if err := r.resolveIndex(ctx, result, nested, ref.Loc, depth+1, visited, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// streamChild reads one child sitemap from src. URLs of a sitemap are handed
+// to fn; the entries of a nested index are returned for the caller to resolve
+// once src is closed. Read and parse failures only mark the child; errors
+// returned by fn and cancellation abort the resolution.
+func (r *Resolver) streamChild(ctx context.Context, child *ChildSitemap, src io.Reader, fn func(SourcedURL) error) ([]IndexEntry, error) {
+	sitemapType, src, err := r.parser.DetectTypeReader(src)
+	if err != nil {
+		child.Error = fmt.Sprintf("failed to detect sitemap type: %v", err)
+		return nil, nil
+	}
+
+	if sitemapType == "index" {
+		child.IsIndex = true
+		if child.Depth >= r.MaxDepth {
+			child.Error = fmt.Sprintf("maximum index depth of %d exceeded", r.MaxDepth)
+			return nil, nil
+		}
+		var refs []IndexEntry
+		err := r.parser.StreamIndex(ctx, src, func(ref IndexEntry) error {
+			refs = append(refs, ref)
+			return nil
+		})
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			child.Error = err.Error()
+			return nil, nil
+		}
+		return refs, nil
+	}
+
+	var fnErr error
+	err = r.parser.Stream(ctx, src, func(u URL) error {
+		child.URLCount++
+		if err := fn(SourcedURL{URL: u, Source: child.Loc}); err != nil {
+			fnErr = err
+			return err
+		}
+		return nil
+	})
+	if fnErr != nil {
+		return nil, fnErr
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		child.Error = err.Error()
+	}
+	return nil, nil
+}
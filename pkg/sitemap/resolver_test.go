@@ -0,0 +1,199 @@
+package sitemap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeFetcher serves documents by location and fails for any other location
+type fakeFetcher map[string]string
+
+func (f fakeFetcher) Fetch(ctx context.Context, loc string) (io.ReadCloser, error) {
+	doc, ok := f[loc]
+	if !ok {
+		return nil, errors.New("404 Not Found")
+	}
+	return io.NopCloser(strings.NewReader(doc)), nil
+}
+
+// index returns a sitemap index listing locs
+func index(locs ...string) string {
+	doc := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	for _, loc := range locs {
+		doc += fmt.Sprintf("<sitemap><loc>%s</loc></sitemap>", loc)
+	}
+	return doc + `</sitemapindex>`
+}
+
+// urlset returns a sitemap listing locs
+func urlset(locs ...string) string {
+	doc := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+	for _, loc := range locs {
+		doc += fmt.Sprintf("<url><loc>%s</loc></url>", loc)
+	}
+	return doc + `</urlset>`
+}
+
+func TestResolve(t *testing.T) {
+	const root = "https://example.com/sitemap.xml"
+
+	tests := []struct {
+		name     string
+		docs     fakeFetcher
+		maxDepth int
+		want     []ChildSitemap
+		urls     []string
+	}{
+		{
+			name: "nested index",
+			docs: fakeFetcher{
+				root:                        index("https://example.com/a.xml", "https://example.com/b.xml"),
+				"https://example.com/a.xml": urlset("https://example.com/1", "https://example.com/2"),
+				"https://example.com/b.xml": index("https://example.com/c.xml"),
+				"https://example.com/c.xml": urlset("https://example.com/3"),
+			},
+			want: []ChildSitemap{
+				{Loc: "https://example.com/a.xml", Parent: root, Depth: 1, URLCount: 2},
+				{Loc: "https://example.com/b.xml", Parent: root, Depth: 1, IsIndex: true},
+				{Loc: "https://example.com/c.xml", Parent: "https://example.com/b.xml", Depth: 2, URLCount: 1},
+			},
+			urls: []string{"https://example.com/1", "https://example.com/2", "https://example.com/3"},
+		},
+		{
+			name: "self-referencing index",
+			docs: fakeFetcher{
+				root:                        index(root, "https://example.com/a.xml"),
+				"https://example.com/a.xml": urlset("https://example.com/1"),
+			},
+			want: []ChildSitemap{
+				{Loc: root, Parent: root, Depth: 1, Error: "cycle detected: sitemap already visited"},
+				{Loc: "https://example.com/a.xml", Parent: root, Depth: 1, URLCount: 1},
+			},
+			urls: []string{"https://example.com/1"},
+		},
+		{
+			name: "cycle between indexes",
+			docs: fakeFetcher{
+				root:                        index("https://example.com/a.xml"),
+				"https://example.com/a.xml": index("https://example.com/b.xml"),
+				"https://example.com/b.xml": index("https://example.com/a.xml"),
+			},
+			want: []ChildSitemap{
+				{Loc: "https://example.com/a.xml", Parent: root, Depth: 1, IsIndex: true},
+				{Loc: "https://example.com/b.xml", Parent: "https://example.com/a.xml", Depth: 2, IsIndex: true},
+				{Loc: "https://example.com/a.xml", Parent: "https://example.com/b.xml", Depth: 3, Error: "cycle detected: sitemap already visited"},
+			},
+		},
+		{
+			name:     "maximum depth",
+			maxDepth: 2,
+			docs: fakeFetcher{
+				root:                        index("https://example.com/a.xml"),
+				"https://example.com/a.xml": index("https://example.com/b.xml"),
+				"https://example.com/b.xml": index("https://example.com/c.xml"),
+				"https://example.com/c.xml": urlset("https://example.com/1"),
+			},
+			want: []ChildSitemap{
+				{Loc: "https://example.com/a.xml", Parent: root, Depth: 1, IsIndex: true},
+				{Loc: "https://example.com/b.xml", Parent: "https://example.com/a.xml", Depth: 2, IsIndex: true, Error: "maximum index depth of 2 exceeded"},
+			},
+		},
+		{
+			name: "child errors",
+			docs: fakeFetcher{
+				root:                          index("https://example.com/missing.xml", "", "https://example.com/rss.xml", "https://example.com/a.xml"),
+				"https://example.com/rss.xml": `<rss></rss>`,
+				"https://example.com/a.xml":   urlset("https://example.com/1"),
+			},
+			want: []ChildSitemap{
+				{Loc: "https://example.com/missing.xml", Parent: root, Depth: 1, Error: "failed to fetch: 404 Not Found"},
+				{Parent: root, Depth: 1, Error: "missing <loc> element"},
+				{Loc: "https://example.com/rss.xml", Parent: root, Depth: 1, Error: "failed to detect sitemap type: unknown sitemap type: rss"},
+				{Loc: "https://example.com/a.xml", Parent: root, Depth: 1, URLCount: 1},
+			},
+			urls: []string{"https://example.com/1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewResolver(tt.docs)
+			if tt.maxDepth > 0 {
+				resolver.MaxDepth = tt.maxDepth
+			}
+			resolved, err := resolver.Resolve(context.Background(), root, []byte(tt.docs[root]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resolved.IsIndex {
+				t.Error("IsIndex = false, want true")
+			}
+			if !reflect.DeepEqual(resolved.Children, tt.want) {
+				t.Errorf("Children = %+v\nwant %+v", resolved.Children, tt.want)
+			}
+			var urls []string
+			for _, u := range resolved.URLs {
+				urls = append(urls, u.Loc)
+			}
+			if !reflect.DeepEqual(urls, tt.urls) {
+				t.Errorf("URLs = %v, want %v", urls, tt.urls)
+			}
+		})
+	}
+}
+
+func TestResolveSitemap(t *testing.T) {
+	// A plain sitemap is returned as is, without fetching anything
+	resolved, err := NewResolver(fakeFetcher{}).Resolve(context.Background(), "https://example.com/a.xml", []byte(urlset("https://example.com/1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SourcedURL{{URL: URL{Loc: "https://example.com/1"}, Source: "https://example.com/a.xml"}}
+	if resolved.IsIndex || resolved.Children != nil || !reflect.DeepEqual(resolved.URLs, want) {
+		t.Errorf("Resolve = %+v, want the sitemap's URLs", resolved)
+	}
+}
+
+// countingCloser records whether a fetched child was closed
+type countingCloser struct {
+	io.Reader
+	closed *int
+}
+
+func (c countingCloser) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestResolveClosesChildren(t *testing.T) {
+	docs := fakeFetcher{
+		"https://example.com/a.xml": index("https://example.com/b.xml"),
+		"https://example.com/b.xml": `<urlset><url><loc>https://example.com/1</loc></url><url><loc>x</url></urlset>`,
+	}
+	opened, closed := 0, 0
+	fetcher := FetcherFunc(func(ctx context.Context, loc string) (io.ReadCloser, error) {
+		rc, err := docs.Fetch(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		opened++
+		return countingCloser{Reader: rc, closed: &closed}, nil
+	})
+
+	resolved, err := NewResolver(fetcher).Resolve(context.Background(), "https://example.com/sitemap.xml", []byte(index("https://example.com/a.xml")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != 2 || closed != opened {
+		t.Errorf("opened %d children and closed %d, want 2 and 2", opened, closed)
+	}
+	// URLs before a parse error are still delivered
+	if len(resolved.URLs) != 1 || resolved.Children[1].URLCount != 1 || resolved.Children[1].Error == "" {
+		t.Errorf("Resolve = %+v, want one URL and a parse error on b.xml", resolved)
+	}
+}
@@ -0,0 +1,135 @@
+package sitemap
+
+import "sort"
+
+// SARIF 2.1.0 types, limited to what is needed to report validation findings
+// so CI systems can annotate the lines of a generated sitemap.
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFLog is the top-level SARIF document
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a single run of an analysis tool
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the analysis tool
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver describes the tool component and the rules it applies
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes a single rule
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFResult is a single finding
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+}
+
+// SARIFMessage holds plain text
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFLocation points at the artifact and region of a result
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation identifies a region within an artifact
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation identifies an artifact by URI
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is a 1-based line/column region
+type SARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// SARIF converts the report to a SARIF 2.1.0 log for the named tool
+func (r *ValidationReport) SARIF(toolName, toolVersion string) *SARIFLog {
+	ruleIDs := make(map[string]bool)
+	results := make([]SARIFResult, 0, len(r.Findings))
+
+	for _, f := range r.Findings {
+		ruleIDs[f.Rule] = true
+
+		location := SARIFLocation{
+			PhysicalLocation: SARIFPhysicalLocation{
+				ArtifactLocation: SARIFArtifactLocation{URI: r.Source},
+			},
+		}
+		if f.Line > 0 {
+			location.PhysicalLocation.Region = &SARIFRegion{StartLine: f.Line, StartColumn: f.Column}
+		}
+
+		results = append(results, SARIFResult{
+			RuleID:    f.Rule,
+			Level:     sarifLevel(f.Severity),
+			Message:   SARIFMessage{Text: f.Message},
+			Locations: []SARIFLocation{location},
+		})
+	}
+
+	rules := make([]SARIFRule, 0, len(ruleIDs))
+	for id := range ruleIDs {
+		rules = append(rules, SARIFRule{ID: id, ShortDescription: SARIFMessage{Text: RuleDescription(id)}})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	return &SARIFLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []SARIFRun{{
+			Tool: SARIFTool{Driver: SARIFDriver{
+				Name:           toolName,
+				Version:        toolVersion,
+				InformationURI: "https://www.sitemaps.org/protocol.html",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
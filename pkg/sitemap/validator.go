@@ -3,8 +3,30 @@ package sitemap
 import (
 	"fmt"
 	"net/url"
+	"strconv"
+	"time"
 )
 
+// w3cDateFormats are the W3C Datetime profiles accepted by the sitemap protocol
+var w3cDateFormats = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	time.RFC3339Nano,
+}
+
+// ParseW3CDate parses a W3C Datetime value such as 2024-01-02 or 2024-01-02T15:04:05+00:00
+func ParseW3CDate(value string) (time.Time, error) {
+	for _, format := range w3cDateFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid W3C datetime: %q", value)
+}
+
 // Validator validates sitemap structure and content
 type Validator struct{}
 
@@ -64,6 +86,142 @@ func (v *Validator) ValidateURL(u *URL) error {
 		}
 	}
 	
+	return v.ValidateExtensions(u)
+}
+
+// ValidateExtensions checks the required fields of image, video, news and
+// hreflang extension elements attached to a URL entry
+func (v *Validator) ValidateExtensions(u *URL) error {
+	for i, img := range u.Images {
+		if err := validateImage(&img); err != nil {
+			return fmt.Errorf("invalid <image:image> %d: %w", i, err)
+		}
+	}
+	
+	for i, video := range u.Videos {
+		if err := validateVideo(&video); err != nil {
+			return fmt.Errorf("invalid <video:video> %d: %w", i, err)
+		}
+	}
+	
+	if u.News != nil {
+		if err := validateNews(u.News); err != nil {
+			return fmt.Errorf("invalid <news:news>: %w", err)
+		}
+	}
+	
+	for i, alt := range u.Alternates {
+		if err := validateAlternate(&alt); err != nil {
+			return fmt.Errorf("invalid <xhtml:link> %d: %w", i, err)
+		}
+	}
+	
+	return nil
+}
+
+func validateImage(img *Image) error {
+	if img.Loc == "" {
+		return fmt.Errorf("missing <image:loc> element")
+	}
+	return validateAbsoluteURL("image:loc", img.Loc)
+}
+
+func validateVideo(video *Video) error {
+	if video.ThumbnailLoc == "" {
+		return fmt.Errorf("missing <video:thumbnail_loc> element")
+	}
+	if err := validateAbsoluteURL("video:thumbnail_loc", video.ThumbnailLoc); err != nil {
+		return err
+	}
+	if video.Title == "" {
+		return fmt.Errorf("missing <video:title> element")
+	}
+	if video.Description == "" {
+		return fmt.Errorf("missing <video:description> element")
+	}
+	if video.ContentLoc == "" && video.PlayerLoc == "" {
+		return fmt.Errorf("one of <video:content_loc> or <video:player_loc> is required")
+	}
+	if video.ContentLoc != "" {
+		if err := validateAbsoluteURL("video:content_loc", video.ContentLoc); err != nil {
+			return err
+		}
+	}
+	if video.PlayerLoc != "" {
+		if err := validateAbsoluteURL("video:player_loc", video.PlayerLoc); err != nil {
+			return err
+		}
+	}
+	if video.Duration != "" {
+		d, err := strconv.Atoi(video.Duration)
+		if err != nil || d < 1 || d > 28800 {
+			return fmt.Errorf("video:duration must be between 1 and 28800 seconds, got %q", video.Duration)
+		}
+	}
+	if video.Rating != "" {
+		r, err := strconv.ParseFloat(video.Rating, 64)
+		if err != nil || r < 0.0 || r > 5.0 {
+			return fmt.Errorf("video:rating must be between 0.0 and 5.0, got %q", video.Rating)
+		}
+	}
+	if video.ViewCount != "" {
+		if n, err := strconv.Atoi(video.ViewCount); err != nil || n < 0 {
+			return fmt.Errorf("video:view_count must be a non-negative integer, got %q", video.ViewCount)
+		}
+	}
+	if video.PublicationDate != "" {
+		if _, err := ParseW3CDate(video.PublicationDate); err != nil {
+			return fmt.Errorf("video:publication_date: %w", err)
+		}
+	}
+	if video.ExpirationDate != "" {
+		if _, err := ParseW3CDate(video.ExpirationDate); err != nil {
+			return fmt.Errorf("video:expiration_date: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateNews(news *News) error {
+	if news.Publication.Name == "" {
+		return fmt.Errorf("missing <news:name> element")
+	}
+	if news.Publication.Language == "" {
+		return fmt.Errorf("missing <news:language> element")
+	}
+	if news.PublicationDate == "" {
+		return fmt.Errorf("missing <news:publication_date> element")
+	}
+	if _, err := ParseW3CDate(news.PublicationDate); err != nil {
+		return fmt.Errorf("news:publication_date: %w", err)
+	}
+	if news.Title == "" {
+		return fmt.Errorf("missing <news:title> element")
+	}
+	return nil
+}
+
+func validateAlternate(alt *Alternate) error {
+	if alt.Rel != "alternate" {
+		return fmt.Errorf("rel must be \"alternate\", got %q", alt.Rel)
+	}
+	if alt.Hreflang == "" {
+		return fmt.Errorf("missing hreflang attribute")
+	}
+	if alt.Href == "" {
+		return fmt.Errorf("missing href attribute")
+	}
+	return validateAbsoluteURL("href", alt.Href)
+}
+
+func validateAbsoluteURL(field, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s URL: %w", field, err)
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("%s must be an absolute URL, got %q", field, raw)
+	}
 	return nil
 }
 